@@ -0,0 +1,104 @@
+package app
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of time used by this module's timers and retry loops. The default reads the system clock; a
+// FakeClock makes time-dependent code deterministic in tests, see TestMode.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type clockHolder struct {
+	clock Clock
+}
+
+var currentClock atomic.Pointer[clockHolder]
+
+// SetClock installs c as the process-wide clock. A nil c restores the system clock.
+func SetClock(c Clock) {
+	if c == nil {
+		currentClock.Store(nil)
+		return
+	}
+	currentClock.Store(&clockHolder{clock: c})
+}
+
+func activeClock() Clock {
+	if holder := currentClock.Load(); holder != nil {
+		return holder.clock
+	}
+	return realClock{}
+}
+
+// Now returns the current time of the installed clock.
+func Now() time.Time {
+	return activeClock().Now()
+}
+
+// Since returns the time elapsed since t on the installed clock.
+func Since(t time.Time) time.Duration {
+	return activeClock().Now().Sub(t)
+}
+
+// Sleep pauses for d on the installed clock.
+func Sleep(d time.Duration) {
+	activeClock().Sleep(d)
+}
+
+// After waits for d on the installed clock and then sends the current time on the returned channel.
+func After(d time.Duration) <-chan time.Time {
+	return activeClock().After(d)
+}
+
+// FakeClock is a Clock that only moves when told to. Sleep and After advance it by the requested duration and
+// return immediately, so code that waits runs instantly while observing the time it would have taken.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a FakeClock reading start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the fake current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d and returns the new time.
+func (c *FakeClock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+	return c.now
+}
+
+// Sleep advances the clock by d without blocking.
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+// After advances the clock by d and returns a channel that already holds the new time.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.Advance(d)
+	return ch
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ClockSkewCheckInterval is how often StartClockSkewMonitor compares the clocks.
+	ClockSkewCheckInterval = time.Second
+	// ClockSkewWindow is how long after a detected jump new MetaErrors are annotated with it.
+	ClockSkewWindow = 5 * time.Minute
+	// DefaultClockSkewTolerance is the tolerance used by StartClockSkewMonitor when it is given one of zero or less.
+	DefaultClockSkewTolerance = time.Second
+)
+
+// ClockJump describes a wall-clock jump detected by StartClockSkewMonitor.
+type ClockJump struct {
+	// At is when the jump was detected
+	At time.Time
+	// Skew is how far the wall clock moved beyond the monotonic clock; negative for a backwards step
+	Skew time.Duration
+	// Stall is how much longer than expected the check took by the monotonic clock, e.g. a paused VM or process
+	Stall time.Duration
+}
+
+var (
+	lastClockJump      atomic.Pointer[ClockJump]
+	clockSkewMonitorOn atomic.Bool
+)
+
+// StartClockSkewMonitor starts a goroutine, stopped by ctx, that compares monotonic and wall-clock progression every
+// ClockSkewCheckInterval. When the wall clock jumps (an NTP step, a VM resumed from pause) or the check itself stalls
+// by more than tolerance, it logs a warning and, for ClockSkewWindow afterwards, new MetaErrors carry "clockSkew",
+// "clockStall" and "clockJumpAt" fields, so timeouts around the jump are easy to explain.
+//
+// A tolerance of zero or less is replaced by DefaultClockSkewTolerance. Only one monitor runs at a time; calls made
+// while one is running do nothing.
+func StartClockSkewMonitor(ctx context.Context, tolerance time.Duration) {
+	if tolerance <= 0 {
+		tolerance = DefaultClockSkewTolerance
+	}
+	if !clockSkewMonitorOn.CompareAndSwap(false, true) {
+		slog.Debug("Clock skew monitor already running")
+		return
+	}
+
+	go func() {
+		defer clockSkewMonitorOn.Store(false)
+
+		// The real clocks are read here rather than the app clock, since they are what is being measured.
+		ticker := time.NewTicker(ClockSkewCheckInterval)
+		defer ticker.Stop()
+
+		prev := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			now := time.Now()
+			jump, jumped := checkClockJump(prev, now, ClockSkewCheckInterval, tolerance)
+			prev = now
+			if !jumped {
+				continue
+			}
+
+			lastClockJump.Store(&jump)
+			slog.Warn("Clock jump detected", "skew", jump.Skew, "stall", jump.Stall, "tolerance", tolerance)
+		}
+	}()
+}
+
+// checkClockJump compares how far the monotonic and wall clocks moved between two readings of time.Now taken
+// interval apart, and reports a jump when they disagree, or the monotonic clock overshot interval, by more than
+// tolerance.
+func checkClockJump(prev, now time.Time, interval, tolerance time.Duration) (ClockJump, bool) {
+	return compareClockProgress(now.Sub(prev), now.Round(0).Sub(prev.Round(0)), interval, tolerance)
+}
+
+// compareClockProgress implements checkClockJump on the elapsed monotonic and wall-clock durations.
+func compareClockProgress(monotonic, wall, interval, tolerance time.Duration) (ClockJump, bool) {
+	jump := ClockJump{At: Now(), Skew: wall - monotonic, Stall: monotonic - interval}
+	return jump, jump.Skew.Abs() > tolerance || jump.Stall > tolerance
+}
+
+// LastClockJump returns the most recent jump detected by StartClockSkewMonitor, if any.
+func LastClockJump() (ClockJump, bool) {
+	jump := lastClockJump.Load()
+	if jump == nil {
+		return ClockJump{}, false
+	}
+	return *jump, true
+}
+
+// annotateClockJump adds the last clock jump to e when it happened within ClockSkewWindow.
+func annotateClockJump(e *MetaError) {
+	jump := lastClockJump.Load()
+	if jump == nil || Since(jump.At) > ClockSkewWindow {
+		return
+	}
+	e.WithField("clockSkew", jump.Skew.String())
+	e.WithField("clockStall", jump.Stall.String())
+	e.WithField("clockJumpAt", jump.At.Format(time.RFC3339))
+}
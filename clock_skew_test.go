@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCheckClockJump tests detecting wall-clock steps and stalled checks beyond the tolerance
+func TestCheckClockJump(t *testing.T) {
+	TestMode(t)
+	prev := time.Now()
+
+	if jump, jumped := checkClockJump(prev, prev.Add(time.Second+10*time.Millisecond), time.Second, 100*time.Millisecond); jumped {
+		t.Errorf("Expected ticker jitter within tolerance to be ignored, got %+v", jump)
+	}
+
+	jump, jumped := checkClockJump(prev, prev.Add(5*time.Second), time.Second, 100*time.Millisecond)
+	if !jumped || jump.Stall != 4*time.Second || jump.Skew != 0 || !jump.At.Equal(TestModeStart) {
+		t.Errorf("Expected a 4s stall detected at the app clock time, got %v %+v", jumped, jump)
+	}
+
+	tests := []struct {
+		name      string
+		monotonic time.Duration
+		wall      time.Duration
+		skew      time.Duration
+		jumped    bool
+	}{
+		{"in step", time.Second, time.Second, 0, false},
+		{"small drift", time.Second, time.Second + 50*time.Millisecond, 50 * time.Millisecond, false},
+		{"forward step", time.Second, time.Minute, 59 * time.Second, true},
+		{"backward step", time.Second, -9 * time.Second, -10 * time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jump, jumped := compareClockProgress(tt.monotonic, tt.wall, time.Second, 100*time.Millisecond)
+			if jumped != tt.jumped || jump.Skew != tt.skew {
+				t.Errorf("Expected jumped %v with skew %s, got %v with %s", tt.jumped, tt.skew, jumped, jump.Skew)
+			}
+		})
+	}
+}
+
+// TestClockJumpAnnotation tests that MetaErrors carry the last jump only within ClockSkewWindow
+func TestClockJumpAnnotation(t *testing.T) {
+	clock := TestMode(t)
+	t.Cleanup(func() {
+		lastClockJump.Store(nil)
+	})
+
+	lastClockJump.Store(&ClockJump{At: Now(), Skew: 3 * time.Second, Stall: time.Second})
+	clock.Advance(ClockSkewWindow - time.Second)
+
+	metaErr := NewMetaError(errors.New("query timed out"))
+	if metaErr.Fields["clockSkew"] != "3s" || metaErr.Fields["clockStall"] != "1s" ||
+		metaErr.Fields["clockJumpAt"] != TestModeStart.Format(time.RFC3339) {
+		t.Errorf("Expected the jump annotated within the window, got %v", metaErr.Fields)
+	}
+
+	clock.Advance(2 * time.Second)
+	if metaErr := NewMetaError(errors.New("query timed out")); metaErr.Fields["clockSkew"] != nil {
+		t.Errorf("Expected no annotation after the window, got %v", metaErr.Fields)
+	}
+}
+
+// TestStartClockSkewMonitorOnce tests that only one monitor runs at a time
+func TestStartClockSkewMonitorOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	StartClockSkewMonitor(ctx, 0)
+	if !clockSkewMonitorOn.Load() {
+		t.Fatal("Expected the monitor running")
+	}
+	StartClockSkewMonitor(ctx, time.Second)
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for clockSkewMonitorOn.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if clockSkewMonitorOn.Load() {
+		t.Error("Expected the monitor to stop with its context")
+	}
+}
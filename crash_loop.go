@@ -0,0 +1,134 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const crashLoopStateFile = "start_times.json"
+
+// CrashLoopConfig holds configuration for crash-loop detection
+type CrashLoopConfig struct {
+	// Threshold is the number of starts within Window that counts as a crash loop
+	Threshold int
+	// Window is the period over which starts are counted
+	Window time.Duration
+	// Backoff is the startup delay applied when a crash loop is detected. It grows with each start beyond
+	// Threshold and is capped at Window.
+	Backoff time.Duration
+	// SafeMode switches the application into safe mode when a crash loop is detected, see EnterSafeMode
+	SafeMode bool
+}
+
+// DefaultCrashLoopConfig provides sensible default values for CrashLoopConfig
+var DefaultCrashLoopConfig = CrashLoopConfig{
+	Threshold: 5,
+	Window:    10 * time.Minute,
+	Backoff:   30 * time.Second,
+	SafeMode:  true,
+}
+
+// DetectCrashLoop records this start in stateDir and reports whether the application is restarting in a crash loop,
+// using DefaultCrashLoopConfig. Call it early in main, before connecting to dependencies.
+//
+// See DetectCrashLoopWithConfig.
+func DetectCrashLoop(stateDir string) (bool, error) {
+	return DetectCrashLoopWithConfig(stateDir, DefaultCrashLoopConfig)
+}
+
+// DetectCrashLoopWithConfig records this start in stateDir and reports whether the number of starts within
+// config.Window exceeds config.Threshold. When it does, it logs an error, sleeps for the configured backoff to
+// protect dependencies from a restart storm, and optionally enters safe mode.
+//
+// Errors reading or writing the state file are returned but never block startup; a corrupt state file is reset.
+func DetectCrashLoopWithConfig(stateDir string, config CrashLoopConfig) (bool, error) {
+	path := filepath.Join(stateDir, crashLoopStateFile)
+	now := Now()
+
+	starts, readErr := readStartTimes(path)
+
+	recent := make([]time.Time, 0, len(starts)+1)
+	for _, start := range starts {
+		if now.Sub(start) <= config.Window {
+			recent = append(recent, start)
+		}
+	}
+	recent = append(recent, now)
+	if limit := config.Threshold * 2; limit > 0 && len(recent) > limit {
+		recent = recent[len(recent)-limit:]
+	}
+
+	writeErr := writeStartTimes(stateDir, path, recent)
+	err := errors.Join(readErr, writeErr)
+
+	if config.Threshold <= 0 || len(recent) <= config.Threshold {
+		return false, err
+	}
+
+	excess := len(recent) - config.Threshold
+	backoff := config.Backoff * time.Duration(excess)
+	if backoff > config.Window {
+		backoff = config.Window
+	}
+
+	slog.Error("CRASH LOOP DETECTED: application restarted too many times",
+		"starts", len(recent),
+		"window", config.Window,
+		"threshold", config.Threshold,
+		"startupBackoff", backoff,
+		"safeMode", config.SafeMode)
+
+	if config.SafeMode {
+		EnterSafeMode(fmt.Sprintf("crash loop: %d starts within %s", len(recent), config.Window))
+	}
+
+	Sleep(backoff)
+	return true, err
+}
+
+func readStartTimes(path string) ([]time.Time, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var starts []time.Time
+	if err := json.Unmarshal(data, &starts); err != nil {
+		return nil, fmt.Errorf("corrupt crash loop state file %s, resetting: %w", path, err)
+	}
+	return starts, nil
+}
+
+func writeStartTimes(stateDir string, path string, starts []time.Time) error {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(starts)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(stateDir, crashLoopStateFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		CloseWithLog(tmp, "crash loop state file")
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
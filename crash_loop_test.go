@@ -0,0 +1,63 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDetectCrashLoop tests counting starts within the window, the startup backoff and entering safe mode
+func TestDetectCrashLoop(t *testing.T) {
+	clock := TestMode(t)
+	t.Cleanup(ExitSafeMode)
+	dir := filepath.Join(t.TempDir(), "state")
+	config := CrashLoopConfig{Threshold: 2, Window: time.Minute, Backoff: 10 * time.Second, SafeMode: true}
+
+	for i := 0; i < 2; i++ {
+		if loop, err := DetectCrashLoopWithConfig(dir, config); loop || err != nil {
+			t.Fatalf("Expected start %d within the threshold, got %v, %v", i+1, loop, err)
+		}
+		clock.Advance(time.Second)
+	}
+
+	before := Now()
+	if loop, err := DetectCrashLoopWithConfig(dir, config); !loop || err != nil {
+		t.Fatalf("Expected the third start to be a crash loop, got %v, %v", loop, err)
+	}
+	if slept := Since(before); slept != config.Backoff {
+		t.Errorf("Expected a backoff of %s, got %s", config.Backoff, slept)
+	}
+	if !InSafeMode() {
+		t.Error("Expected safe mode after a crash loop")
+	}
+
+	before = Now()
+	if loop, _ := DetectCrashLoopWithConfig(dir, config); !loop || Since(before) != 2*config.Backoff {
+		t.Errorf("Expected the backoff to grow with each extra start, got %s", Since(before))
+	}
+
+	clock.Advance(config.Window + time.Second)
+	if loop, err := DetectCrashLoopWithConfig(dir, config); loop || err != nil {
+		t.Errorf("Expected starts outside the window to be forgotten, got %v, %v", loop, err)
+	}
+}
+
+// TestDetectCrashLoopCorruptState tests that a corrupt state file is reported and reset without blocking startup
+func TestDetectCrashLoopCorruptState(t *testing.T) {
+	TestMode(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, crashLoopStateFile)
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loop, err := DetectCrashLoopWithConfig(dir, DefaultCrashLoopConfig)
+	if loop || err == nil {
+		t.Fatalf("Expected the corrupt state reported without a crash loop, got %v, %v", loop, err)
+	}
+	starts, err := readStartTimes(path)
+	if err != nil || len(starts) != 1 {
+		t.Errorf("Expected the state reset to this start, got %v, %v", starts, err)
+	}
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -27,6 +28,36 @@ func (d *DebugContext) WithValue(key, val interface{}) *DebugContext {
 	}
 }
 
+// debugContextKey finds the nearest DebugContext in a context chain, see debugContextFrom.
+type debugContextKey struct{}
+
+// Value implements context.Context, also answering debugContextKey with d itself so contexts derived from a
+// DebugContext can still reach its values.
+func (d *DebugContext) Value(key interface{}) interface{} {
+	if _, ok := key.(debugContextKey); ok {
+		return d
+	}
+	return d.Context.Value(key)
+}
+
+// snapshot returns the values set through WithValue as "key=value" strings, sorted for stable log output.
+func (d *DebugContext) snapshot() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]string, 0, len(d.data))
+	for k, v := range d.data {
+		out = append(out, fmt.Sprintf("%v=%v", k, v))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func debugContextFrom(ctx context.Context) (*DebugContext, bool) {
+	d, ok := ctx.Value(debugContextKey{}).(*DebugContext)
+	return d, ok && d != nil
+}
+
 func (d *DebugContext) PrintValues() {
 	d.mu.Lock()
 	defer d.mu.Unlock()
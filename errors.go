@@ -1,7 +1,12 @@
 package app
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log/slog"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -9,6 +14,16 @@ const separator = "; "
 
 type MultiError struct {
 	Errors []error
+	// MaxErrors caps the number of errors kept when positive. Errors appended past the cap are counted but not
+	// stored, see NewBoundedMultiError.
+	MaxErrors int
+	// Unique makes Append collapse errors with identical messages into one counted entry, see AppendUnique.
+	Unique bool
+	// SummarizeCancellations makes Append collapse context cancellations and deadline expiries into one counted
+	// entry, so genuine failures stay visible when a parent context dies mid-batch, see CollapseCancellations.
+	SummarizeCancellations bool
+
+	dropped int
 }
 
 func AppendError(err error, errs ...error) error {
@@ -40,6 +55,16 @@ func AppendError(err error, errs ...error) error {
 	return mErr
 }
 
+// AppendErrorFlat behaves like AppendError but flattens nested MultiErrors, in err and in errs, so the result is a
+// single flat list. See MultiError.Flatten.
+func AppendErrorFlat(err error, errs ...error) error {
+	result := AppendError(err, errs...)
+	if mErr, ok := result.(*MultiError); ok {
+		return mErr.Flatten()
+	}
+	return result
+}
+
 func NewMultiError(errs ...error) *MultiError {
 	mErr := &MultiError{}
 	for _, err := range errs {
@@ -48,6 +73,34 @@ func NewMultiError(errs ...error) *MultiError {
 	return mErr
 }
 
+// NewBoundedMultiError creates a MultiError that keeps only the first maxErrors errors and counts the rest, so long-running
+// jobs do not accumulate unbounded memory. Error reports the overflow as "... and N more errors".
+func NewBoundedMultiError(maxErrors int) *MultiError {
+	return &MultiError{MaxErrors: maxErrors}
+}
+
+// FromJoined decomposes err into a MultiError. Errors produced by errors.Join, and any other error with an
+// Unwrap() []error method such as a MultiError, are expanded recursively; other errors become a single entry. It
+// returns nil for a nil err.
+func FromJoined(err error) *MultiError {
+	if err == nil {
+		return nil
+	}
+	mErr := &MultiError{}
+	mErr.appendJoined(err)
+	return mErr
+}
+
+func (m *MultiError) appendJoined(err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			m.appendJoined(e)
+		}
+		return
+	}
+	m.Append(err)
+}
+
 func (m *MultiError) Append(err error) {
 	if err != nil {
 		if m == nil {
@@ -55,11 +108,64 @@ func (m *MultiError) Append(err error) {
 			return
 		}
 
-		if m.Errors == nil {
-			m.Errors = make([]error, 0)
+		if m.SummarizeCancellations && IsContextCancelledOrExpiredError(err) {
+			m.appendCancellation(err)
+			return
+		}
+		if m.Unique {
+			m.AppendUnique(err)
+			return
 		}
-		m.Errors = append(m.Errors, err)
+		m.store(err, 1)
+	}
+}
+
+// store appends err unless MaxErrors is reached, in which case the count of errors it stands for is dropped.
+func (m *MultiError) store(err error, count int) {
+	if m.MaxErrors > 0 && len(m.Errors) >= m.MaxErrors {
+		m.dropped += count
+		return
+	}
+
+	if m.Errors == nil {
+		m.Errors = make([]error, 0)
+	}
+	m.Errors = append(m.Errors, err)
+}
+
+// Merge appends the errors of other to m as Append would, so the MaxErrors and Unique settings of m apply. Entries
+// keep their wrapping, repeat counts from AppendUnique are carried over, and errors other dropped are added to the
+// dropped count of m. other is not modified.
+//
+// Example usage:
+//
+//	job := app.NewBoundedMultiError(1000)
+//	for _, shard := range shards {
+//		job.Merge(shard.Errors())
+//	}
+func (m *MultiError) Merge(other *MultiError) {
+	if m == nil || other == nil {
+		return
+	}
+	for _, err := range other.Errors {
+		m.Append(err)
+	}
+	m.dropped += other.dropped
+}
+
+// AppendWrapped appends err wrapped as "op: err", so entries collected in a loop identify the step that failed.
+// The original error stays reachable through errors.Is and errors.As. Nil errors are ignored.
+//
+// Example usage:
+//
+//	for _, id := range ids {
+//		mErr.AppendWrapped("sync "+id, syncRecord(id))
+//	}
+func (m *MultiError) AppendWrapped(op string, err error) {
+	if err == nil {
+		return
 	}
+	m.Append(fmt.Errorf("%s: %w", op, err))
 }
 
 func (m *MultiError) Error() string {
@@ -71,7 +177,7 @@ func (m *MultiError) Error() string {
 		return ""
 	}
 
-	if len(m.Errors) < 5 {
+	if len(m.Errors) < 5 && m.dropped == 0 {
 		result := m.Errors[0].Error()
 		for i := 1; i < len(m.Errors); i++ {
 			result += separator + m.Errors[i].Error()
@@ -84,10 +190,21 @@ func (m *MultiError) Error() string {
 			sb.WriteString(separator)
 			sb.WriteString(m.Errors[i].Error())
 		}
+		if m.dropped > 0 {
+			fmt.Fprintf(&sb, "%s... and %d more errors", separator, m.dropped)
+		}
 		return sb.String()
 	}
 }
 
+// Dropped returns the number of errors discarded because MaxErrors was reached.
+func (m *MultiError) Dropped() int {
+	if m == nil {
+		return 0
+	}
+	return m.dropped
+}
+
 // ErrorOrNil returns nil if there are no Errors, or the error interface if there are
 func (m *MultiError) ErrorOrNil() error {
 	if m == nil {
@@ -107,6 +224,22 @@ func (m *MultiError) HasErrors() bool {
 	return len(m.Errors) > 0
 }
 
+// First returns the first error, or nil if there are none.
+func (m *MultiError) First() error {
+	if m == nil || len(m.Errors) == 0 {
+		return nil
+	}
+	return m.Errors[0]
+}
+
+// Last returns the most recently appended error, or nil if there are none.
+func (m *MultiError) Last() error {
+	if m == nil || len(m.Errors) == 0 {
+		return nil
+	}
+	return m.Errors[len(m.Errors)-1]
+}
+
 func (m *MultiError) Unwrap() []error {
 	if len(m.Errors) == 0 {
 		return nil
@@ -114,3 +247,213 @@ func (m *MultiError) Unwrap() []error {
 
 	return m.Errors
 }
+
+// Fingerprint returns a stable hash derived from the sorted set of entry fingerprints. Two MultiErrors holding the
+// same distinct failures produce the same fingerprint regardless of order or repetition, so alerts on aggregated
+// batch failures can be grouped across runs. It returns an empty string when there are no errors.
+func (m *MultiError) Fingerprint() string {
+	if m == nil || len(m.Errors) == 0 {
+		return ""
+	}
+
+	set := make(map[string]struct{}, len(m.Errors))
+	for _, err := range m.Errors {
+		if err != nil {
+			set[errorFingerprint(err)] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// errorFingerprint hashes the identifying parts of a single error: MetaError.Fingerprint for a MetaError, otherwise
+// the type of the root cause and the message.
+func errorFingerprint(err error) string {
+	if counted, ok := err.(*countedError); ok {
+		err = counted.err
+	}
+	if summary, ok := err.(*cancellationSummary); ok {
+		err = summary.first
+	}
+
+	if metaErr, ok := err.(*MetaError); ok {
+		return metaErr.Fingerprint()
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%T|%s", RootCause(err), err.Error())
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Flatten returns a new MultiError in which nested *MultiError entries, at any depth, are replaced by their errors,
+// keeping order. Errors dropped by bounded nested MultiErrors are added to the dropped count of the result.
+func (m *MultiError) Flatten() *MultiError {
+	flat := &MultiError{}
+	if m == nil {
+		return flat
+	}
+	flat.MaxErrors = m.MaxErrors
+	flat.flattenFrom(m)
+	return flat
+}
+
+func (m *MultiError) flattenFrom(src *MultiError) {
+	m.dropped += src.dropped
+	for _, err := range src.Errors {
+		if nested, ok := err.(*MultiError); ok && nested != nil {
+			m.flattenFrom(nested)
+			continue
+		}
+		m.Append(err)
+	}
+}
+
+// Filter returns a new MultiError holding the errors for which keep returns true.
+//
+// Example usage:
+//
+//	mErr = mErr.Filter(func(err error) bool {
+//		return !errors.Is(err, context.Canceled)
+//	})
+func (m *MultiError) Filter(keep func(error) bool) *MultiError {
+	filtered := &MultiError{}
+	if m == nil {
+		return filtered
+	}
+	filtered.MaxErrors = m.MaxErrors
+	filtered.dropped = m.dropped
+	for _, err := range m.Errors {
+		if keep(err) {
+			filtered.Errors = append(filtered.Errors, err)
+		}
+	}
+	return filtered
+}
+
+// Map returns a new MultiError holding f applied to each error. Entries for which f returns nil are dropped.
+func (m *MultiError) Map(f func(error) error) *MultiError {
+	mapped := &MultiError{}
+	if m == nil {
+		return mapped
+	}
+	mapped.MaxErrors = m.MaxErrors
+	mapped.dropped = m.dropped
+	for _, err := range m.Errors {
+		if err = f(err); err != nil {
+			mapped.Errors = append(mapped.Errors, err)
+		}
+	}
+	return mapped
+}
+
+// Dedup returns a new MultiError in which errors with identical messages are collapsed into the first occurrence,
+// rendered with a count such as "connection refused (x12)". The collapsed entry unwraps to the first occurrence, so
+// errors.Is and errors.As keep working. Order follows first occurrence.
+func (m *MultiError) Dedup() *MultiError {
+	deduped := &MultiError{}
+	if m == nil {
+		return deduped
+	}
+	deduped.MaxErrors = m.MaxErrors
+	deduped.dropped = m.dropped
+	for _, err := range m.Errors {
+		deduped.AppendUnique(err)
+	}
+	return deduped
+}
+
+// AppendUnique appends err unless an entry with the same message is already present, in which case that entry's
+// count is incremented instead. Use it in place of Append when the same failure may repeat many times.
+func (m *MultiError) AppendUnique(err error) {
+	if err == nil {
+		return
+	}
+	if m == nil {
+		slog.Warn("app.MultiError.AppendUnique called on nil receiver")
+		return
+	}
+
+	add := 1
+	if counted, ok := err.(*countedError); ok {
+		err, add = counted.err, counted.count
+	}
+
+	msg := err.Error()
+	for i, existing := range m.Errors {
+		counted, ok := existing.(*countedError)
+		if !ok {
+			if existing == nil || existing.Error() != msg {
+				continue
+			}
+			counted = &countedError{err: existing, count: 1}
+			m.Errors[i] = counted
+		} else if counted.err.Error() != msg {
+			continue
+		}
+		counted.count += add
+		return
+	}
+
+	if add > 1 {
+		err = &countedError{err: err, count: add}
+	}
+	m.store(err, add)
+}
+
+// countedError is an error that occurred count times, produced by Dedup and AppendUnique.
+type countedError struct {
+	err   error
+	count int
+}
+
+func (c *countedError) Error() string {
+	return fmt.Sprintf("%s (x%d)", c.err.Error(), c.count)
+}
+
+func (c *countedError) Unwrap() error {
+	return c.err
+}
+
+// LogAttrs returns one slog group per error, keyed by its index, holding the message, fingerprint and, for
+// MetaErrors, the capture location. It lets a whole batch failure be emitted as structured data in one call:
+//
+//	slog.LogAttrs(ctx, slog.LevelError, "batch failed", mErr.LogAttrs()...)
+func (m *MultiError) LogAttrs() []slog.Attr {
+	if m == nil || len(m.Errors) == 0 {
+		return nil
+	}
+
+	attrs := make([]slog.Attr, 0, len(m.Errors))
+	for i, err := range m.Errors {
+		if err == nil {
+			continue
+		}
+
+		fields := []interface{}{
+			slog.Int("index", i),
+			slog.String("message", err.Error()),
+		}
+		if metaErr, ok := AsMetaError(err); ok {
+			fields = append(fields,
+				slog.String("file", metaErr.File),
+				slog.Int("line", metaErr.Line),
+				slog.String("func", metaErr.Func),
+				slog.String("package", metaErr.Package),
+			)
+		}
+		fields = append(fields, slog.String("fingerprint", errorFingerprint(err)))
+
+		attrs = append(attrs, slog.Group(strconv.Itoa(i), fields...))
+	}
+	return attrs
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"fmt"
+)
+
+// cancellationSummary stands for every context cancellation or deadline expiry appended to a MultiError with
+// SummarizeCancellations set. It unwraps to the first one, so errors.Is(err, context.Canceled) keeps working.
+type cancellationSummary struct {
+	first error
+	count int
+}
+
+func (c *cancellationSummary) Error() string {
+	if c.count == 1 {
+		return c.first.Error()
+	}
+	return fmt.Sprintf("%d operations cancelled or timed out, first: %s", c.count, c.first.Error())
+}
+
+func (c *cancellationSummary) Unwrap() error {
+	return c.first
+}
+
+// appendCancellation counts err into the summary entry, creating it at the position of the first cancellation.
+func (m *MultiError) appendCancellation(err error) {
+	add := 1
+	if summary, ok := err.(*cancellationSummary); ok {
+		err, add = summary.first, summary.count
+	}
+
+	for _, existing := range m.Errors {
+		if summary, ok := existing.(*cancellationSummary); ok {
+			summary.count += add
+			return
+		}
+	}
+	m.store(&cancellationSummary{first: err, count: add}, add)
+}
+
+// Cancellations returns the number of context cancellations and deadline expiries collapsed into a summary entry by
+// SummarizeCancellations or CollapseCancellations.
+func (m *MultiError) Cancellations() int {
+	if m == nil {
+		return 0
+	}
+	for _, err := range m.Errors {
+		if summary, ok := err.(*cancellationSummary); ok {
+			return summary.count
+		}
+	}
+	return 0
+}
+
+// CollapseCancellations returns a new MultiError in which every entry that is a context cancellation or deadline
+// expiry, see IsContextCancelledOrExpiredError, is replaced by one entry such as "312 operations cancelled or timed
+// out, first: fetch 17: context canceled", placed where the first occurred. Other entries keep their order. The
+// result has SummarizeCancellations set, so later cancellations are counted into the same entry.
+//
+// Example usage:
+//
+//	for _, id := range ids {
+//		mErr.AppendWrapped("fetch "+id, fetch(ctx, id))
+//	}
+//	return mErr.CollapseCancellations().ErrorOrNil()
+func (m *MultiError) CollapseCancellations() *MultiError {
+	collapsed := &MultiError{SummarizeCancellations: true}
+	if m == nil {
+		return collapsed
+	}
+	collapsed.MaxErrors = m.MaxErrors
+	collapsed.Unique = m.Unique
+	collapsed.dropped = m.dropped
+	for _, err := range m.Errors {
+		collapsed.Append(err)
+	}
+	return collapsed
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxChainDepth bounds FormatChain on errors whose Unwrap methods form a cycle.
+const maxChainDepth = 64
+
+// FormatChain renders err and everything it wraps as an indented tree, one error per line with its Go type, its
+// message and, for MetaErrors, its capture site. Errors wrapping several others, such as MultiError entries and
+// errors.Join results, branch into one subtree per error. It returns "<nil>" for a nil err.
+//
+// Example output:
+//
+//	*fmt.wrapError: sync filings: 2 failed
+//	└─ *app.MultiError: fetch 0001: timeout; parse 0002: bad json
+//	   ├─ *app.MetaError: fetch 0001: timeout [fetch.go:41 (fetchFiling) github.com/org/sync]
+//	   │  └─ *errors.errorString: timeout
+//	   └─ *fmt.wrapError: parse 0002: bad json
+//	      └─ *errors.errorString: bad json
+func FormatChain(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+
+	var sb strings.Builder
+	writeChainNode(&sb, err, "", "", 0)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// writeChainNode writes err after prefix and its children indented by childPrefix.
+func writeChainNode(sb *strings.Builder, err error, prefix string, childPrefix string, depth int) {
+	sb.WriteString(prefix)
+	if err == nil {
+		sb.WriteString("<nil>\n")
+		return
+	}
+	fmt.Fprintf(sb, "%T: %s", err, singleLine(err.Error()))
+	if metaErr, ok := err.(*MetaError); ok && metaErr.File != "" {
+		fmt.Fprintf(sb, " [%s:%d (%s) %s]", metaErr.File, metaErr.Line, metaErr.Func, metaErr.Package)
+	}
+	sb.WriteByte('\n')
+
+	children := unwrapChildren(err)
+	if depth >= maxChainDepth {
+		if len(children) > 0 {
+			sb.WriteString(childPrefix + "└─ ...\n")
+		}
+		return
+	}
+	for i, child := range children {
+		if i == len(children)-1 {
+			writeChainNode(sb, child, childPrefix+"└─ ", childPrefix+"   ", depth+1)
+		} else {
+			writeChainNode(sb, child, childPrefix+"├─ ", childPrefix+"│  ", depth+1)
+		}
+	}
+}
+
+// unwrapChildren returns the errors err wraps directly, through Unwrap() error or Unwrap() []error.
+func unwrapChildren(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	case interface{ Unwrap() error }:
+		if inner := e.Unwrap(); inner != nil {
+			return []error{inner}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// errorJSON is the JSON form of one MultiError entry.
+type errorJSON struct {
+	Message string `json:"message"`
+	Type    string `json:"type,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Func    string `json:"func,omitempty"`
+	Package string `json:"package,omitempty"`
+	Dropped int    `json:"dropped,omitempty"`
+}
+
+// MarshalJSON encodes the errors as an array of objects holding the message, the Go type of the root cause and,
+// for errors wrapping a MetaError, its capture location. Errors dropped by MaxErrors are counted in a final entry:
+//
+//	[{"message":"not found","type":"*errors.errorString","file":"store.go","line":42,"func":"Get","package":"store"},
+//	 {"message":"... and 3 more errors","dropped":3}]
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+
+	entries := make([]errorJSON, 0, len(m.Errors))
+	for _, err := range m.Errors {
+		if err == nil {
+			continue
+		}
+
+		entry := errorJSON{
+			Message: err.Error(),
+			Type:    fmt.Sprintf("%T", RootCause(err)),
+		}
+		if metaErr, ok := AsMetaError(err); ok {
+			entry.File = metaErr.File
+			entry.Line = metaErr.Line
+			entry.Func = metaErr.Func
+			entry.Package = metaErr.Package
+		}
+		entries = append(entries, entry)
+	}
+	if m.dropped > 0 {
+		entries = append(entries, errorJSON{Message: fmt.Sprintf("... and %d more errors", m.dropped), Dropped: m.dropped})
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON decodes the array produced by MarshalJSON. Entries with a capture location become *MetaError values
+// and the rest plain errors; the original error types are not restored. The dropped count is restored from the final
+// entry.
+func (m *MultiError) UnmarshalJSON(data []byte) error {
+	var entries []errorJSON
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	m.Errors = make([]error, 0, len(entries))
+	m.dropped = 0
+	for _, entry := range entries {
+		if entry.Dropped > 0 {
+			m.dropped += entry.Dropped
+			continue
+		}
+
+		err := errors.New(entry.Message)
+		if entry.File != "" || entry.Func != "" {
+			err = &MetaError{
+				Err:     err,
+				File:    entry.File,
+				Line:    entry.Line,
+				Func:    entry.Func,
+				Package: entry.Package,
+			}
+		}
+		m.Errors = append(m.Errors, err)
+	}
+	return nil
+}
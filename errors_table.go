@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// RenderTable writes the errors as an aligned text table for terminals and plain-text reports such as cron emails:
+//
+//	#  CATEGORY          LOCATION                      MESSAGE
+//	1  DB_TIMEOUT        store.go:42 (Get)             query filings: context deadline exceeded
+//	2  *net.OpError      -                             dial tcp 10.0.0.7:443: connect: connection refused
+//
+// The category is the error code set with WithCode, then the category from CategoryOf unless it is
+// CategoryInternal, then the Go type of the root cause. The location is the capture
+// site of the first MetaError in the entry. Whitespace runs in messages, including newlines, collapse to one space so
+// each error stays on one row, and a final line reports errors dropped by MaxErrors.
+func (m *MultiError) RenderTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tCATEGORY\tLOCATION\tMESSAGE")
+
+	if m != nil {
+		row := 0
+		for _, err := range m.Errors {
+			if err == nil {
+				continue
+			}
+			row++
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", row, errorCategory(err), errorLocation(err), singleLine(err.Error()))
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if dropped := m.Dropped(); dropped > 0 {
+		_, err := fmt.Fprintf(w, "... and %d more errors\n", dropped)
+		return err
+	}
+	return nil
+}
+
+func errorCategory(err error) string {
+	if code := CodeOf(err); code != "" {
+		return code
+	}
+	if category := CategoryOf(err); category != CategoryInternal {
+		return string(category)
+	}
+	return fmt.Sprintf("%T", RootCause(err))
+}
+
+func errorLocation(err error) string {
+	var metaErr *MetaError
+	if !errors.As(err, &metaErr) {
+		return "-"
+	}
+	return fmt.Sprintf("%s:%d (%s)", metaErr.File, metaErr.Line, metaErr.Func)
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -1,8 +1,12 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -348,3 +352,442 @@ func TestAppendError(t *testing.T) {
 		})
 	}
 }
+
+func TestMultiError_Fingerprint(t *testing.T) {
+	a := NewMultiError(errors.New("timeout"), errors.New("refused"), errors.New("timeout"))
+	b := NewMultiError(errors.New("refused"), errors.New("timeout"))
+	c := NewMultiError(errors.New("refused"), errors.New("not found"))
+
+	if a.Fingerprint() == "" {
+		t.Fatal("Fingerprint() should not be empty for a MultiError with errors")
+	}
+
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("Fingerprint() should ignore order and repetition, got %v and %v", a.Fingerprint(), b.Fingerprint())
+	}
+
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Error("Fingerprint() should differ for different failure sets")
+	}
+
+	var empty MultiError
+	if empty.Fingerprint() != "" {
+		t.Error("Fingerprint() should be empty for an empty MultiError")
+	}
+}
+
+func TestMultiError_LogAttrs(t *testing.T) {
+	m := NewMultiError(errors.New("plain"), NewMetaError(errors.New("meta")), fmt.Errorf("wrapped: %w", NewMetaError(errors.New("meta"))))
+
+	attrs := m.LogAttrs()
+	if len(attrs) != 3 {
+		t.Fatalf("LogAttrs() returned %d attrs, want 3", len(attrs))
+	}
+
+	if attrs[0].Key != "0" || attrs[1].Key != "1" {
+		t.Errorf("LogAttrs() keys = %v, %v, want 0, 1", attrs[0].Key, attrs[1].Key)
+	}
+
+	group := attrs[1].Value.Group()
+	keys := make(map[string]bool)
+	for _, a := range group {
+		keys[a.Key] = true
+	}
+	for _, want := range []string{"index", "message", "file", "line", "func", "package", "fingerprint"} {
+		if !keys[want] {
+			t.Errorf("LogAttrs() group for MetaError missing %q", want)
+		}
+	}
+
+	if len(attrs[2].Value.Group()) != len(group) {
+		t.Errorf("LogAttrs() group for wrapped MetaError has %d attrs, want %d", len(attrs[2].Value.Group()), len(group))
+	}
+
+	if len(attrs[0].Value.Group()) != 3 {
+		t.Errorf("LogAttrs() group for plain error has %d attrs, want 3", len(attrs[0].Value.Group()))
+	}
+
+	var empty MultiError
+	if empty.LogAttrs() != nil {
+		t.Error("LogAttrs() should be nil for an empty MultiError")
+	}
+}
+
+// TestSafeMultiError_Concurrent tests appending to a SafeMultiError from many goroutines
+func TestSafeMultiError_Concurrent(t *testing.T) {
+	var errs SafeMultiError
+	if errs.ErrorOrNil() != nil {
+		t.Error("Expected nil for an empty SafeMultiError")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs.Append(fmt.Errorf("error %d", i))
+			errs.Append(nil)
+		}(i)
+	}
+	wg.Wait()
+
+	if errs.Len() != 50 {
+		t.Errorf("Expected 50 errors, got %d", errs.Len())
+	}
+
+	snapshot := errs.Snapshot()
+	errs.Append(errors.New("late"))
+	if len(snapshot.Errors) != 50 {
+		t.Errorf("Expected snapshot to be unaffected by later appends, got %d errors", len(snapshot.Errors))
+	}
+}
+
+// TestMultiError_AppendWrapped tests that wrapped entries carry the operation and keep the original error
+func TestMultiError_AppendWrapped(t *testing.T) {
+	errNotFound := errors.New("not found")
+
+	var mErr MultiError
+	mErr.AppendWrapped("item 3", errNotFound)
+	mErr.AppendWrapped("item 4", nil)
+
+	if len(mErr.Errors) != 1 {
+		t.Fatalf("Expected 1 error, got %d", len(mErr.Errors))
+	}
+	if mErr.Error() != "item 3: not found" {
+		t.Errorf("Expected 'item 3: not found', got '%s'", mErr.Error())
+	}
+	if !errors.Is(&mErr, errNotFound) {
+		t.Error("Expected wrapped error to match with errors.Is")
+	}
+}
+
+// TestMultiError_Dedup tests collapsing repeated errors into counted entries
+func TestMultiError_Dedup(t *testing.T) {
+	errRefused := errors.New("connection refused")
+
+	mErr := NewMultiError(errRefused, errors.New("timeout"), errRefused, fmt.Errorf("connection refused"))
+	deduped := mErr.Dedup()
+
+	if len(mErr.Errors) != 4 {
+		t.Errorf("Expected Dedup to leave the original untouched, got %d errors", len(mErr.Errors))
+	}
+	if deduped.Error() != "connection refused (x3); timeout" {
+		t.Errorf("Expected 'connection refused (x3); timeout', got '%s'", deduped.Error())
+	}
+	if !errors.Is(deduped, errRefused) {
+		t.Error("Expected counted entry to unwrap to the original error")
+	}
+	if deduped.Fingerprint() != mErr.Fingerprint() {
+		t.Error("Expected Dedup to keep the fingerprint")
+	}
+
+	var unique MultiError
+	unique.AppendUnique(errRefused)
+	unique.AppendUnique(errRefused)
+	unique.AppendUnique(nil)
+	if unique.Error() != "connection refused (x2)" {
+		t.Errorf("Expected 'connection refused (x2)', got '%s'", unique.Error())
+	}
+}
+
+// TestMultiError_JSON tests that a MultiError round-trips through JSON as structured entries
+func TestMultiError_JSON(t *testing.T) {
+	mErr := NewMultiError(errors.New("plain"), NewMetaError(errors.New("located")))
+
+	data, err := json.Marshal(mErr)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("Expected a JSON array, got %s", data)
+	}
+	if len(entries) != 2 || entries[0]["message"] != "plain" || entries[0]["type"] != "*errors.errorString" {
+		t.Errorf("Unexpected entries: %s", data)
+	}
+	if entries[1]["func"] != "TestMultiError_JSON" {
+		t.Errorf("Expected MetaError fields, got %s", data)
+	}
+
+	var decoded MultiError
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded.Error() != mErr.Error() {
+		t.Errorf("Expected '%s', got '%s'", mErr.Error(), decoded.Error())
+	}
+	if _, ok := decoded.Errors[1].(*MetaError); !ok {
+		t.Errorf("Expected second entry to decode as *MetaError, got %T", decoded.Errors[1])
+	}
+}
+
+// TestMultiError_JSONWrappedAndBounded tests that wrapped MetaErrors keep their location and the dropped count
+// round-trips
+func TestMultiError_JSONWrappedAndBounded(t *testing.T) {
+	mErr := NewBoundedMultiError(1)
+	mErr.Append(fmt.Errorf("saving filing: %w", NewMetaError(errors.New("disk full"))))
+	mErr.Append(errors.New("second"))
+	mErr.Append(errors.New("third"))
+
+	data, err := json.Marshal(mErr)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("Expected a JSON array, got %s", data)
+	}
+	if len(entries) != 2 || entries[0]["func"] != "TestMultiError_JSONWrappedAndBounded" || entries[1]["dropped"] != float64(2) {
+		t.Errorf("Expected the wrapped location and a dropped entry, got %s", data)
+	}
+
+	var decoded MultiError
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(decoded.Errors) != 1 || decoded.Dropped() != 2 || decoded.Error() != mErr.Error() {
+		t.Errorf("Expected '%s' with 2 dropped, got '%s' with %d", mErr.Error(), decoded.Error(), decoded.Dropped())
+	}
+}
+
+// TestMultiError_Bounded tests that a bounded MultiError keeps the first errors and summarizes the rest
+func TestMultiError_Bounded(t *testing.T) {
+	mErr := NewBoundedMultiError(2)
+	for i := 0; i < 5; i++ {
+		mErr.Append(fmt.Errorf("error %d", i))
+	}
+
+	if len(mErr.Errors) != 2 {
+		t.Errorf("Expected 2 stored errors, got %d", len(mErr.Errors))
+	}
+	if mErr.Dropped() != 3 {
+		t.Errorf("Expected 3 dropped errors, got %d", mErr.Dropped())
+	}
+	if mErr.Error() != "error 0; error 1; ... and 3 more errors" {
+		t.Errorf("Expected overflow summary, got '%s'", mErr.Error())
+	}
+}
+
+// TestGroupedMultiError tests grouping errors by a classifier
+func TestGroupedMultiError(t *testing.T) {
+	errTimeout := errors.New("timeout")
+
+	grouped := NewGroupedMultiError(func(err error) string {
+		if errors.Is(err, errTimeout) {
+			return "network errors"
+		}
+		return "validation errors"
+	})
+
+	if grouped.ErrorOrNil() != nil {
+		t.Error("Expected nil for an empty GroupedMultiError")
+	}
+
+	grouped.Append(errTimeout)
+	grouped.Append(errors.New("missing name"))
+	grouped.Append(fmt.Errorf("fetch: %w", errTimeout))
+	grouped.Append(nil)
+
+	if grouped.Error() != "network errors: 2; validation errors: 1" {
+		t.Errorf("Expected group summary, got '%s'", grouped.Error())
+	}
+	if len(grouped.Group("network errors").Errors) != 2 {
+		t.Error("Expected 2 network errors")
+	}
+	if !errors.Is(grouped, errTimeout) {
+		t.Error("Expected errors.Is to search grouped errors")
+	}
+}
+
+// TestGroupedMultiError_Nil tests that a nil GroupedMultiError behaves as an empty one
+func TestGroupedMultiError_Nil(t *testing.T) {
+	var grouped *GroupedMultiError
+
+	if grouped.Error() != "" || grouped.Unwrap() != nil || grouped.ErrorOrNil() != nil || grouped.HasErrors() {
+		t.Error("Expected a nil GroupedMultiError to report no errors")
+	}
+	if grouped.Group("network errors") != nil || grouped.Keys() != nil || len(grouped.Counts()) != 0 {
+		t.Error("Expected a nil GroupedMultiError to have no groups")
+	}
+	if errors.Is(grouped, context.Canceled) {
+		t.Error("Expected errors.Is to find nothing in a nil GroupedMultiError")
+	}
+}
+
+// TestMultiError_FilterMap tests filtering and mapping entries into new MultiErrors
+func TestMultiError_FilterMap(t *testing.T) {
+	mErr := NewMultiError(errors.New("a"), context.Canceled, errors.New("b"))
+
+	filtered := mErr.Filter(func(err error) bool {
+		return !errors.Is(err, context.Canceled)
+	})
+	if filtered.Error() != "a; b" {
+		t.Errorf("Expected 'a; b', got '%s'", filtered.Error())
+	}
+	if len(mErr.Errors) != 3 {
+		t.Error("Expected Filter to leave the original untouched")
+	}
+
+	mapped := filtered.Map(func(err error) error {
+		return fmt.Errorf("req-1: %w", err)
+	})
+	if mapped.Error() != "req-1: a; req-1: b" {
+		t.Errorf("Expected 'req-1: a; req-1: b', got '%s'", mapped.Error())
+	}
+}
+
+// TestMultiError_Flatten tests flattening nested MultiErrors into one list
+func TestMultiError_Flatten(t *testing.T) {
+	inner := NewMultiError(errors.New("b"), NewMultiError(errors.New("c")))
+	outer := NewMultiError(errors.New("a"), inner, errors.New("d"))
+
+	flat := outer.Flatten()
+	if len(flat.Errors) != 4 {
+		t.Fatalf("Expected 4 errors, got %d", len(flat.Errors))
+	}
+	if flat.Error() != "a; b; c; d" {
+		t.Errorf("Expected 'a; b; c; d', got '%s'", flat.Error())
+	}
+
+	err := AppendErrorFlat(inner, errors.New("e"))
+	if mErr, ok := err.(*MultiError); !ok || len(mErr.Errors) != 3 {
+		t.Errorf("Expected a flat MultiError with 3 errors, got %v", err)
+	}
+}
+
+// TestFromJoined tests decomposing errors.Join output into a MultiError
+func TestFromJoined(t *testing.T) {
+	a, b, c := errors.New("a"), errors.New("b"), errors.New("c")
+
+	mErr := FromJoined(errors.Join(a, errors.Join(b, c)))
+	if len(mErr.Errors) != 3 {
+		t.Fatalf("Expected 3 errors, got %d", len(mErr.Errors))
+	}
+	if mErr.First() != a || mErr.Last() != c {
+		t.Errorf("Expected first 'a' and last 'c', got '%v' and '%v'", mErr.First(), mErr.Last())
+	}
+
+	if FromJoined(nil) != nil {
+		t.Error("Expected nil for a nil error")
+	}
+	if single := FromJoined(a); len(single.Errors) != 1 {
+		t.Errorf("Expected a single entry, got %d", len(single.Errors))
+	}
+	if (&MultiError{}).First() != nil {
+		t.Error("Expected First to return nil on an empty MultiError")
+	}
+}
+
+// TestMultiError_Merge tests that merging honours the receiver's cap and dedup settings
+func TestMultiError_Merge(t *testing.T) {
+	shard1 := NewMultiError(errors.New("timeout"), errors.New("timeout"))
+	shard2 := NewBoundedMultiError(1)
+	shard2.Append(errors.New("timeout"))
+	shard2.Append(errors.New("dropped in shard"))
+
+	job := NewBoundedMultiError(2)
+	job.Unique = true
+	job.Merge(shard1)
+	job.Merge(shard2)
+	job.Merge(nil)
+	job.AppendWrapped("shard 3", errors.New("bad row"))
+	job.Append(errors.New("over cap"))
+
+	if job.Error() != "timeout (x3); shard 3: bad row; ... and 2 more errors" {
+		t.Errorf("Unexpected merge result '%s'", job.Error())
+	}
+	if len(shard1.Errors) != 2 {
+		t.Error("Expected Merge to leave other untouched")
+	}
+}
+
+// TestMultiError_RenderTable tests the aligned table rendering of categories, locations and messages
+func TestMultiError_RenderTable(t *testing.T) {
+	m := NewBoundedMultiError(2)
+	m.Append(NewMetaError(errors.New("query failed")).WithCode("DB_TIMEOUT"))
+	m.Append(errors.New("line one\nline two"))
+	m.Append(errors.New("dropped"))
+
+	var sb strings.Builder
+	if err := m.RenderTable(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header, 2 rows and a dropped line, got %q", sb.String())
+	}
+	if !strings.HasPrefix(lines[1], "1  DB_TIMEOUT") || !strings.Contains(lines[1], "(TestMultiError_RenderTable)") {
+		t.Errorf("Expected code and location in first row, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "*errors.errorString") || !strings.HasSuffix(lines[2], "line one line two") {
+		t.Errorf("Expected type category and single-line message, got %q", lines[2])
+	}
+	if strings.Index(lines[1], "query failed") != strings.Index(lines[2], "line one") {
+		t.Error("Expected message column to be aligned")
+	}
+	if lines[3] != "... and 1 more errors" {
+		t.Errorf("Expected dropped summary, got %q", lines[3])
+	}
+}
+
+// TestMultiError_SummarizeCancellations tests collapsing context cancellations into one counted entry
+func TestMultiError_SummarizeCancellations(t *testing.T) {
+	mErr := &MultiError{SummarizeCancellations: true}
+	mErr.Append(errors.New("record 1: invalid cik"))
+	for i := 2; i < 300; i++ {
+		mErr.AppendWrapped(fmt.Sprintf("record %d", i), context.Canceled)
+	}
+	mErr.AppendWrapped("record 300", context.DeadlineExceeded)
+	mErr.Append(errors.New("record 301: duplicate filing"))
+
+	if len(mErr.Errors) != 3 || mErr.Cancellations() != 299 {
+		t.Fatalf("Expected two failures and one summary of 299, got %d entries: %v", len(mErr.Errors), mErr)
+	}
+	want := "record 1: invalid cik; 299 operations cancelled or timed out, first: record 2: context canceled; record 301: duplicate filing"
+	if mErr.Error() != want {
+		t.Errorf("Expected %q, got %q", want, mErr.Error())
+	}
+	if !errors.Is(mErr, context.Canceled) {
+		t.Error("Expected the summary to unwrap to the first cancellation")
+	}
+
+	plain := NewMultiError(context.Canceled, errors.New("boom"), context.Canceled)
+	collapsed := plain.CollapseCancellations()
+	if len(collapsed.Errors) != 2 || collapsed.Cancellations() != 2 || len(plain.Errors) != 3 {
+		t.Errorf("Expected CollapseCancellations to return a collapsed copy, got %v", collapsed)
+	}
+
+	merged := &MultiError{SummarizeCancellations: true}
+	merged.Append(context.Canceled)
+	merged.Merge(collapsed)
+	if merged.Cancellations() != 3 {
+		t.Errorf("Expected merged summaries to add up, got %d", merged.Cancellations())
+	}
+}
+
+// TestFormatChain tests rendering wrapped and joined errors as a tree
+func TestFormatChain(t *testing.T) {
+	metaErr := NewMetaError(fmt.Errorf("fetch 0001: %w", errors.New("timeout")))
+	mErr := NewMultiError(metaErr, fmt.Errorf("parse 0002: %w", errors.New("bad json")))
+	err := fmt.Errorf("sync filings: %w", mErr)
+
+	want := strings.Join([]string{
+		"*fmt.wrapError: sync filings: fetch 0001: timeout; parse 0002: bad json",
+		"└─ *app.MultiError: fetch 0001: timeout; parse 0002: bad json",
+		fmt.Sprintf("   ├─ *app.MetaError: fetch 0001: timeout [errors_test.go:%d (TestFormatChain) github.com/mhpenta/app]", metaErr.Line),
+		"   │  └─ *fmt.wrapError: fetch 0001: timeout",
+		"   │     └─ *errors.errorString: timeout",
+		"   └─ *fmt.wrapError: parse 0002: bad json",
+		"      └─ *errors.errorString: bad json",
+	}, "\n")
+	if got := FormatChain(err); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	if FormatChain(nil) != "<nil>" {
+		t.Error("Expected <nil> for a nil error")
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"errors"
+)
+
+// Exit codes returned by ExitCode. They follow sysexits.h where a matching code exists, so orchestrators can tell
+// failure classes apart from the exit status alone.
+const (
+	ExitOK          = 0
+	ExitInternal    = 1
+	ExitUnavailable = 69
+	ExitConfig      = 78
+	ExitCancelled   = 130
+)
+
+var (
+	// ErrConfig marks errors caused by invalid or missing configuration. Wrap it to exit with ExitConfig.
+	ErrConfig = errors.New("configuration error")
+	// ErrDependencyUnavailable marks errors caused by a required dependency being unreachable. Wrap it to exit with
+	// ExitUnavailable.
+	ErrDependencyUnavailable = errors.New("dependency unavailable")
+)
+
+// ExitCoder is implemented by errors that choose their own process exit code.
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// ExitCode maps err to a stable process exit code: ExitOK for nil, the code of the first ExitCoder in the chain,
+// ExitConfig for ErrConfig, ExitUnavailable for ErrDependencyUnavailable, ExitCancelled for context cancellation and
+// ExitInternal for everything else, including panics.
+//
+// Example usage:
+//
+//	return fmt.Errorf("%w: DATABASE_URL is not set", app.ErrConfig)
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+
+	switch {
+	case errors.Is(err, ErrPanic):
+		return ExitInternal
+	case errors.Is(err, ErrConfig):
+		return ExitConfig
+	case errors.Is(err, ErrDependencyUnavailable):
+		return ExitUnavailable
+	case errors.Is(err, context.Canceled) || errors.Is(err, ErrContextCancelled):
+		return ExitCancelled
+	}
+	return ExitInternal
+}
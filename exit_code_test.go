@@ -0,0 +1,30 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestExitCode tests mapping errors to process exit codes
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"config", fmt.Errorf("%w: missing DATABASE_URL", ErrConfig), ExitConfig},
+		{"unavailable", NewMetaError(fmt.Errorf("postgres: %w", ErrDependencyUnavailable)), ExitUnavailable},
+		{"cancelled", fmt.Errorf("shutdown: %w", context.Canceled), ExitCancelled},
+		{"panic", FromPanic("boom"), ExitInternal},
+		{"other", errors.New("boom"), ExitInternal},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("%s: Expected exit code %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
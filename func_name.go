@@ -1,9 +1,126 @@
 package app
 
 import (
+	"runtime"
 	"strings"
 )
 
+// FuncInfo describes a fully qualified function name as reported by runtime.Func.Name or runtime.Frame.Function.
+type FuncInfo struct {
+	// PkgPath is the import path of the package, e.g. "github.com/mhpenta/app"
+	PkgPath string `json:"pkgPath"`
+	// Receiver is the receiver type name of a method, without the pointer and type parameters
+	Receiver string `json:"receiver,omitempty"`
+	// Ptr reports whether the method has a pointer receiver
+	Ptr bool `json:"ptr,omitempty"`
+	// TypeGeneric is the type argument list of a generic receiver, without brackets
+	TypeGeneric string `json:"typeGeneric,omitempty"`
+	// FuncGeneric is the type argument list of a generic function, without brackets
+	FuncGeneric string `json:"funcGeneric,omitempty"`
+	// Name is the function or method name. For anonymous functions it is the enclosing named function.
+	Name string `json:"name"`
+	// IsAnonymous reports whether the name is that of a function literal
+	IsAnonymous bool `json:"isAnonymous,omitempty"`
+	// Qualifier is the receiver type, or the package level function that encloses the function when the name does
+	// not tell the two apart
+	Qualifier string `json:"qualifier,omitempty"`
+	// File and Line locate the call, set by Caller only
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// Caller describes the function skip frames above the caller of Caller, with the file and line of the call; Caller(0)
+// describes the function calling Caller, like runtime.Caller. Runtime frames such as runtime.gopanic are passed over,
+// so callers inside deferred recovery code resolve to the function that panicked. Names that cannot be parsed fall
+// back to splitting on the last dot. A zero FuncInfo is returned when there is no such frame.
+//
+// Example usage:
+//
+//	func logRequest(r *http.Request) {
+//		caller := app.Caller(1)
+//		slog.Info("Request handled", "path", r.URL.Path, "func", caller.Name, "file", caller.File, "line", caller.Line)
+//	}
+func Caller(skip int) FuncInfo {
+	info, _ := caller(skip + 2)
+	return info
+}
+
+// caller implements Caller, counting skip from caller itself, so caller(1) describes the function calling it. It also
+// returns the number of runtime frames passed over.
+func caller(skip int) (FuncInfo, int) {
+	frame, skipped, ok := callerFrame(skip + 1)
+	if !ok {
+		return FuncInfo{}, 0
+	}
+	info := funcInfo(frame.Function)
+	info.File = frame.File
+	info.Line = frame.Line
+	return info, skipped
+}
+
+// callerFrame returns the frame skip frames above it, as counted by runtime.Caller, passing over runtime frames such
+// as runtime.gopanic and runtime.sigpanic so errors created while unwinding a panic point at user code. skipped is
+// the number of runtime frames passed over.
+func callerFrame(skip int) (frame runtime.Frame, skipped int, ok bool) {
+	var pcs [8]uintptr
+	// runtime.Callers counts itself as frame 0, one more than runtime.Caller
+	n := runtime.Callers(skip+1, pcs[:])
+	if n == 0 {
+		return runtime.Frame{}, 0, false
+	}
+
+	frames := runtime.CallersFrames(pcs[:n])
+	first, more := frames.Next()
+	for frame, i := first, 0; ; i++ {
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			return frame, i, true
+		}
+		if !more {
+			return first, 0, true
+		}
+		frame, more = frames.Next()
+	}
+}
+
+// funcInfo parses fullName with ParseFuncName, falling back to splitting on the last dot when it cannot be parsed.
+func funcInfo(fullName string) FuncInfo {
+	info := ParseFuncName(fullName)
+	if info.Name != "" && info.PkgPath != "" {
+		return info
+	}
+	if i := strings.LastIndex(fullName, "."); i != -1 {
+		return FuncInfo{PkgPath: fullName[:i], Name: fullName[i+1:]}
+	}
+	return FuncInfo{Name: fullName}
+}
+
+// ParseFuncName parses a fully qualified function name such as "github.com/mhpenta/app.(*MetaError).Error" into
+// its parts. Names that cannot be parsed return a FuncInfo with an empty PkgPath or Name.
+//
+// Example usage:
+//
+//	fn := runtime.FuncForPC(pc)
+//	info := app.ParseFuncName(fn.Name())
+//	slog.Info("Handler finished", "package", info.PkgPath, "receiver", info.Receiver, "func", info.Name)
+func ParseFuncName(fullName string) FuncInfo {
+	pkgPath, qualifier, recvPtr, typeGeneric, funcGeneric, funcName, notice := parseFuncName(fullName)
+
+	info := FuncInfo{
+		PkgPath:     pkgPath,
+		Receiver:    qualifier,
+		Ptr:         recvPtr,
+		TypeGeneric: typeGeneric,
+		FuncGeneric: funcGeneric,
+		Name:        funcName,
+		IsAnonymous: strings.Contains(notice, "anonymous function"),
+		Qualifier:   qualifier,
+	}
+	if strings.Contains(notice, "Calling function on package") {
+		info.Receiver = ""
+	}
+	return info
+}
+
 // parseFuncName parses the full function name to get the package path, receiver name, receiver pointer, type generic, function generic, and function name.
 //
 // Qualifier is the receiver name or the package level function which called the function, depending on the situation.
@@ -16,15 +133,26 @@ import (
 // a/b/c.Z
 // a/b/c.Z[].X
 // a/b/c.X[]
+// a/b/c.A.func1.2
+// a/b/c.(*C).X-fm
+// a/b/c.A.deferwrap1
 //
 // parse process:
 //
+//	closure and method value suffixes
 //	funcGeneric
 //	funcName
 //	recvType
 //	recvGeneric
 //	pkgPath
 func parseFuncName(fullName string) (pkgPath string, qualifier string, recvPtr bool, typeGeneric string, funcGeneric string, funcName string, notice string) {
+	// method values, nested closures and compiler wrappers resolve to the nearest named function
+	fullName = strings.TrimSuffix(fullName, "-fm")
+	if trimmed, ok := trimClosureSuffix(fullName); ok {
+		fullName = trimmed
+		notice = "anonymous function"
+	}
+
 	sepIdx := strings.LastIndex(fullName, "/")
 	pkgRecvFuncNoGeneric := fullName
 
@@ -159,6 +287,42 @@ func parseFuncName(fullName string) (pkgPath string, qualifier string, recvPtr b
 	return
 }
 
+// trimClosureSuffix removes the trailing name segments the compiler gives function literals ("func1", "2" for a
+// closure nested in it), go and defer statement wrappers ("gowrap1", "deferwrap1"), so that only the enclosing named
+// function remains. It reports whether anything was removed.
+func trimClosureSuffix(fullName string) (string, bool) {
+	trimmed := false
+	for {
+		dot := strings.LastIndex(fullName, ".")
+		if dot < 0 || dot < strings.LastIndex(fullName, "/") {
+			return fullName, trimmed
+		}
+		segment := fullName[dot+1:]
+		if !isDigits(segment) && !isNumberedName(segment, "func") && !isNumberedName(segment, "gowrap") &&
+			!isNumberedName(segment, "deferwrap") {
+			return fullName, trimmed
+		}
+		fullName = fullName[:dot]
+		trimmed = true
+	}
+}
+
+func isNumberedName(segment string, prefix string) bool {
+	return strings.HasPrefix(segment, prefix) && isDigits(segment[len(prefix):])
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 func isAnonymousFuncName(funcName string) bool {
 
 	if strings.Contains(funcName, ".func") {
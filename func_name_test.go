@@ -111,3 +111,51 @@ func TestIsGoPackageURLPattern(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFuncNameInfo(t *testing.T) {
+	tests := []struct {
+		fullName string
+		want     FuncInfo
+	}{
+		{"github.com/mhpenta/app.(*MetaError).Error", FuncInfo{PkgPath: "github.com/mhpenta/app", Receiver: "MetaError", Ptr: true, Name: "Error", Qualifier: "MetaError"}},
+		{"github.com/mhpenta/app.Safe[int]", FuncInfo{PkgPath: "github.com/mhpenta/app", FuncGeneric: "int", Name: "Safe"}},
+		{"a/b/c.(*C).X.func1", FuncInfo{PkgPath: "a/b/c", Receiver: "C", Ptr: true, Name: "X", IsAnonymous: true, Qualifier: "C"}},
+		{"main.main.func1", FuncInfo{PkgPath: "main", Name: "main", IsAnonymous: true}},
+		{"example/pkg.Outer.T.M", FuncInfo{PkgPath: "example/pkg", Name: "M", Qualifier: "Outer"}},
+		{"github.com/mhpenta/app.(*Queue).run-fm", FuncInfo{PkgPath: "github.com/mhpenta/app", Receiver: "Queue", Ptr: true, Name: "run", Qualifier: "Queue"}},
+		{"github.com/mhpenta/app.Run.func1.2.1", FuncInfo{PkgPath: "github.com/mhpenta/app", Name: "Run", IsAnonymous: true}},
+		{"github.com/mhpenta/app.(*Server).Serve.func3.2", FuncInfo{PkgPath: "github.com/mhpenta/app", Receiver: "Server", Ptr: true, Name: "Serve", IsAnonymous: true, Qualifier: "Server"}},
+		{"github.com/mhpenta/app.Supervise.deferwrap1", FuncInfo{PkgPath: "github.com/mhpenta/app", Name: "Supervise", IsAnonymous: true}},
+		{"github.com/mhpenta/app.Watch[...].gowrap2", FuncInfo{PkgPath: "github.com/mhpenta/app", FuncGeneric: "...", Name: "Watch", IsAnonymous: true}},
+		{"runtime.gopanic", FuncInfo{PkgPath: "runtime", Name: "gopanic"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fullName, func(t *testing.T) {
+			if got := ParseFuncName(tt.fullName); got != tt.want {
+				t.Errorf("ParseFuncName(%q) = %+v; want %+v", tt.fullName, got, tt.want)
+			}
+		})
+	}
+}
+
+type callerProbe struct{}
+
+func (*callerProbe) where() FuncInfo {
+	return Caller(0)
+}
+
+func TestCaller(t *testing.T) {
+	info := (&callerProbe{}).where()
+	if info.PkgPath != "github.com/mhpenta/app" || info.Receiver != "callerProbe" || !info.Ptr || info.Name != "where" {
+		t.Errorf("Expected (*callerProbe).where, got %+v", info)
+	}
+	if !strings.HasSuffix(info.File, "func_name_test.go") || info.Line == 0 {
+		t.Errorf("Expected the file and line of the call, got %s:%d", info.File, info.Line)
+	}
+
+	info = func() FuncInfo { return Caller(1) }()
+	if info.Name != "TestCaller" || info.IsAnonymous {
+		t.Errorf("Expected Caller(1) to describe TestCaller, got %+v", info)
+	}
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GroupedMultiError buckets appended errors by the key returned from a classifier, so a batch failure can be reported
+// as "network errors: 5; validation errors: 2" and each group handled separately.
+//
+// Example usage:
+//
+//	grouped := app.NewGroupedMultiError(func(err error) string {
+//		if httpext.IsTransientNetworkOrDNSIssueErr(err) {
+//			return "network errors"
+//		}
+//		return "other errors"
+//	})
+//	for _, item := range items {
+//		grouped.Append(process(item))
+//	}
+//	retryLater(grouped.Group("network errors"))
+type GroupedMultiError struct {
+	classify func(error) string
+	groups   map[string]*MultiError
+	keys     []string
+}
+
+// NewGroupedMultiError creates a GroupedMultiError that groups errors by classify.
+func NewGroupedMultiError(classify func(error) string) *GroupedMultiError {
+	return &GroupedMultiError{
+		classify: classify,
+		groups:   make(map[string]*MultiError),
+	}
+}
+
+// Append adds err to the group named by the classifier. Nil errors are ignored.
+func (g *GroupedMultiError) Append(err error) {
+	if err == nil {
+		return
+	}
+
+	key := g.classify(err)
+	group, ok := g.groups[key]
+	if !ok {
+		group = NewMultiError()
+		g.groups[key] = group
+		g.keys = append(g.keys, key)
+	}
+	group.Append(err)
+}
+
+// Group returns the errors classified under key, or nil if there are none.
+func (g *GroupedMultiError) Group(key string) *MultiError {
+	if g == nil {
+		return nil
+	}
+	return g.groups[key]
+}
+
+// Keys returns the group keys in the order they were first seen.
+func (g *GroupedMultiError) Keys() []string {
+	if g == nil {
+		return nil
+	}
+	keys := make([]string, len(g.keys))
+	copy(keys, g.keys)
+	return keys
+}
+
+// Counts returns the number of errors in each group.
+func (g *GroupedMultiError) Counts() map[string]int {
+	if g == nil {
+		return map[string]int{}
+	}
+	counts := make(map[string]int, len(g.groups))
+	for key, group := range g.groups {
+		counts[key] = len(group.Errors)
+	}
+	return counts
+}
+
+// Error summarizes the groups as "key: count" in first-seen order.
+func (g *GroupedMultiError) Error() string {
+	if g == nil {
+		return ""
+	}
+	sb := strings.Builder{}
+	for i, key := range g.keys {
+		if i > 0 {
+			sb.WriteString(separator)
+		}
+		sb.WriteString(key)
+		sb.WriteString(": ")
+		sb.WriteString(strconv.Itoa(len(g.groups[key].Errors)))
+	}
+	return sb.String()
+}
+
+// ErrorOrNil returns nil if no errors were appended, or the GroupedMultiError otherwise.
+func (g *GroupedMultiError) ErrorOrNil() error {
+	if g == nil || len(g.keys) == 0 {
+		return nil
+	}
+	return g
+}
+
+// HasErrors reports whether any error was appended.
+func (g *GroupedMultiError) HasErrors() bool {
+	return g != nil && len(g.keys) > 0
+}
+
+// Unwrap returns one *MultiError per group, so errors.Is and errors.As search every grouped error.
+func (g *GroupedMultiError) Unwrap() []error {
+	if g == nil || len(g.keys) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(g.keys))
+	for _, key := range g.keys {
+		errs = append(errs, g.groups[key])
+	}
+	return errs
+}
@@ -0,0 +1,6 @@
+// Package grpcext converts between app.MetaError and gRPC status values.
+//
+// It is a separate module so that applications not using gRPC do not depend on google.golang.org/grpc:
+//
+//	go get github.com/mhpenta/app/grpcext
+package grpcext
@@ -0,0 +1,14 @@
+module github.com/mhpenta/app/grpcext
+
+go 1.26.0
+
+require (
+	github.com/mhpenta/app v0.0.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260921155816-b14227669459
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.12
+)
+
+require golang.org/x/sys v0.47.0 // indirect
+
+replace github.com/mhpenta/app => ../
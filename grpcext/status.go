@@ -0,0 +1,278 @@
+package grpcext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrorInfoDomain is the domain set on the ErrorInfo detail written by ToGRPCStatus.
+var ErrorInfoDomain = "github.com/mhpenta/app"
+
+// Metadata keys of the ErrorInfo detail. Fields attached with WithField are stored under FieldPrefix + key.
+const (
+	MetadataFile     = "file"
+	MetadataLine     = "line"
+	MetadataFunc     = "func"
+	MetadataPackage  = "package"
+	MetadataCategory = "category"
+	MetadataSeverity = "severity"
+	MetadataError    = "error"
+	FieldPrefix      = "field."
+)
+
+// ToGRPCStatus converts err to a gRPC status. The code is derived from the error (see CodeFor). An ErrorInfo detail
+// carries the error code, category and severity of err, the qualified name of the error created with app.Define in
+// its chain and, when err contains a MetaError, its capture site and fields; the stack trace goes in a DebugInfo
+// detail.
+// ToGRPCStatus returns nil for a nil err.
+//
+// Example usage:
+//
+//	func (s *server) GetFiling(ctx context.Context, req *pb.GetFilingRequest) (*pb.Filing, error) {
+//		filing, err := s.store.Filing(ctx, req.Id)
+//		if err != nil {
+//			return nil, grpcext.ToGRPCStatus(err).Err()
+//		}
+//		return filing, nil
+//	}
+func ToGRPCStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+	if st, ok := status.FromError(err); ok && st.Code() != codes.Unknown {
+		return st
+	}
+
+	st := status.New(CodeFor(err), err.Error())
+
+	info := &errdetails.ErrorInfo{
+		Reason: app.CodeOf(err),
+		Domain: ErrorInfoDomain,
+		Metadata: map[string]string{
+			MetadataCategory: string(app.CategoryOf(err)),
+			MetadataSeverity: string(app.SeverityOf(err)),
+		},
+	}
+	var definedErr *app.Error
+	if errors.As(err, &definedErr) {
+		info.Metadata[MetadataError] = definedErr.QualifiedName()
+	}
+
+	metaErr, ok := app.AsMetaError(err)
+	if !ok {
+		return withDetails(st, info)
+	}
+
+	info.Metadata[MetadataFile] = metaErr.File
+	info.Metadata[MetadataLine] = strconv.Itoa(metaErr.Line)
+	info.Metadata[MetadataFunc] = metaErr.Func
+	info.Metadata[MetadataPackage] = metaErr.Package
+	for k, v := range metaErr.Fields {
+		info.Metadata[FieldPrefix+k] = fmt.Sprint(v)
+	}
+
+	debug := &errdetails.DebugInfo{Detail: metaErr.Error()}
+	for _, frame := range metaErr.Frames() {
+		debug.StackEntries = append(debug.StackEntries, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+	}
+	return withDetails(st, info, debug)
+}
+
+// withDetails returns st with details attached, or st itself if they cannot be encoded.
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) *status.Status {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// FromGRPCStatus rebuilds a MetaError from a status produced by ToGRPCStatus, restoring the capture site, fields and
+// error code of the remote error. When the remote error was created with app.Define in a package also linked into
+// this process, the result wraps the local definition with the same qualified name, so errors.Is, app.CategoryOf
+// and app.SeverityOf match the remote error; otherwise the remote category and severity are kept as the "category" and "severity" fields. The HTTP
+// status matching the gRPC code is set with WithHTTPStatus, and Unavailable statuses are marked retryable.
+// FromGRPCStatus returns nil for a nil or OK status.
+func FromGRPCStatus(st *status.Status) *app.MetaError {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+
+	metaErr := &app.MetaError{Err: errors.New(st.Message())}
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok {
+			continue
+		}
+		if info.Reason != "" {
+			metaErr.WithCode(info.Reason)
+		}
+
+		definedErr, defined := app.LookupError(info.Metadata[MetadataError])
+		if defined {
+			metaErr.Err = &remoteError{message: st.Message(), defined: definedErr}
+		}
+
+		for k, v := range info.Metadata {
+			switch {
+			case k == MetadataFile:
+				metaErr.File = v
+			case k == MetadataLine:
+				metaErr.Line, _ = strconv.Atoi(v)
+			case k == MetadataFunc:
+				metaErr.Func = v
+			case k == MetadataPackage:
+				metaErr.Package = v
+			case (k == MetadataCategory || k == MetadataSeverity) && !defined:
+				metaErr.WithField(k, v)
+			case strings.HasPrefix(k, FieldPrefix):
+				metaErr.WithField(strings.TrimPrefix(k, FieldPrefix), v)
+			}
+		}
+	}
+
+	metaErr.WithHTTPStatus(httpStatusFor(st.Code()))
+	if st.Code() == codes.Unavailable {
+		app.MarkRetryable(metaErr)
+	}
+	return metaErr
+}
+
+// remoteError keeps the message of a remote error while wrapping the local definition of its sentinel.
+type remoteError struct {
+	message string
+	defined *app.Error
+}
+
+func (e *remoteError) Error() string {
+	return e.message
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.defined
+}
+
+// CodeFor maps err to a gRPC code. Context errors map to Canceled and DeadlineExceeded, then a specific HTTP status
+// set with app.WithHTTPStatus or app.Define, explicit retryability and app.CategoryOf are consulted. The remaining
+// errors are mapped by severity: Unknown for SeverityWarning, Internal for SeverityFatal, such as panics, and for
+// errors carrying a 500 status, Unknown otherwise.
+func CodeFor(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+
+	httpStatus, hasStatus := app.HTTPStatus(err)
+	if hasStatus && httpStatus != http.StatusInternalServerError {
+		return codeForHTTPStatus(httpStatus)
+	}
+	if retryable, marked := app.IsRetryable(err); marked && retryable {
+		return codes.Unavailable
+	}
+
+	switch app.CategoryOf(err) {
+	case app.CategoryNotFound:
+		return codes.NotFound
+	case app.CategoryInvalid:
+		return codes.InvalidArgument
+	case app.CategoryConflict:
+		return codes.AlreadyExists
+	case app.CategoryUnauthorized:
+		return codes.Unauthenticated
+	case app.CategoryForbidden:
+		return codes.PermissionDenied
+	case app.CategoryRateLimited:
+		return codes.ResourceExhausted
+	case app.CategoryUnavailable:
+		return codes.Unavailable
+	case app.CategoryTimeout:
+		return codes.DeadlineExceeded
+	case app.CategoryCancelled:
+		return codes.Canceled
+	case app.CategoryConfig:
+		return codes.FailedPrecondition
+	}
+
+	switch severity := app.SeverityOf(err); {
+	case severity == app.SeverityWarning:
+		return codes.Unknown
+	case severity == app.SeverityFatal || hasStatus:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+func codeForHTTPStatus(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case 499:
+		return codes.Canceled
+	}
+	if httpStatus >= 500 {
+		return codes.Internal
+	}
+	if httpStatus >= 400 {
+		return codes.FailedPrecondition
+	}
+	return codes.Unknown
+}
+
+func httpStatusFor(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,129 @@
+package grpcext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+	"testing"
+)
+
+var (
+	errFilingNotFound = app.Define("ErrFilingNotFound", app.CategoryNotFound)
+	errLedgerCorrupt  = app.Define("ErrLedgerCorrupt", app.CategoryInternal).WithSeverity(app.SeverityFatal)
+	errCacheStale     = app.Define("ErrCacheStale", app.CategoryInternal).WithSeverity(app.SeverityWarning)
+	errQuotaExceeded  = app.Define("ErrQuotaExceeded", app.CategoryRateLimited).WithSeverity(app.SeverityError)
+)
+
+// overTheWire serializes st the way a gRPC server does and decodes it on the client side
+func overTheWire(t *testing.T, st *status.Status) *status.Status {
+	t.Helper()
+	data, err := proto.Marshal(st.Proto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := st.Proto()
+	proto.Reset(decoded)
+	if err := proto.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	return status.FromProto(decoded)
+}
+
+// TestCodeFor tests mapping errors to gRPC codes by context, HTTP status, retryability, category and severity
+func TestCodeFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"nil", nil, codes.OK},
+		{"cancelled", fmt.Errorf("query: %w", context.Canceled), codes.Canceled},
+		{"deadline", app.NewMetaError(context.DeadlineExceeded), codes.DeadlineExceeded},
+		{"http status", app.NewMetaError(errors.New("no such filing")).WithHTTPStatus(http.StatusNotFound), codes.NotFound},
+		{"retryable", app.MarkRetryable(errors.New("lock contention")), codes.Unavailable},
+		{"config", fmt.Errorf("%w: missing DSN", app.ErrConfig), codes.FailedPrecondition},
+		{"dependency", fmt.Errorf("postgres: %w", app.ErrDependencyUnavailable), codes.Unavailable},
+		{"defined category", fmt.Errorf("%w: 0000320193", errFilingNotFound), codes.NotFound},
+		{"defined rate limit", errQuotaExceeded, codes.ResourceExhausted},
+		{"fatal severity", errLedgerCorrupt, codes.Internal},
+		{"warning severity", errCacheStale, codes.Unknown},
+		{"panic", app.FromPanic("nil map write"), codes.Internal},
+		{"plain", errors.New("boom"), codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeFor(tt.err); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestStatusRoundTrip tests that location, fields, code, category and severity survive a trip through a status
+func TestStatusRoundTrip(t *testing.T) {
+	original := app.NewMetaError(fmt.Errorf("%w: 0000320193", errQuotaExceeded)).
+		WithCode("QUOTA").
+		WithField("cik", "0000320193")
+
+	st := overTheWire(t, ToGRPCStatus(original))
+	if st.Code() != codes.ResourceExhausted || st.Message() != original.Error() {
+		t.Fatalf("Expected ResourceExhausted with the error message, got %s %q", st.Code(), st.Message())
+	}
+
+	remote := FromGRPCStatus(st)
+	if remote.Error() != original.Error() {
+		t.Errorf("Expected message %q, got %q", original.Error(), remote.Error())
+	}
+	if remote.File != original.File || remote.Line != original.Line || remote.Func != original.Func || remote.Package != original.Package {
+		t.Errorf("Expected location %s:%d %s, got %s:%d %s", original.File, original.Line, original.Func, remote.File, remote.Line, remote.Func)
+	}
+	if app.CodeOf(remote) != "QUOTA" || remote.Fields["cik"] != "0000320193" {
+		t.Errorf("Expected code and fields restored, got %q %v", app.CodeOf(remote), remote.Fields)
+	}
+	if !errors.Is(remote, errQuotaExceeded) || app.SeverityOf(remote) != app.SeverityError || app.CategoryOf(remote) != app.CategoryRateLimited {
+		t.Errorf("Expected the defined error with its overridden severity, got %s %s", app.CategoryOf(remote), app.SeverityOf(remote))
+	}
+	if CodeFor(remote) != codes.ResourceExhausted {
+		t.Errorf("Expected the rebuilt error to map back to ResourceExhausted, got %s", CodeFor(remote))
+	}
+	if status, _ := app.HTTPStatus(remote); status != http.StatusTooManyRequests {
+		t.Errorf("Expected HTTP status 429, got %d", status)
+	}
+}
+
+// TestStatusRoundTripUndefined tests errors not created with app.Define, nil values and foreign statuses
+func TestStatusRoundTripUndefined(t *testing.T) {
+	st := overTheWire(t, ToGRPCStatus(app.FromPanic("nil map write")))
+	remote := FromGRPCStatus(st)
+	if st.Code() != codes.Internal || remote.Fields[MetadataSeverity] != string(app.SeverityFatal) ||
+		remote.Fields[MetadataCategory] != string(app.CategoryInternal) {
+		t.Errorf("Expected severity and category kept as fields, got %s %v", st.Code(), remote.Fields)
+	}
+
+	st = overTheWire(t, ToGRPCStatus(fmt.Errorf("postgres: %w", app.ErrDependencyUnavailable)))
+	remote = FromGRPCStatus(st)
+	if retryable, marked := app.IsRetryable(remote); st.Code() != codes.Unavailable || !retryable || !marked {
+		t.Errorf("Expected an Unavailable status rebuilt as retryable, got %s", st.Code())
+	}
+	if len(st.Details()) != 1 || remote.Fields[MetadataSeverity] != string(app.SeverityError) {
+		t.Errorf("Expected only an ErrorInfo detail for a plain error, got %v %v", st.Details(), remote.Fields)
+	}
+
+	foreign := status.New(codes.PermissionDenied, "denied")
+	if ToGRPCStatus(foreign.Err()).Code() != codes.PermissionDenied {
+		t.Error("Expected a status error to be passed through")
+	}
+	if remote := FromGRPCStatus(foreign); remote.Error() != "denied" || len(remote.Fields) != 0 {
+		t.Errorf("Expected a status without details rebuilt from its message, got %v", remote)
+	}
+
+	if ToGRPCStatus(nil) != nil || FromGRPCStatus(nil) != nil || FromGRPCStatus(status.New(codes.OK, "")) != nil {
+		t.Error("Expected nil for nil errors and OK statuses")
+	}
+}
@@ -0,0 +1,49 @@
+package httpext
+
+import (
+	"encoding/json"
+	"github.com/mhpenta/app"
+	"log/slog"
+	"net/http"
+)
+
+// NewAdminMux returns a mux for operational endpoints, with the breaker states already exposed at /breakers. Other
+// packages add their own routes, e.g. retry.RegisterAdminRoutes. Debugging routes should be wrapped with AdminOnly;
+// routes operators need in production, such as retry.RegisterKillSwitchRoute, need their own authentication.
+//
+// Example usage:
+//
+//	mux := httpext.NewAdminMux()
+//	retry.RegisterAdminRoutes(mux)
+//	go http.ListenAndServe("localhost:6061", mux)
+func NewAdminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/breakers", AdminOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, BadRequestError, http.StatusMethodNotAllowed)
+			return
+		}
+		WriteJSON(w, http.StatusOK, BreakerStates())
+	})))
+	return mux
+}
+
+// AdminOnly rejects requests with 403 Forbidden unless the application runs in app.DebugMode or app.DevMode.
+func AdminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.Mode != app.DebugMode && app.Mode != app.DevMode {
+			http.Error(w, "admin endpoints are disabled in "+string(app.Mode)+" mode", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WriteJSON writes v as a JSON response with the given status code.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error writing JSON response", "err", err)
+	}
+}
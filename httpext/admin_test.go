@@ -0,0 +1,50 @@
+package httpext
+
+import (
+	"encoding/json"
+	"github.com/mhpenta/app"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAdminMux tests that admin routes are only served in debug and dev mode
+func TestAdminMux(t *testing.T) {
+	previous := app.Mode
+	t.Cleanup(func() {
+		app.Mode = previous
+	})
+	BreakerFor(t.Name())
+	mux := NewAdminMux()
+
+	app.Mode = app.ReleaseMode
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/breakers", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 in release mode, got %d", rec.Code)
+	}
+
+	app.Mode = app.DevMode
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/breakers", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/breakers", nil))
+	var states []BreakerSnapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected a JSON list of breakers, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON content type, got %q", rec.Header().Get("Content-Type"))
+	}
+	found := false
+	for _, state := range states {
+		found = found || state.Name == t.Name()
+	}
+	if !found {
+		t.Errorf("Expected breaker %s in %+v", t.Name(), states)
+	}
+}
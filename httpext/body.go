@@ -0,0 +1,202 @@
+package httpext
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrTruncatedBody is returned when a response body ends before the length announced by Content-Length. It is
+	// treated as a transient error by IsTransientNetworkOrDNSIssueErr.
+	ErrTruncatedBody = errors.New("response body truncated")
+
+	// ErrBodyDigestMismatch is returned when a response body does not match the digest announced in its headers.
+	ErrBodyDigestMismatch = errors.New("response body digest mismatch")
+)
+
+// ReadVerifiedBody reads and closes resp.Body, verifying that the number of bytes received matches Content-Length.
+// When the response carries a Repr-Digest, Digest or Content-MD5 header with a supported algorithm (sha-256, sha-512,
+// md5), the body is also checked against it.
+//
+// A short body returns an error wrapping ErrTruncatedBody, so truncated downloads fail here, where they can be
+// retried, instead of later in JSON parsing. Digests are not checked when the transport transparently decompressed
+// the body, since the header then describes the compressed representation.
+func ReadVerifiedBody(resp *http.Response) ([]byte, error) {
+	if resp == nil || resp.Body == nil {
+		return nil, nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return body, fmt.Errorf("%w: received %d of %d bytes: %w", ErrTruncatedBody, len(body), resp.ContentLength, err)
+		}
+		return body, err
+	}
+
+	if resp.ContentLength >= 0 && int64(len(body)) != resp.ContentLength {
+		if int64(len(body)) < resp.ContentLength {
+			return body, fmt.Errorf("%w: received %d of %d bytes", ErrTruncatedBody, len(body), resp.ContentLength)
+		}
+		return body, fmt.Errorf("response body longer than Content-Length: received %d of %d bytes", len(body), resp.ContentLength)
+	}
+
+	if !resp.Uncompressed {
+		if err := verifyDigest(resp.Header, body); err != nil {
+			return body, err
+		}
+	}
+
+	return body, nil
+}
+
+// verifyDigest checks body against the first supported digest found in the headers.
+func verifyDigest(header http.Header, body []byte) error {
+	// Repr-Digest (RFC 9530): sha-256=:base64:, sha-512=:base64:
+	if v := header.Get("Repr-Digest"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			alg, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok {
+				continue
+			}
+			if h := digestHash(alg); h != nil {
+				return compareDigest(alg, h, strings.Trim(value, ":"), body)
+			}
+		}
+	}
+
+	// Digest (RFC 3230): SHA-256=base64
+	if v := header.Get("Digest"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			alg, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok {
+				continue
+			}
+			if h := digestHash(alg); h != nil {
+				return compareDigest(alg, h, value, body)
+			}
+		}
+	}
+
+	if v := header.Get("Content-MD5"); v != "" {
+		return compareDigest("md5", md5.New(), v, body)
+	}
+
+	return nil
+}
+
+func digestHash(alg string) hash.Hash {
+	switch strings.ToLower(alg) {
+	case "sha-256":
+		return sha256.New()
+	case "sha-512":
+		return sha512.New()
+	case "md5":
+		return md5.New()
+	}
+	return nil
+}
+
+func compareDigest(alg string, h hash.Hash, encoded string, body []byte) error {
+	expected, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: invalid %s digest value %q", ErrBodyDigestMismatch, alg, encoded)
+	}
+
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return fmt.Errorf("%w: %s digest does not match received body", ErrBodyDigestMismatch, alg)
+	}
+	return nil
+}
+
+// maxDrainBytes bounds what ReadBodyLimited discards from an oversized body so its connection can be reused. Larger
+// remainders are cheaper to drop along with the connection.
+const maxDrainBytes = 64 << 10
+
+// BodyReadError is returned by ReadBodyLimited when reading the body fails, classified so callers can tell a timeout
+// from a reset without matching the message.
+type BodyReadError struct {
+	// Category is the classification of Err, see Classify
+	Category Category
+	// Read is the number of bytes received before the failure
+	Read int
+	// Err is the read error, wrapping ErrTruncatedBody when the body ended early
+	Err error
+}
+
+func (e *BodyReadError) Error() string {
+	category := e.Category
+	if category == CategoryNone {
+		category = "unclassified"
+	}
+	return fmt.Sprintf("reading response body failed after %d bytes (%s): %v", e.Read, category, e.Err)
+}
+
+// Unwrap returns the read error.
+func (e *BodyReadError) Unwrap() error {
+	return e.Err
+}
+
+// ReadBodyLimited reads resp.Body up to maxBytes and always closes it, draining what is left of a small remainder
+// first so the connection can be reused. A maxBytes of zero or less means no cap.
+//
+// A body larger than maxBytes, by its Content-Length or by what was read, returns an error wrapping ErrBodyTooLarge.
+// A failed read returns a *BodyReadError classified with Classify, e.g. CategoryTimeout or CategoryReset, with the
+// bytes received so far; a body ending before Content-Length wraps ErrTruncatedBody.
+//
+// Example usage:
+//
+//	body, err := httpext.ReadBodyLimited(resp, 10<<20)
+//	var readErr *httpext.BodyReadError
+//	if errors.As(err, &readErr) && readErr.Category == httpext.CategoryTimeout {
+//		return app.MarkRetryable(err)
+//	}
+func ReadBodyLimited(resp *http.Response, maxBytes int64) ([]byte, error) {
+	if resp == nil || resp.Body == nil {
+		return nil, nil
+	}
+	defer resp.Body.Close()
+
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		drainBody(resp.Body)
+		return nil, fmt.Errorf("%w: Content-Length %d exceeds %d bytes", ErrBodyTooLarge, resp.ContentLength, maxBytes)
+	}
+
+	reader := resp.Body
+	if maxBytes > 0 {
+		reader = io.NopCloser(io.LimitReader(resp.Body, maxBytes+1))
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("%w: received %d of %d bytes: %w", ErrTruncatedBody, len(body), resp.ContentLength, err)
+		}
+		return body, &BodyReadError{Category: Classify(err), Read: len(body), Err: err}
+	}
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		drainBody(resp.Body)
+		return body[:maxBytes], fmt.Errorf("%w: body exceeds %d bytes", ErrBodyTooLarge, maxBytes)
+	}
+	if resp.ContentLength >= 0 && int64(len(body)) < resp.ContentLength {
+		err = fmt.Errorf("%w: received %d of %d bytes", ErrTruncatedBody, len(body), resp.ContentLength)
+		return body, &BodyReadError{Category: Classify(err), Read: len(body), Err: err}
+	}
+	return body, nil
+}
+
+// drainBody discards up to maxDrainBytes of body.
+func drainBody(body io.Reader) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+}
@@ -0,0 +1,171 @@
+package httpext
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestReadVerifiedBodyTruncated tests detecting bodies cut short by the server or shorter than Content-Length
+func TestReadVerifiedBodyTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = io.WriteString(w, `{"filings":[`)
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ReadVerifiedBody(resp)
+	if !errors.Is(err, ErrTruncatedBody) || !errors.Is(err, io.ErrUnexpectedEOF) || string(body) != `{"filings":[` {
+		t.Errorf("Expected ErrTruncatedBody wrapping the read error, got %q, %v", body, err)
+	}
+	if !IsTransientNetworkOrDNSIssueErr(err) {
+		t.Errorf("Expected a truncated body to be transient, got %v", err)
+	}
+
+	short := &http.Response{Body: io.NopCloser(strings.NewReader("abc")), ContentLength: 5}
+	if _, err := ReadVerifiedBody(short); !errors.Is(err, ErrTruncatedBody) {
+		t.Errorf("Expected ErrTruncatedBody for a short body, got %v", err)
+	}
+	long := &http.Response{Body: io.NopCloser(strings.NewReader("abcdef")), ContentLength: 5}
+	if _, err := ReadVerifiedBody(long); err == nil || errors.Is(err, ErrTruncatedBody) {
+		t.Errorf("Expected an error for a body longer than Content-Length, got %v", err)
+	}
+}
+
+// TestReadVerifiedBodyDigest tests the Repr-Digest, Digest and Content-MD5 headers
+func TestReadVerifiedBodyDigest(t *testing.T) {
+	const payload = `{"cik":"0000320193"}`
+	sha := sha256.Sum256([]byte(payload))
+	sum := md5.Sum([]byte(payload))
+	sha256Value := base64.StdEncoding.EncodeToString(sha[:])
+	md5Value := base64.StdEncoding.EncodeToString(sum[:])
+	wrong := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	tests := []struct {
+		name         string
+		header       http.Header
+		uncompressed bool
+		wantErr      bool
+	}{
+		{"no digest", http.Header{}, false, false},
+		{"repr-digest", http.Header{"Repr-Digest": {"sha-256=:" + sha256Value + ":"}}, false, false},
+		{"repr-digest mismatch", http.Header{"Repr-Digest": {"sha-256=:" + wrong + ":"}}, false, true},
+		{"repr-digest unsupported then supported", http.Header{"Repr-Digest": {"sha-1=:abc:, sha-256=:" + sha256Value + ":"}}, false, false},
+		{"digest", http.Header{"Digest": {"SHA-256=" + sha256Value}}, false, false},
+		{"digest mismatch", http.Header{"Digest": {"SHA-256=" + wrong}}, false, true},
+		{"content-md5", http.Header{"Content-Md5": {md5Value}}, false, false},
+		{"content-md5 invalid", http.Header{"Content-Md5": {"not base64!"}}, false, true},
+		{"decompressed body skips digest", http.Header{"Digest": {"SHA-256=" + wrong}}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header:        tt.header,
+				Body:          io.NopCloser(strings.NewReader(payload)),
+				ContentLength: int64(len(payload)),
+				Uncompressed:  tt.uncompressed,
+			}
+			body, err := ReadVerifiedBody(resp)
+			if string(body) != payload {
+				t.Errorf("Expected the body returned, got %q", body)
+			}
+			if tt.wantErr != errors.Is(err, ErrBodyDigestMismatch) {
+				t.Errorf("Expected mismatch %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// errReader returns the start of a body followed by err
+type errReader struct {
+	body   io.Reader
+	err    error
+	closed bool
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+	return n, err
+}
+
+func (r *errReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+// TestReadBodyLimited tests enforcing the size cap, closing the body and classifying read failures
+func TestReadBodyLimited(t *testing.T) {
+	response := func(body io.ReadCloser, contentLength int64) *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Body: body, ContentLength: contentLength}
+	}
+
+	body := &errReader{body: strings.NewReader("filing"), err: io.EOF}
+	got, err := ReadBodyLimited(response(body, 6), 6)
+	if err != nil || string(got) != "filing" || !body.closed {
+		t.Errorf("Expected the body read and closed, got %q, %v, closed %v", got, err, body.closed)
+	}
+
+	body = &errReader{body: strings.NewReader("filing"), err: io.EOF}
+	if _, err := ReadBodyLimited(response(body, 6), 5); !errors.Is(err, ErrBodyTooLarge) || !body.closed {
+		t.Errorf("Expected ErrBodyTooLarge from Content-Length, got %v, closed %v", err, body.closed)
+	}
+
+	remainder := strings.NewReader("filing" + strings.Repeat("x", 100))
+	body = &errReader{body: remainder, err: io.EOF}
+	got, err = ReadBodyLimited(response(body, -1), 5)
+	if !errors.Is(err, ErrBodyTooLarge) || string(got) != "filin" || remainder.Len() != 0 || !body.closed {
+		t.Errorf("Expected ErrBodyTooLarge with the body drained, got %q, %v, %d bytes left", got, err, remainder.Len())
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		category Category
+	}{
+		{"timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, CategoryTimeout},
+		{"reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, CategoryReset},
+		{"truncated", io.ErrUnexpectedEOF, CategoryTransient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &errReader{body: strings.NewReader("fil"), err: tt.err}
+			got, err := ReadBodyLimited(response(body, 6), 1024)
+			var readErr *BodyReadError
+			if !errors.As(err, &readErr) || !body.closed {
+				t.Fatalf("Expected a *BodyReadError with the body closed, got %v", err)
+			}
+			if readErr.Category != tt.category || readErr.Read != 3 || string(got) != "fil" {
+				t.Errorf("Expected %s after 3 bytes, got %s after %d", tt.category, readErr.Category, readErr.Read)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected the read error in the chain, got %v", err)
+			}
+		})
+	}
+
+	body = &errReader{body: strings.NewReader("fil"), err: io.EOF}
+	if _, err := ReadBodyLimited(response(body, 6), 0); !errors.Is(err, ErrTruncatedBody) {
+		t.Errorf("Expected ErrTruncatedBody for a short body, got %v", err)
+	}
+}
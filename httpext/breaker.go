@@ -0,0 +1,243 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"github.com/mhpenta/app"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned when a call is rejected because the circuit breaker for its key is open.
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig holds configuration for a circuit breaker
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before allowing trial requests
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of concurrent trial requests allowed while half-open
+	HalfOpenMaxRequests int
+}
+
+// DefaultBreakerConfig provides sensible default values for BreakerConfig
+var DefaultBreakerConfig = BreakerConfig{
+	FailureThreshold:    5,
+	OpenTimeout:         30 * time.Second,
+	HalfOpenMaxRequests: 1,
+}
+
+// BreakerSnapshot is a point-in-time view of a breaker, as published through expvar under "httpext.breakers".
+type BreakerSnapshot struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Failures  int       `json:"failures"`
+	OpenedAt  time.Time `json:"openedAt,omitempty"`
+	Rejected  int64     `json:"rejected"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// Breaker is a circuit breaker shared by every caller using the same name.
+type Breaker struct {
+	name   string
+	config BreakerConfig
+
+	mu               sync.Mutex
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight int
+	generation       uint64
+	rejected         int64
+	lastErr          string
+}
+
+// BreakerTicket records the state a call was admitted under. It is returned by Allow and passed back to Record, so
+// results of calls admitted before the breaker last changed state are ignored.
+type BreakerTicket struct {
+	generation uint64
+	probe      bool
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*Breaker)
+)
+
+func init() {
+	expvar.Publish("httpext.breakers", expvar.Func(func() interface{} {
+		return BreakerStates()
+	}))
+}
+
+// BreakerFor returns the shared breaker for name, creating it with DefaultBreakerConfig if needed.
+func BreakerFor(name string) *Breaker {
+	return BreakerForConfig(name, DefaultBreakerConfig)
+}
+
+// BreakerForConfig returns the shared breaker for name, creating it with config if needed. The config of an
+// existing breaker is not changed.
+func BreakerForConfig(name string, config BreakerConfig) *Breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	if b, ok := breakers[name]; ok {
+		return b
+	}
+
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultBreakerConfig.FailureThreshold
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = DefaultBreakerConfig.OpenTimeout
+	}
+	if config.HalfOpenMaxRequests <= 0 {
+		config.HalfOpenMaxRequests = DefaultBreakerConfig.HalfOpenMaxRequests
+	}
+
+	b := &Breaker{name: name, config: config}
+	breakers[name] = b
+	return b
+}
+
+// BreakerStates returns snapshots of all registered breakers sorted by name.
+func BreakerStates() []BreakerSnapshot {
+	breakersMu.Lock()
+	list := make([]*Breaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	snapshots := make([]BreakerSnapshot, 0, len(list))
+	for _, b := range list {
+		snapshots = append(snapshots, b.Snapshot())
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name < snapshots[j].Name
+	})
+	return snapshots
+}
+
+// Allow reports whether a call may proceed. It returns ErrBreakerOpen when the breaker is open, or half-open with
+// all trial slots taken. Every successful Allow must be followed by exactly one Record with the returned ticket.
+//
+// Example usage:
+//
+//	ticket, err := breaker.Allow()
+//	if err != nil {
+//		return err
+//	}
+//	err = callUpstream(ctx)
+//	breaker.Record(ticket, err)
+func (b *Breaker) Allow() (BreakerTicket, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && app.Since(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(BreakerHalfOpen)
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		b.rejected++
+		return BreakerTicket{}, ErrBreakerOpen
+	case BreakerHalfOpen:
+		if b.halfOpenInFlight >= b.config.HalfOpenMaxRequests {
+			b.rejected++
+			return BreakerTicket{}, ErrBreakerOpen
+		}
+		b.halfOpenInFlight++
+		return BreakerTicket{generation: b.generation, probe: true}, nil
+	}
+	return BreakerTicket{generation: b.generation}, nil
+}
+
+// Record reports the outcome of a call admitted by Allow. A nil error counts as success. Cancellations by the
+// caller (context.Canceled) are not counted either way. Only trial calls admitted while half-open can close the
+// breaker; outcomes of calls admitted before the breaker last changed state, such as a slow call that succeeds
+// after the breaker opened, are ignored.
+func (b *Breaker) Record(ticket BreakerTicket, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.generation != b.generation {
+		return
+	}
+	if ticket.probe {
+		b.halfOpenInFlight--
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		if ticket.probe {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	b.lastErr = err.Error()
+	if ticket.probe || b.failures >= b.config.FailureThreshold {
+		b.setState(BreakerOpen)
+		b.openedAt = app.Now()
+	}
+}
+
+// setState moves the breaker to state and starts a new generation. The caller must hold b.mu.
+func (b *Breaker) setState(state BreakerState) {
+	b.state = state
+	b.generation++
+	b.halfOpenInFlight = 0
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Snapshot returns a point-in-time view of the breaker.
+func (b *Breaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return BreakerSnapshot{
+		Name:      b.name,
+		State:     b.state.String(),
+		Failures:  b.failures,
+		OpenedAt:  b.openedAt,
+		Rejected:  b.rejected,
+		LastError: b.lastErr,
+	}
+}
@@ -0,0 +1,165 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestBreakerUnderTestMode tests that the open timeout follows the fake clock
+func TestBreakerUnderTestMode(t *testing.T) {
+	clock := app.TestMode(t)
+
+	b := BreakerForConfig(t.Name(), BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	ticket, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Record(ticket, errors.New("upstream down"))
+	if _, err := b.Allow(); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("Expected ErrBreakerOpen, got %v", err)
+	}
+	if opened := b.Snapshot().OpenedAt; !opened.Equal(app.TestModeStart) {
+		t.Errorf("Expected the breaker opened at the fake time, got %s", opened)
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := b.Allow(); err != nil || b.State() != BreakerHalfOpen {
+		t.Errorf("Expected a trial request once the fake clock passed the open timeout, got %v in %s", err, b.State())
+	}
+}
+
+// TestBreakerStateMachine tests the transitions between closed, open and half-open
+func TestBreakerStateMachine(t *testing.T) {
+	clock := app.TestMode(t)
+	b := BreakerForConfig(t.Name(), BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+	failure := errors.New("upstream down")
+
+	call := func(err error) error {
+		ticket, allowErr := b.Allow()
+		if allowErr != nil {
+			return allowErr
+		}
+		b.Record(ticket, err)
+		return nil
+	}
+
+	_ = call(failure)
+	_ = call(failure)
+	_ = call(nil)
+	_ = call(failure)
+	_ = call(context.Canceled)
+	_ = call(failure)
+	if b.State() != BreakerClosed {
+		t.Fatalf("Expected a success to reset the failure count and cancellations not to count, got %s", b.State())
+	}
+
+	_ = call(failure)
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected the third consecutive failure to open the breaker, got %s", b.State())
+	}
+	if err := call(nil); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Expected ErrBreakerOpen while open, got %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	probe, err := b.Allow()
+	if err != nil || b.State() != BreakerHalfOpen {
+		t.Fatalf("Expected a trial request after OpenTimeout, got %v in %s", err, b.State())
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Expected a second concurrent trial rejected, got %v", err)
+	}
+	b.Record(probe, failure)
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected a failed trial to reopen the breaker, got %s", b.State())
+	}
+
+	clock.Advance(time.Minute)
+	if err := call(nil); err != nil || b.State() != BreakerClosed {
+		t.Errorf("Expected a successful trial to close the breaker, got %v in %s", err, b.State())
+	}
+
+	snapshot := b.Snapshot()
+	if snapshot.Name != t.Name() || snapshot.State != "closed" || snapshot.Rejected != 2 || snapshot.LastError != "upstream down" {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+}
+
+// TestBreakerIgnoresStaleResults tests that calls admitted before the breaker changed state cannot close or reopen it
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	clock := app.TestMode(t)
+	b := BreakerForConfig(t.Name(), BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+
+	slow, _ := b.Allow()
+	fast, _ := b.Allow()
+	b.Record(fast, errors.New("upstream down"))
+	b.Record(slow, nil)
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected a late success not to close the open breaker, got %s", b.State())
+	}
+
+	clock.Advance(time.Minute)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Record(slow, errors.New("late failure"))
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("Expected a late failure not to reopen the half-open breaker, got %s", b.State())
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Expected the late result not to free the trial slot, got %v", err)
+	}
+
+	b.Record(probe, nil)
+	if b.State() != BreakerClosed {
+		t.Errorf("Expected the successful trial to close the breaker, got %s", b.State())
+	}
+}
+
+// TestBreakerTransport tests that clients share the breaker of a host and stop sending once it opens
+func TestBreakerTransport(t *testing.T) {
+	app.TestMode(t)
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	config := ClientConfig{
+		Breaker:    &BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute},
+		BreakerKey: func(*http.Request) string { return t.Name() },
+	}
+	first, second := NewClient(config), NewClient(config)
+
+	for _, client := range []*http.Client{first, second} {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	_, err := first.Get(srv.URL)
+	if !errors.Is(err, ErrBreakerOpen) || hits.Load() != 2 {
+		t.Errorf("Expected the shared breaker open after two 503s, got %v after %d requests", err, hits.Load())
+	}
+
+	found := false
+	for _, snapshot := range BreakerStates() {
+		if snapshot.Name == t.Name() {
+			found = snapshot.State == "open" && snapshot.Failures == 2
+		}
+	}
+	if !found {
+		t.Errorf("Expected the open breaker in BreakerStates, got %+v", BreakerStates())
+	}
+}
@@ -0,0 +1,216 @@
+package httpext
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Category is the kind of network failure an error is classified as by a Classifier.
+type Category string
+
+// Categories returned by Classify. Every category except CategoryNone is transient, see
+// IsTransientNetworkOrDNSIssueErr.
+const (
+	// CategoryNone is returned for nil errors and errors that are not network failures
+	CategoryNone Category = ""
+	// CategoryTransient is a network or DNS failure that does not fit a more specific category
+	CategoryTransient Category = "transient"
+	// CategoryDial is a failure to establish a connection: refused, unreachable or unresolvable
+	CategoryDial Category = "dial"
+	// CategoryTimeout is an I/O or request timeout on an established connection
+	CategoryTimeout Category = "timeout"
+	// CategoryReset is a connection reset by the peer
+	CategoryReset Category = "reset"
+	// CategoryGoAway is an HTTP/2 connection shut down by a GOAWAY frame
+	CategoryGoAway Category = "goaway"
+	// CategoryTLS is a TLS failure worth retrying on a new connection, see IsTransientTLSError
+	CategoryTLS Category = "tls"
+	// CategoryStream is an HTTP/2 stream reset while its connection stayed up, see IsStreamError
+	CategoryStream Category = "stream"
+	// CategoryIdleConn is a request lost on a pooled connection closed while idle, see IsIdleConnClosed
+	CategoryIdleConn Category = "idle conn"
+)
+
+// Rule maps the errors it matches to a category.
+type Rule struct {
+	// Name describes the rule in Rules
+	Name string
+	// Category is the category of matched errors. CategoryNone declares matched errors not to be network failures.
+	Category Category
+	// Match reports whether the rule applies to err, which is never nil
+	Match func(err error) bool
+}
+
+// SubstringRule returns a rule matching errors whose message contains any of substrings, ignoring case.
+func SubstringRule(category Category, substrings ...string) Rule {
+	lowered := make([]string, len(substrings))
+	for i, s := range substrings {
+		lowered[i] = strings.ToLower(s)
+	}
+	return Rule{
+		Name:     fmt.Sprintf("substring %q", substrings),
+		Category: category,
+		Match: func(err error) bool {
+			errMsg := strings.ToLower(err.Error())
+			for _, s := range lowered {
+				if strings.Contains(errMsg, s) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// RegexRule returns a rule matching errors whose message matches re.
+func RegexRule(category Category, re *regexp.Regexp) Rule {
+	return Rule{
+		Name:     "regex " + re.String(),
+		Category: category,
+		Match: func(err error) bool {
+			return re.MatchString(err.Error())
+		},
+	}
+}
+
+// TypeRule returns a rule matching errors with an error of type T in their chain, see errors.As.
+//
+// Example usage:
+//
+//	httpext.RegisterRule(httpext.TypeRule[*smithy.OperationError](httpext.CategoryTransient))
+func TypeRule[T error](category Category) Rule {
+	var zero T
+	return Rule{
+		Name:     fmt.Sprintf("type %T", zero),
+		Category: category,
+		Match: func(err error) bool {
+			var target T
+			return errors.As(err, &target)
+		},
+	}
+}
+
+// Classifier maps errors to categories with an ordered list of rules. The first matching rule decides; errors no rule
+// matches are passed to the built-in classification of DefaultClassifier, or classified CategoryNone by classifiers
+// created with NewClassifier.
+type Classifier struct {
+	mu    sync.RWMutex
+	rules []Rule
+	// builtin falls back to builtinCategory for errors no rule matches
+	builtin bool
+}
+
+// DefaultClassifier is used by Classify and RegisterRule. Without registered rules it classifies errors with the
+// Is* functions of this package.
+var DefaultClassifier = &Classifier{builtin: true}
+
+// NewClassifier creates a classifier that applies rules in order.
+func NewClassifier(rules ...Rule) *Classifier {
+	return &Classifier{rules: append([]Rule(nil), rules...)}
+}
+
+// Register adds rules to c. Rules registered later are checked first, so they can refine or override earlier ones.
+func (c *Classifier) Register(rules ...Rule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules = append(append([]Rule(nil), rules...), c.rules...)
+}
+
+// Rules returns the rules of c in the order they are checked.
+func (c *Classifier) Rules() []Rule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Rule(nil), c.rules...)
+}
+
+// Classify returns the category of err.
+func (c *Classifier) Classify(err error) Category {
+	if err == nil {
+		return CategoryNone
+	}
+	if category, ok := c.match(err); ok {
+		return category
+	}
+	if c.builtin {
+		return builtinCategory(err)
+	}
+	return CategoryNone
+}
+
+// match returns the category of the first rule matching err.
+func (c *Classifier) match(err error) (Category, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, rule := range c.rules {
+		if rule.Match(err) {
+			return rule.Category, true
+		}
+	}
+	return CategoryNone, false
+}
+
+// Classify returns the category of err according to DefaultClassifier.
+//
+// Example usage:
+//
+//	switch httpext.Classify(err) {
+//	case httpext.CategoryDial:
+//		return retry.OnConnectionError(ctx, fetch)
+//	case httpext.CategoryNone:
+//		return nil, err
+//	}
+func Classify(err error) Category {
+	return DefaultClassifier.Classify(err)
+}
+
+// RegisterRule adds rules to DefaultClassifier, e.g. for error strings of a cloud provider SDK. Errors they match
+// are transient for IsTransientNetworkOrDNSIssueErr, and so for the retry loops built on it, unless the rule's
+// category is CategoryNone.
+//
+// Example usage:
+//
+//	httpext.RegisterRule(httpext.SubstringRule(httpext.CategoryTransient, "SlowDown: please reduce your request rate"))
+func RegisterRule(rules ...Rule) {
+	DefaultClassifier.Register(rules...)
+}
+
+// builtinCategory classifies err with the Is* functions, from the most specific category to the least.
+func builtinCategory(err error) Category {
+	switch {
+	case IsCertificateError(err), IsUnixSocketUnavailableError(err):
+		return CategoryNone
+	case IsTransientTLSError(err):
+		return CategoryTLS
+	case IsHTTP2GoAwayError(err):
+		return CategoryGoAway
+	case IsConnectionResetByPeerError(err):
+		return CategoryReset
+	case IsStreamError(err):
+		return CategoryStream
+	case IsIdleConnClosed(err):
+		return CategoryIdleConn
+	case isDialOp(err):
+		return CategoryDial
+	case IsIOTimeoutError(err):
+		return CategoryTimeout
+	case IsDialError(err):
+		return CategoryDial
+	case IsTransientNetworkOrDNSIssueErr(err):
+		return CategoryTransient
+	}
+	return CategoryNone
+}
+
+// isDialOp reports whether err failed while dialing or resolving, before a connection was established.
+func isDialOp(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	_, ok := AsDNSFailure(err)
+	return ok
+}
@@ -0,0 +1,147 @@
+package httpext
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ClientConfig holds configuration for clients created by NewClient
+type ClientConfig struct {
+	// Timeout is the overall request timeout, see http.Client.Timeout
+	Timeout time.Duration
+	// Transport is the base transport. Defaults to a clone of http.DefaultTransport.
+	Transport http.RoundTripper
+	// DialContext, when set, opens the connections of the base transport, e.g. through a SOCKS proxy or an SSH
+	// tunnel. It is ignored when Transport is not an *http.Transport.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TracePhases wraps the base transport in PhaseTransport, so timeouts report the phase they occurred in
+	TracePhases bool
+	// Debug wraps the base transport in DebugTransport when non-nil, so failed exchanges carry a sanitized dump
+	Debug *DebugTransportConfig
+	// Breaker enables a per-host circuit breaker when non-nil
+	Breaker *BreakerConfig
+	// BreakerKey maps a request to its breaker name. Defaults to the request host.
+	BreakerKey func(*http.Request) string
+	// Retry enables RetryTransport when non-nil. Retries wrap the breaker, so each attempt is counted by it.
+	Retry *RetryTransportConfig
+	// Priority queues requests by their context priority when non-nil, see WithPriority. It wraps retries, so a
+	// request keeps its slot between attempts.
+	Priority *PriorityQueue
+	// SLO records per-endpoint latency and error rate when non-nil. It wraps retries, so it measures what the
+	// caller experiences, queueing included.
+	SLO *SLOTracker
+	// HostPolicy restricts outbound hosts when non-nil. It is the outermost layer, so rejected requests are not
+	// retried or counted by the breaker and SLO tracker.
+	HostPolicy *HostPolicy
+}
+
+// DefaultClientConfig provides sensible default values for ClientConfig
+var DefaultClientConfig = ClientConfig{
+	Timeout: 30 * time.Second,
+	Breaker: &BreakerConfig{
+		FailureThreshold:    DefaultBreakerConfig.FailureThreshold,
+		OpenTimeout:         DefaultBreakerConfig.OpenTimeout,
+		HalfOpenMaxRequests: DefaultBreakerConfig.HalfOpenMaxRequests,
+	},
+}
+
+// NewClient creates an http.Client from config. With a breaker configured, every outbound call goes through the
+// shared breaker for its host (or BreakerKey label), so all clients in the process see the same open/half-open
+// state, which is also visible through expvar.
+//
+// Clients also accept unix:// URLs, see UnixScheme, sending them over the named Unix domain socket with the settings
+// of the base transport.
+func NewClient(config ClientConfig) *http.Client {
+	transport := config.Transport
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if base, ok := transport.(*http.Transport); ok && config.DialContext != nil {
+		base = base.Clone()
+		base.DialContext = config.DialContext
+		transport = base
+	}
+	transport = newUnixSocketTransport(transport)
+
+	if config.Debug != nil {
+		transport = &DebugTransport{Base: transport, Config: *config.Debug}
+	}
+
+	if config.TracePhases {
+		transport = &PhaseTransport{Base: transport}
+	}
+
+	if config.Breaker != nil {
+		transport = &BreakerTransport{
+			Base:   transport,
+			Config: *config.Breaker,
+			Key:    config.BreakerKey,
+		}
+	}
+
+	if config.Retry != nil {
+		transport = &RetryTransport{
+			Base:   transport,
+			Config: *config.Retry,
+		}
+	}
+
+	if config.Priority != nil {
+		transport = config.Priority.Transport(transport)
+	}
+
+	if config.SLO != nil {
+		transport = config.SLO.Transport(transport)
+	}
+
+	if config.HostPolicy != nil {
+		transport = config.HostPolicy.Transport(transport)
+	}
+
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: transport,
+	}
+}
+
+// BreakerTransport is an http.RoundTripper that guards each request with the shared Breaker for its key. Transport
+// errors and 5xx responses count as failures.
+type BreakerTransport struct {
+	Base   http.RoundTripper
+	Config BreakerConfig
+	// Key maps a request to its breaker name. Defaults to the request host, or the socket path for unix:// URLs.
+	Key func(*http.Request) string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *BreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := requestHost(req)
+	if t.Key != nil {
+		key = t.Key(req)
+	}
+
+	breaker := BreakerForConfig(key, t.Config)
+	ticket, err := breaker.Allow()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, key)
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	switch {
+	case err != nil:
+		breaker.Record(ticket, err)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		breaker.Record(ticket, fmt.Errorf("server error: %s", resp.Status))
+	default:
+		breaker.Record(ticket, nil)
+	}
+	return resp, err
+}
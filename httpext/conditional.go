@@ -0,0 +1,109 @@
+package httpext
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ResourceState holds the validators of a remote resource, as returned by FetchIfChanged. Persist it between polls
+// and pass it back in.
+type ResourceState struct {
+	ETag          string `json:"etag,omitempty"`
+	LastModified  string `json:"lastModified,omitempty"`
+	ContentLength int64  `json:"contentLength"`
+}
+
+// unchanged reports whether current describes the same representation as s.
+func (s ResourceState) unchanged(current ResourceState) bool {
+	if s.ETag != "" && current.ETag != "" {
+		return s.ETag == current.ETag
+	}
+	if s.LastModified != "" && current.LastModified != "" {
+		return s.LastModified == current.LastModified && s.ContentLength == current.ContentLength
+	}
+	return false
+}
+
+func stateFromResponse(resp *http.Response) ResourceState {
+	return ResourceState{
+		ETag:          resp.Header.Get("ETag"),
+		LastModified:  resp.Header.Get("Last-Modified"),
+		ContentLength: resp.ContentLength,
+	}
+}
+
+// FetchIfChanged issues a HEAD request for url and only downloads it with GET when its ETag or Last-Modified differ
+// from previous. It returns a nil response when the resource is unchanged, in which case nothing was downloaded.
+// Otherwise the caller owns the response and must close its body.
+//
+// When maxBytes is positive, a Content-Length above it fails with ErrBodyTooLarge before the GET is sent. Servers
+// that reject HEAD are handled by a conditional GET, which also returns a nil response on 304 Not Modified. A nil
+// client uses http.DefaultClient.
+//
+// Example usage:
+//
+//	resp, state, err := httpext.FetchIfChanged(ctx, client, feedURL, job.State, 50<<20)
+//	if err != nil || resp == nil {
+//		return err
+//	}
+//	defer resp.Body.Close()
+//	job.State = state
+func FetchIfChanged(ctx context.Context, client *http.Client, url string, previous ResourceState, maxBytes int64) (*http.Response, ResourceState, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	head, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, previous, err
+	}
+
+	headResp, err := client.Do(head)
+	if err != nil {
+		return nil, previous, err
+	}
+	_ = headResp.Body.Close()
+
+	switch {
+	case headResp.StatusCode == http.StatusMethodNotAllowed || headResp.StatusCode == http.StatusNotImplemented:
+		// fall through to a conditional GET
+	case headResp.StatusCode >= 400:
+		return nil, previous, fmt.Errorf("HEAD %s: %s", url, headResp.Status)
+	default:
+		current := stateFromResponse(headResp)
+		if previous.unchanged(current) {
+			return nil, previous, nil
+		}
+		if maxBytes > 0 && current.ContentLength > maxBytes {
+			return nil, previous, fmt.Errorf("%w: Content-Length %d exceeds %d bytes", ErrBodyTooLarge, current.ContentLength, maxBytes)
+		}
+	}
+
+	get, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, previous, err
+	}
+	if previous.ETag != "" {
+		get.Header.Set("If-None-Match", previous.ETag)
+	}
+	if previous.LastModified != "" {
+		get.Header.Set("If-Modified-Since", previous.LastModified)
+	}
+
+	resp, err := client.Do(get)
+	if err != nil {
+		return nil, previous, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close()
+		return nil, previous, nil
+	}
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		_ = resp.Body.Close()
+		return nil, previous, fmt.Errorf("%w: Content-Length %d exceeds %d bytes", ErrBodyTooLarge, resp.ContentLength, maxBytes)
+	}
+
+	return resp, stateFromResponse(resp), nil
+}
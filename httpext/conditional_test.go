@@ -0,0 +1,103 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// TestFetchIfChanged tests skipping unchanged resources, the size cap and the conditional GET fallback
+func TestFetchIfChanged(t *testing.T) {
+	version := "v1"
+	allowHead := true
+	gets := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		etag := `"` + version + `"`
+		w.Header().Set("ETag", etag)
+		switch {
+		case r.Method == http.MethodHead && !allowHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Length", strconv.Itoa(len(version)))
+		case r.Header.Get("If-None-Match") == etag:
+			gets++
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			gets++
+			_, _ = io.WriteString(w, version)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	fetch := func(previous ResourceState, maxBytes int64) (string, ResourceState, error) {
+		resp, state, err := FetchIfChanged(ctx, srv.Client(), srv.URL+"/feed", previous, maxBytes)
+		if err != nil || resp == nil {
+			return "", state, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), state, err
+	}
+
+	body, state, err := fetch(ResourceState{}, 0)
+	if err != nil || body != "v1" || state.ETag != `"v1"` || gets != 1 {
+		t.Fatalf("Expected the first fetch to download, got %q %+v %v after %d GETs", body, state, err, gets)
+	}
+
+	body, unchanged, err := fetch(state, 0)
+	if err != nil || body != "" || unchanged != state || gets != 1 {
+		t.Errorf("Expected an unchanged resource skipped after HEAD, got %q %+v %v after %d GETs", body, unchanged, err, gets)
+	}
+
+	version = "v22"
+	if _, kept, err := fetch(state, 2); !errors.Is(err, ErrBodyTooLarge) || kept != state || gets != 1 {
+		t.Errorf("Expected the size cap to fail before GET, got %+v %v after %d GETs", kept, err, gets)
+	}
+	body, state, err = fetch(state, 0)
+	if err != nil || body != "v22" || state.ETag != `"v22"` {
+		t.Errorf("Expected a changed resource downloaded, got %q %+v %v", body, state, err)
+	}
+
+	allowHead = false
+	body, kept, err := fetch(state, 0)
+	if err != nil || body != "" || kept != state || gets != 3 {
+		t.Errorf("Expected a 304 from the conditional GET to report no change, got %q %+v %v after %d GETs", body, kept, err, gets)
+	}
+
+	if _, _, err := FetchIfChanged(ctx, srv.Client(), srv.URL+"/missing", ResourceState{}, 0); err == nil {
+		t.Error("Expected an error for a failed HEAD")
+	}
+}
+
+// TestResourceStateUnchanged tests comparing validators
+func TestResourceStateUnchanged(t *testing.T) {
+	tests := []struct {
+		name      string
+		previous  ResourceState
+		current   ResourceState
+		unchanged bool
+	}{
+		{"same etag", ResourceState{ETag: `"a"`}, ResourceState{ETag: `"a"`, ContentLength: 10}, true},
+		{"etag wins over last modified", ResourceState{ETag: `"a"`, LastModified: "Mon"}, ResourceState{ETag: `"b"`, LastModified: "Mon"}, false},
+		{"last modified and length", ResourceState{LastModified: "Mon", ContentLength: 10}, ResourceState{LastModified: "Mon", ContentLength: 10}, true},
+		{"length changed", ResourceState{LastModified: "Mon", ContentLength: 10}, ResourceState{LastModified: "Mon", ContentLength: 11}, false},
+		{"no validators", ResourceState{}, ResourceState{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.previous.unchanged(tt.current); got != tt.unchanged {
+				t.Errorf("Expected unchanged %v, got %v", tt.unchanged, got)
+			}
+		})
+	}
+}
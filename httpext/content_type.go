@@ -0,0 +1,218 @@
+package httpext
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"github.com/mhpenta/app/jsonext"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedContentType is matched by errors.Is for errors returned by DecodeByContentType for bodies no
+// registered decoder handles.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// UnsupportedContentTypeError is returned by DecodeByContentType when no decoder is registered for the response's
+// media type, e.g. an HTML error page from a proxy in front of a JSON API.
+type UnsupportedContentTypeError struct {
+	// ContentType is the media type of the response, without parameters
+	ContentType string
+	// Status is the response status line
+	Status string
+}
+
+func (e *UnsupportedContentTypeError) Error() string {
+	return fmt.Sprintf("%s: %q (%s)", ErrUnsupportedContentType, e.ContentType, e.Status)
+}
+
+// Is reports whether target is ErrUnsupportedContentType.
+func (e *UnsupportedContentTypeError) Is(target error) bool {
+	return target == ErrUnsupportedContentType
+}
+
+// BodyDecoder decodes a response body into v.
+type BodyDecoder func(body []byte, v interface{}) error
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]BodyDecoder{
+		"application/json":                  jsonext.Unmarshal,
+		"application/xml":                   xml.Unmarshal,
+		"text/xml":                          xml.Unmarshal,
+		"application/x-www-form-urlencoded": decodeForm,
+	}
+)
+
+// RegisterDecoder registers decoder for responses of mediaType, e.g. "application/x-ndjson", replacing any decoder
+// registered before. Media types are matched case-insensitively and without parameters such as charset.
+func RegisterDecoder(mediaType string, decoder BodyDecoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(strings.TrimSpace(mediaType))] = decoder
+}
+
+// DecodeByContentType reads and closes resp.Body and decodes it into v with the decoder registered for its
+// Content-Type: JSON (including "+json" types), XML (including "+xml" types) and form encoding by default, see
+// RegisterDecoder. A response without a Content-Type is sniffed as JSON or XML from its first character.
+//
+// Empty responses, redirects and 4xx/5xx statuses are handled like DecodeOrEmpty. A body of any other type returns an
+// *UnsupportedContentTypeError marked permanent, so an HTML error page is not mistaken for a malformed JSON payload
+// and retried.
+//
+// Example usage:
+//
+//	var filing Filing
+//	if err := httpext.DecodeByContentType(resp, &filing); err != nil {
+//		return err
+//	}
+func DecodeByContentType(resp *http.Response, v interface{}) error {
+	body, ok, err := readPayload(resp, v)
+	if err != nil || !ok {
+		return err
+	}
+
+	mediaType := responseMediaType(resp.Header.Get("Content-Type"), body)
+	decoder, found := lookupDecoder(mediaType)
+	if !found {
+		return app.MarkPermanent(&UnsupportedContentTypeError{ContentType: mediaType, Status: resp.Status})
+	}
+	return decoder(body, v)
+}
+
+func lookupDecoder(mediaType string) (BodyDecoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	if decoder, ok := decoders[mediaType]; ok {
+		return decoder, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return decoders["application/json"], true
+	case strings.HasSuffix(mediaType, "+xml"):
+		return decoders["application/xml"], true
+	}
+	return nil, false
+}
+
+// responseMediaType returns the media type of contentType, or one sniffed from body when the header is missing.
+func responseMediaType(contentType string, body []byte) string {
+	if contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			return strings.ToLower(mediaType)
+		}
+		return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")), bytes.HasPrefix(trimmed, []byte("[")):
+		return "application/json"
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return "application/xml"
+	}
+	return "application/octet-stream"
+}
+
+// decodeForm decodes an application/x-www-form-urlencoded body into a *url.Values, *map[string][]string,
+// *map[string]string or a pointer to a struct whose fields are named by `form` tags. Struct fields may be strings,
+// booleans, numbers or string slices.
+func decodeForm(body []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	switch target := v.(type) {
+	case *url.Values:
+		*target = values
+		return nil
+	case *map[string][]string:
+		*target = values
+		return nil
+	case *map[string]string:
+		*target = make(map[string]string, len(values))
+		for key := range values {
+			(*target)[key] = values.Get(key)
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form decoding into %T is not supported", v)
+	}
+	rv = rv.Elem()
+
+	var problems []string
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := values[name]; !ok {
+			continue
+		}
+		if err := setFormField(rv.Field(i), values[name]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("form decoding: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func setFormField(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String {
+		field.Set(reflect.ValueOf(append([]string(nil), values...)).Convert(field.Type()))
+		return nil
+	}
+
+	value := values[0]
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package httpext
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/mhpenta/app"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MetaError fields holding the dumps recorded by DebugTransport.
+const (
+	RequestDumpField  = "requestDump"
+	ResponseDumpField = "responseDump"
+)
+
+// redactedValue replaces the values of redacted headers in dumps.
+const redactedValue = "[REDACTED]"
+
+// DebugTransportConfig holds configuration for DebugTransport
+type DebugTransportConfig struct {
+	// RedactHeaders lists the headers whose values are replaced in dumps, matched case-insensitively. Nil uses the
+	// headers of DefaultDebugTransportConfig; an empty, non-nil list turns redaction off.
+	RedactHeaders []string
+	// MaxBody is the number of bytes of the request and response bodies kept in dumps
+	MaxBody int64
+}
+
+// DefaultDebugTransportConfig provides sensible default values for DebugTransportConfig
+var DefaultDebugTransportConfig = DebugTransportConfig{
+	RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"},
+	MaxBody:       2048,
+}
+
+// Dump is the sanitized request and response text recorded by DebugTransport.
+type Dump struct {
+	// Request is the request line, headers and the start of the body
+	Request string
+	// Response is the status line, headers and the start of the body, empty when no response was received
+	Response string
+}
+
+type dumpKey struct{}
+
+// DumpFromResponse returns the dump DebugTransport recorded for a non-2xx response.
+func DumpFromResponse(resp *http.Response) (Dump, bool) {
+	if resp == nil || resp.Request == nil {
+		return Dump{}, false
+	}
+	dump, ok := resp.Request.Context().Value(dumpKey{}).(*Dump)
+	if !ok || dump == nil || dump.Request == "" {
+		return Dump{}, false
+	}
+	return *dump, true
+}
+
+// DebugTransport is an http.RoundTripper that records a sanitized dump of failed exchanges, to make flaky upstream
+// APIs debuggable. Transport errors are returned as a *app.MetaError carrying the request dump in its
+// RequestDumpField field. Non-2xx responses are returned unchanged, their body still readable in full, with the dump
+// available from DumpFromResponse; DecodeOrEmpty and DecodeByContentType attach it to the error they return for 4xx
+// and 5xx statuses.
+//
+// Example usage:
+//
+//	client := &http.Client{Transport: &httpext.DebugTransport{Config: httpext.DefaultDebugTransportConfig}}
+type DebugTransport struct {
+	Base   http.RoundTripper
+	Config DebugTransportConfig
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	dump := &Dump{}
+	req = req.WithContext(context.WithValue(req.Context(), dumpKey{}, dump))
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		dump.Request = t.dumpRequest(req)
+		return nil, app.NewMetaError(err).WithField(RequestDumpField, dump.Request)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	dump.Request = t.dumpRequest(req)
+	dump.Response = t.dumpResponse(resp)
+	resp.Request = req
+	return resp, nil
+}
+
+func (t *DebugTransport) dumpRequest(req *http.Request) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\r\n", req.Method, req.URL.Redacted(), req.Proto)
+	t.writeHeaders(&b, req.Header)
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			snippet, _ := io.ReadAll(io.LimitReader(body, t.maxBody()))
+			body.Close()
+			b.Write(snippet)
+		}
+	} else if req.Body != nil && req.Body != http.NoBody {
+		b.WriteString("[body not replayable]")
+	}
+	return b.String()
+}
+
+// dumpResponse records the status, headers and the start of the body of resp, leaving the whole body readable.
+func (t *DebugTransport) dumpResponse(resp *http.Response) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\r\n", resp.Proto, resp.Status)
+	t.writeHeaders(&b, resp.Header)
+
+	if resp.Body != nil {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, t.maxBody()))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(snippet), resp.Body), resp.Body}
+		b.Write(snippet)
+	}
+	return b.String()
+}
+
+func (t *DebugTransport) writeHeaders(b *strings.Builder, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			if t.redacted(key) {
+				value = redactedValue
+			}
+			fmt.Fprintf(b, "%s: %s\r\n", key, value)
+		}
+	}
+	b.WriteString("\r\n")
+}
+
+func (t *DebugTransport) redacted(key string) bool {
+	names := t.Config.RedactHeaders
+	if names == nil {
+		names = DefaultDebugTransportConfig.RedactHeaders
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *DebugTransport) maxBody() int64 {
+	if t.Config.MaxBody > 0 {
+		return t.Config.MaxBody
+	}
+	return DefaultDebugTransportConfig.MaxBody
+}
+
+// withDump attaches the dump DebugTransport recorded for resp to err.
+func withDump(resp *http.Response, err error) error {
+	dump, ok := DumpFromResponse(resp)
+	if !ok {
+		return err
+	}
+	return app.NewMetaError(err).WithField(RequestDumpField, dump.Request).WithField(ResponseDumpField, dump.Response)
+}
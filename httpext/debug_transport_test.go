@@ -0,0 +1,107 @@
+package httpext
+
+import (
+	"errors"
+	"github.com/mhpenta/app"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDebugTransport tests the dumps attached to transport errors and non-2xx responses
+func TestDebugTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, "database unavailable, try later")
+	}))
+	defer srv.Close()
+
+	config := DefaultDebugTransportConfig
+	config.MaxBody = 8
+	client := NewClient(ClientConfig{Debug: &config})
+
+	newRequest := func(url string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"cik":"0000320193"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("X-Trace", "abc")
+		return req
+	}
+
+	resp, err := client.Do(newRequest(srv.URL + "/filings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump, ok := DumpFromResponse(resp)
+	if !ok {
+		t.Fatal("Expected a dump for a 500 response")
+	}
+	if !strings.HasPrefix(dump.Request, "POST "+srv.URL+"/filings HTTP/1.1\r\n") || !strings.Contains(dump.Request, "Authorization: [REDACTED]") ||
+		!strings.Contains(dump.Request, "X-Trace: abc") || !strings.HasSuffix(dump.Request, "\r\n\r\n{\"cik\":\"") {
+		t.Errorf("Unexpected request dump %q", dump.Request)
+	}
+	if !strings.HasPrefix(dump.Response, "HTTP/1.1 500 Internal Server Error\r\n") || strings.Contains(dump.Response, "session=secret") ||
+		!strings.HasSuffix(dump.Response, "database") {
+		t.Errorf("Unexpected response dump %q", dump.Response)
+	}
+
+	err = DecodeOrEmpty(resp, new(map[string]string))
+	metaErr, ok := app.AsMetaError(err)
+	if !ok || !errors.Is(err, ErrUnexpectedStatus) || metaErr.Fields[ResponseDumpField] != dump.Response {
+		t.Fatalf("Expected the dump attached to the status error, got %v", err)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Body != "database unavailable, try later" {
+		t.Errorf("Expected the whole body left readable, got %+v", statusErr)
+	}
+
+	_, err = client.Do(newRequest("http://127.0.0.1:1/filings"))
+	metaErr, ok = app.AsMetaError(err)
+	if !ok || !strings.Contains(metaErr.Fields[RequestDumpField].(string), "Authorization: [REDACTED]") {
+		t.Errorf("Expected the request dump attached to the transport error, got %v", err)
+	}
+	if !IsDialError(err) {
+		t.Errorf("Expected the transport error to stay classifiable, got %v", err)
+	}
+}
+
+// TestDebugTransportZeroValue tests that a zero-value DebugTransport still redacts credentials and keeps the body
+func TestDebugTransportZeroValue(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, "upstream timed out")
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/filings", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("X-Api-Key", "secret")
+
+	client := &http.Client{Transport: &DebugTransport{}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	dump, ok := DumpFromResponse(resp)
+	if !ok || strings.Contains(dump.Request, "secret") || strings.Count(dump.Request, redactedValue) != 3 {
+		t.Errorf("Expected the credentials redacted, got %q", dump.Request)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "upstream timed out" {
+		t.Errorf("Expected the whole body readable, got %q, %v", body, err)
+	}
+	if !strings.HasSuffix(dump.Response, "upstream timed out") {
+		t.Errorf("Expected the body in the response dump, got %q", dump.Response)
+	}
+}
@@ -0,0 +1,91 @@
+package httpext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app/jsonext"
+	"net/http"
+	"reflect"
+)
+
+var (
+	// ErrUnexpectedRedirect is returned by DecodeOrEmpty for 3xx responses the client did not follow, such as when
+	// CheckRedirect returns http.ErrUseLastResponse or the Location header is missing.
+	ErrUnexpectedRedirect = errors.New("unexpected redirect response")
+
+	// ErrUnexpectedStatus is returned by DecodeOrEmpty for 4xx and 5xx responses, whose bodies are not the expected
+	// payload.
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+)
+
+// DecodeOrEmpty reads and closes resp.Body and decodes it into v with jsonext.Unmarshal. Responses that carry no
+// payload, 204, 205 and 304, replies to HEAD and bodies that are empty or whitespace, succeed and leave v at its zero
+// value instead of failing with a JSON syntax error that the unmarshalling retry would keep repeating.
+//
+// Unfollowed 3xx responses return an error wrapping ErrUnexpectedRedirect and 4xx/5xx responses a *StatusError
+// matching ErrUnexpectedStatus, so neither is mistaken for a malformed payload.
+//
+// Example usage:
+//
+//	var result SearchResult
+//	if err := httpext.DecodeOrEmpty(resp, &result); err != nil {
+//		return err
+//	}
+func DecodeOrEmpty(resp *http.Response, v interface{}) error {
+	body, ok, err := readPayload(resp, v)
+	if err != nil || !ok {
+		return err
+	}
+	return jsonext.Unmarshal(body, v)
+}
+
+// readPayload reads and closes resp.Body for decoding into v. It returns ok false, after resetting v, for responses
+// that carry no payload, and an error for redirects, 4xx/5xx statuses and truncated bodies.
+func readPayload(resp *http.Response, v interface{}) (body []byte, ok bool, err error) {
+	if resp == nil {
+		return nil, false, errors.New("nil response")
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent, resp.StatusCode == http.StatusResetContent,
+		resp.StatusCode == http.StatusNotModified,
+		resp.Request != nil && resp.Request.Method == http.MethodHead:
+		closeBody(resp)
+		resetValue(v)
+		return nil, false, nil
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		closeBody(resp)
+		location := resp.Header.Get("Location")
+		if location == "" {
+			location = "no Location header"
+		}
+		return nil, false, fmt.Errorf("%w: %s (%s)", ErrUnexpectedRedirect, resp.Status, location)
+	case resp.StatusCode >= 400:
+		return nil, false, withDump(resp, NewStatusError(resp))
+	}
+
+	body, err = ReadVerifiedBody(resp)
+	if err != nil {
+		return body, false, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		resetValue(v)
+		return body, false, nil
+	}
+	return body, true, nil
+}
+
+func closeBody(resp *http.Response) {
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+}
+
+// resetValue sets the value v points to back to its zero value.
+func resetValue(v interface{}) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+	}
+}
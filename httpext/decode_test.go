@@ -0,0 +1,113 @@
+package httpext
+
+import (
+	"errors"
+	"github.com/mhpenta/app"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestDecodeOrEmpty tests empty, redirect and payload responses
+func TestDecodeOrEmpty(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	response := func(status int, body string) *http.Response {
+		return &http.Response{
+			StatusCode:    status,
+			Status:        http.StatusText(status),
+			Header:        http.Header{},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+	}
+
+	v := payload{Name: "stale"}
+	if err := DecodeOrEmpty(response(http.StatusNoContent, ""), &v); err != nil || v.Name != "" {
+		t.Errorf("Expected 204 to decode to the zero value, got %+v (%v)", v, err)
+	}
+
+	v = payload{Name: "stale"}
+	if err := DecodeOrEmpty(response(http.StatusOK, "  \n"), &v); err != nil || v.Name != "" {
+		t.Errorf("Expected empty body to decode to the zero value, got %+v (%v)", v, err)
+	}
+
+	if err := DecodeOrEmpty(response(http.StatusOK, `{"name":"ok"}`), &v); err != nil || v.Name != "ok" {
+		t.Errorf("Expected payload to decode, got %+v (%v)", v, err)
+	}
+
+	if err := DecodeOrEmpty(response(http.StatusFound, "<html>moved</html>"), &v); !errors.Is(err, ErrUnexpectedRedirect) {
+		t.Errorf("Expected ErrUnexpectedRedirect, got %v", err)
+	}
+
+	if err := DecodeOrEmpty(response(http.StatusBadGateway, "bad gateway"), &v); !errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("Expected ErrUnexpectedStatus, got %v", err)
+	}
+}
+
+// TestDecodeByContentType tests dispatching on Content-Type, registered decoders and unsupported bodies
+func TestDecodeByContentType(t *testing.T) {
+	type filing struct {
+		CIK   string `json:"cik" xml:"cik" form:"cik"`
+		Count int    `json:"count" xml:"count" form:"count"`
+	}
+
+	response := func(contentType string, body string) *http.Response {
+		resp := &http.Response{
+			StatusCode:    http.StatusOK,
+			Status:        "200 OK",
+			Header:        http.Header{},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+		if contentType != "" {
+			resp.Header.Set("Content-Type", contentType)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{"application/json; charset=utf-8", `{"cik":"320193","count":2}`},
+		{"application/vnd.api+json", `{"cik":"320193","count":2}`},
+		{"text/xml", `<filing><cik>320193</cik><count>2</count></filing>`},
+		{"application/x-www-form-urlencoded", `cik=320193&count=2`},
+		{"", ` {"cik":"320193","count":2}`},
+	}
+	for _, tt := range tests {
+		var v filing
+		if err := DecodeByContentType(response(tt.contentType, tt.body), &v); err != nil || v.CIK != "320193" || v.Count != 2 {
+			t.Errorf("Expected %q to decode, got %+v (%v)", tt.contentType, v, err)
+		}
+	}
+
+	var v filing
+	err := DecodeByContentType(response("text/html", "<html>gateway error</html>"), &v)
+	var ctErr *UnsupportedContentTypeError
+	if !errors.Is(err, ErrUnsupportedContentType) || !errors.As(err, &ctErr) || ctErr.ContentType != "text/html" || !isMarkedPermanent(err) {
+		t.Errorf("Expected a permanent UnsupportedContentTypeError, got %v", err)
+	}
+
+	RegisterDecoder("text/html", func(body []byte, v interface{}) error {
+		v.(*filing).CIK = "html"
+		return nil
+	})
+	defer func() {
+		decodersMu.Lock()
+		delete(decoders, "text/html")
+		decodersMu.Unlock()
+	}()
+	if err := DecodeByContentType(response("text/html", "<html></html>"), &v); err != nil || v.CIK != "html" {
+		t.Errorf("Expected the registered decoder to run, got %+v (%v)", v, err)
+	}
+}
+
+func isMarkedPermanent(err error) bool {
+	retryable, marked := app.IsRetryable(err)
+	return marked && !retryable
+}
@@ -0,0 +1,53 @@
+package httpext
+
+import (
+	"errors"
+	"net"
+)
+
+// DNSFailure describes a failed DNS lookup, so callers can pick a backoff: a name that does not exist will not
+// appear on retry, while a timed out or temporarily failing resolver usually recovers.
+type DNSFailure struct {
+	// Name is the name looked up
+	Name string
+	// Server is the resolver that answered or failed to, empty when unknown
+	Server string
+	// Err is the resolver's description of the failure, e.g. "no such host"
+	Err string
+	// IsNotFound reports that the name does not exist (NXDOMAIN)
+	IsNotFound bool
+	// IsTimeout reports that the resolver did not answer in time
+	IsTimeout bool
+	// IsTemporary reports that the resolver reported a temporary failure, such as SERVFAIL
+	IsTemporary bool
+}
+
+// Retryable reports whether the lookup is likely to succeed when retried after a backoff.
+func (f DNSFailure) Retryable() bool {
+	return !f.IsNotFound && (f.IsTimeout || f.IsTemporary)
+}
+
+// AsDNSFailure returns the details of the *net.DNSError in err's chain.
+//
+// Example usage:
+//
+//	if failure, ok := httpext.AsDNSFailure(err); ok {
+//		slog.Warn("DNS lookup failed", "name", failure.Name, "server", failure.Server, "notFound", failure.IsNotFound)
+//		if !failure.Retryable() {
+//			return app.MarkPermanent(err)
+//		}
+//	}
+func AsDNSFailure(err error) (DNSFailure, bool) {
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		return DNSFailure{}, false
+	}
+	return DNSFailure{
+		Name:        dnsErr.Name,
+		Server:      dnsErr.Server,
+		Err:         dnsErr.Err,
+		IsNotFound:  dnsErr.IsNotFound,
+		IsTimeout:   dnsErr.IsTimeout,
+		IsTemporary: dnsErr.IsTemporary,
+	}, true
+}
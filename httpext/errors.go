@@ -1,10 +1,14 @@
 package httpext
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
-	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"reflect"
 	"strings"
 	"syscall"
 )
@@ -16,19 +20,31 @@ const (
 	possibleGotAwayMsg   = "server sent GOAWAY"
 )
 
-// IsTransientNetworkOrDNSIssueErr checks if the error is a possible network or DNS issue.
+// IsTransientNetworkOrDNSIssueErr checks if the error is a possible network or DNS issue. A Unix domain socket that
+// is missing or refuses connections is a local problem, not a network one, see IsUnixSocketUnavailableError.
+//
+// Rules added with RegisterRule take precedence: errors they classify are transient unless the category is
+// CategoryNone.
 func IsTransientNetworkOrDNSIssueErr(err error) bool {
 	if err == nil {
 		return false
 	}
+	if category, ok := DefaultClassifier.match(err); ok {
+		return category != CategoryNone
+	}
+	if IsUnixSocketUnavailableError(err) {
+		return false
+	}
+
+	if errors.Is(err, ErrTruncatedBody) || IsTransientTLSError(err) || IsStreamError(err) || IsIdleConnClosed(err) {
+		return true
+	}
 
-	// Unwrap the error to get the root cause
-	unwrappedErr := errors.Unwrap(err)
-	if unwrappedErr != nil {
-		err = unwrappedErr
+	if IsCertificateError(err) {
+		return false
 	}
 
-	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+	if hasTimeout(err) {
 		return true
 	}
 
@@ -50,12 +66,122 @@ func IsTransientNetworkOrDNSIssueErr(err error) bool {
 	return false
 }
 
+// transientTLSMessages are fragments of TLS errors caused by a dropped or confused connection rather than by the
+// peer's configuration, so a fresh connection usually succeeds.
+var transientTLSMessages = []string{
+	"tls: use of closed connection",
+	"tls: bad record mac",
+	"tls: unexpected message",
+	"tls: received unexpected handshake message",
+	"session ticket",
+	"resumed a session with a different",
+}
+
+// IsTransientTLSError determines if the given error is a TLS failure worth retrying on a new connection: a connection
+// closed under the TLS layer, a handshake cut short by EOF or reset, a corrupted record, or a session resumption
+// problem. Certificate errors are never transient, see IsCertificateError.
+func IsTransientTLSError(err error) bool {
+	if err == nil || IsCertificateError(err) {
+		return false
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	if strings.Contains(errMsg, "handshake") &&
+		(strings.HasSuffix(errMsg, "eof") || strings.Contains(errMsg, "connection reset by peer")) {
+		return true
+	}
+
+	for _, msg := range transientTLSMessages {
+		if strings.Contains(errMsg, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCertificateError determines if the given error comes from certificate verification: an unknown authority, an
+// invalid or expired certificate, a hostname mismatch or a pin mismatch. These need a configuration change, not a
+// retry.
+func IsCertificateError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &verifyErr) || errors.As(err, &authorityErr) || errors.As(err, &invalidErr) ||
+		errors.As(err, &hostnameErr) || errors.Is(err, ErrPinMismatch) {
+		return true
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	return strings.Contains(errMsg, "x509: ") ||
+		strings.Contains(errMsg, "tls: bad certificate") ||
+		strings.Contains(errMsg, "tls: unknown certificate") ||
+		strings.Contains(errMsg, "tls: certificate required")
+}
+
+// IsTLSError determines if the given error comes from the TLS layer: certificate verification, an alert sent by the
+// peer, a handshake or record failure, or a non-TLS server answering a TLS client. Use IsTransientTLSError and
+// IsCertificateError to tell whether a retry can help.
+func IsTLSError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsCertificateError(err) {
+		return true
+	}
+
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) {
+		return true
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	return strings.Contains(errMsg, "tls: ") || strings.Contains(errMsg, "tls handshake")
+}
+
+// IsCertExpiredError determines if the given error is a certificate that has expired or is not yet valid, which is
+// also what a client with a badly skewed clock reports.
+func IsCertExpiredError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &invalidErr) {
+		return invalidErr.Reason == x509.Expired
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "certificate has expired or is not yet valid")
+}
+
+// IsHostnameMismatchError determines if the given error is a certificate that is not valid for the host dialed,
+// typically a wrong URL, a missing SAN or a load balancer serving the default certificate.
+func IsHostnameMismatchError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		return true
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	return strings.Contains(errMsg, "x509: certificate is valid for") ||
+		strings.Contains(errMsg, "x509: certificate is not valid for any names") ||
+		strings.Contains(errMsg, "doesn't contain any ip sans")
+}
+
 // IsDialError determines if the given error is related to network dialing or connectivity issues.
 // It checks for various types of network errors, including:
 //   - Timeout errors (net.Error with Timeout() == true)
 //   - Dial and read operation errors (net.OpError)
 //   - Specific system errors like connection refused, host unreachable, and network unreachable
-//   - DNS lookup timeout errors (net.DNSError)
+//   - DNS lookup timeout errors (net.DNSError, see AsDNSFailure for the details)
 //   - Generic timeout errors (detected by os.IsTimeout)
 //   - String matching for common network error messages
 //
@@ -63,9 +189,15 @@ func IsTransientNetworkOrDNSIssueErr(err error) bool {
 // and may be resolved by retrying the operation after a delay.
 //
 // Returns true if the error is identified as a network dialing or connectivity issue,
-// false otherwise or if the input error is nil.
+// false otherwise or if the input error is nil. See IsDialErrorWith to exclude errors caused by a cancelled or
+// expired context.
 func IsDialError(err error) bool {
-	if err == nil {
+	return IsDialErrorWith(err)
+}
+
+// IsDialErrorWith is IsDialError adjusted by opts, e.g. ExcludeContextErrors.
+func IsDialErrorWith(err error, opts ...CheckOption) bool {
+	if err == nil || checkOptionsFrom(opts).excluded(err) {
 		return false
 	}
 
@@ -91,13 +223,8 @@ func IsDialError(err error) bool {
 		}
 	}
 
-	var dnsErr *net.DNSError
-	if errors.As(err, &dnsErr) {
-		slog.Warn("DNS lookup error encountered",
-			"error", dnsErr,
-			"isTimeout", dnsErr.IsTimeout,
-			"name", dnsErr.Name)
-		return dnsErr.IsTimeout
+	if failure, ok := AsDNSFailure(err); ok {
+		return failure.IsTimeout
 	}
 
 	if os.IsTimeout(err) {
@@ -136,6 +263,85 @@ func IsHTTP2GoAwayError(err error) bool {
 	return false
 }
 
+// IsStreamError determines if the given error is an HTTP/2 stream error: a single request reset with RST_STREAM, such
+// as REFUSED_STREAM or INTERNAL_ERROR, while the connection and its other streams stay up.
+//
+// The check is a heuristic, so this module does not depend on golang.org/x/net: it matches the StreamError type of
+// golang.org/x/net/http2 and the unexported http2StreamError copy bundled in net/http by name, then falls back to
+// their "stream error: stream ID" message. TestTransportErrors checks the names against the errors net/http returns.
+func IsStreamError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return hasStreamErrorType(err) || strings.Contains(strings.ToLower(err.Error()), "stream error: stream id")
+}
+
+// hasStreamErrorType reports whether the chain of err holds an HTTP/2 StreamError, matched by type name.
+func hasStreamErrorType(err error) bool {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		t := reflect.TypeOf(e)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Name() == "StreamError" && strings.HasSuffix(t.PkgPath(), "http2") {
+			return true
+		}
+		if t.Name() == "http2StreamError" && t.PkgPath() == "net/http" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsConnectionPoolExhausted determines if the given error is a request that timed out waiting for a connection from
+// the transport's pool, e.g. when http.Transport.MaxConnsPerHost connections are all busy. Timeouts are attributed to
+// the pool by PhaseTransport, see PhaseWaitConn, or by the message of older transports.
+func IsConnectionPoolExhausted(err error) bool {
+	if err == nil {
+		return false
+	}
+	if phase, ok := TimeoutPhase(err); ok && phase == PhaseWaitConn {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "while waiting for connection")
+}
+
+// IsIdleConnClosed determines if the given error is a request lost on a pooled connection that the server closed or
+// that stopped answering while idle. The request never reached the server's handler or its fate is unknown; the
+// transport retries idempotent requests itself, so this surfaces for the others.
+func IsIdleConnClosed(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	return strings.Contains(errMsg, "server closed idle connection") ||
+		strings.Contains(errMsg, "http2: client connection lost") ||
+		strings.Contains(errMsg, "http2: client conn is closed") ||
+		strings.Contains(errMsg, "http2: client connection force closed")
+}
+
+// IsProxyError determines if the given error comes from the proxy in front of the request: dialing it, the CONNECT
+// it refused, a SOCKS handshake, or a 407 Proxy Authentication Required response.
+func IsProxyError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusProxyAuthRequired {
+		return true
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	return strings.Contains(errMsg, "proxyconnect") || strings.Contains(errMsg, "socks connect")
+}
+
 // IsIOTimeoutError determines if the given error is an I/O timeout error.
 // It checks for various types of timeout errors, including:
 //   - net.Error with Timeout() == true
@@ -144,17 +350,20 @@ func IsHTTP2GoAwayError(err error) bool {
 //   - String matching for common I/O timeout error messages
 //
 // Returns true if the error is identified as an I/O timeout error,
-// false otherwise or if the input error is nil.
+// false otherwise or if the input error is nil. See IsIOTimeoutErrorWith to exclude errors caused by a cancelled or
+// expired context.
 func IsIOTimeoutError(err error) bool {
-	if err == nil {
+	return IsIOTimeoutErrorWith(err)
+}
+
+// IsIOTimeoutErrorWith is IsIOTimeoutError adjusted by opts, e.g. ExcludeContextErrors.
+func IsIOTimeoutErrorWith(err error, opts ...CheckOption) bool {
+	if err == nil || checkOptionsFrom(opts).excluded(err) {
 		return false
 	}
 
-	var netErr net.Error
-	if errors.As(err, &netErr) {
-		if netErr.Timeout() {
-			return true
-		}
+	if hasTimeout(err) {
+		return true
 	}
 
 	var opErr *net.OpError
@@ -175,3 +384,65 @@ func IsIOTimeoutError(err error) bool {
 		strings.Contains(errMsg, "read timeout") ||
 		strings.Contains(errMsg, "write timeout")
 }
+
+// IsCausedByContext determines if the given error comes from a cancelled or expired context rather than from the
+// network: context.Canceled and context.DeadlineExceeded anywhere in the chain, including the net package errors
+// that wrap them. Note that dial timeouts set with net.Dialer.Timeout are implemented with a context deadline and
+// match too; check the caller's ctx.Err() to know whether the caller gave up.
+func IsCausedByContext(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	errMsg := err.Error()
+	return strings.Contains(errMsg, "context canceled") || strings.Contains(errMsg, "context deadline exceeded")
+}
+
+// CheckOption adjusts an error predicate such as IsDialErrorWith.
+type CheckOption func(*checkOptions)
+
+type checkOptions struct {
+	excludeContext bool
+}
+
+// ExcludeContextErrors makes a predicate return false for errors for which IsCausedByContext is true, so a retry
+// loop stops as soon as its caller gives up instead of treating the cancellation as a network failure.
+//
+// Example usage:
+//
+//	if httpext.IsDialErrorWith(err, httpext.ExcludeContextErrors()) {
+//		return retry.OnConnectionError(ctx, fetch)
+//	}
+func ExcludeContextErrors() CheckOption {
+	return func(o *checkOptions) {
+		o.excludeContext = true
+	}
+}
+
+func checkOptionsFrom(opts []CheckOption) checkOptions {
+	var o checkOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// excluded reports whether the options rule err out before any check.
+func (o checkOptions) excluded(err error) bool {
+	return o.excludeContext && IsCausedByContext(err)
+}
+
+// hasTimeout reports whether any error in err's chain reports Timeout() == true. Unlike errors.As with net.Error it
+// keeps looking past wrappers such as *url.Error whose Timeout method only inspects the next error.
+func hasTimeout(err error) bool {
+	for err != nil {
+		if t, ok := err.(interface{ Timeout() bool }); ok && t.Timeout() {
+			return true
+		}
+		err = errors.Unwrap(err)
+	}
+	return false
+}
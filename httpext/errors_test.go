@@ -0,0 +1,276 @@
+package httpext
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// TestIsTransientTLSError tests TLS classification against error strings recorded in production
+func TestIsTransientTLSError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"closed connection", errors.New(`Get "https://api.example.com/v1/items": tls: use of closed connection`), true},
+		{"handshake EOF", errors.New("http: TLS handshake error from 10.0.3.17:51234: EOF"), true},
+		{"handshake reset", errors.New("tls handshake: read tcp 10.0.3.17:51234->52.1.2.3:443: read: connection reset by peer"), true},
+		{"bad record mac", errors.New(`Post "https://api.example.com/v1/events": local error: tls: bad record MAC`), true},
+		{"unexpected message", errors.New(`Get "https://cdn.example.com/a.json": remote error: tls: unexpected message`), true},
+		{"session resumption", errors.New("tls: server resumed a session with a different version"), true},
+		{"session ticket", errors.New("tls: received a session ticket with invalid lifetime"), true},
+		{"unknown authority", fmt.Errorf("Get \"https://internal\": %w", x509.UnknownAuthorityError{}), false},
+		{"expired", errors.New(`Get "https://old.example.com": tls: failed to verify certificate: x509: certificate has expired or is not yet valid`), false},
+		{"bad certificate", errors.New("remote error: tls: bad certificate"), false},
+		{"pin mismatch during handshake", fmt.Errorf("tls handshake: %w: EOF", ErrPinMismatch), false},
+		{"plain", errors.New("invalid character '<' looking for beginning of value"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientTLSError(tt.err); got != tt.transient {
+				t.Errorf("Expected IsTransientTLSError %v, got %v", tt.transient, got)
+			}
+			if tt.err != nil && IsTransientNetworkOrDNSIssueErr(tt.err) != tt.transient {
+				t.Errorf("Expected IsTransientNetworkOrDNSIssueErr %v", tt.transient)
+			}
+		})
+	}
+}
+
+// TestCertificateErrors tests TLS and certificate detection against typed errors and a real handshake
+func TestCertificateErrors(t *testing.T) {
+	expired := fmt.Errorf("Get \"https://old.example.com\": %w",
+		&tls.CertificateVerificationError{Err: x509.CertificateInvalidError{Reason: x509.Expired}})
+	if !IsTLSError(expired) || !IsCertExpiredError(expired) || IsHostnameMismatchError(expired) {
+		t.Errorf("Expected an expired certificate, got %v", expired)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	client := srv.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.ServerName = "wrong.example.net"
+
+	_, err := client.Get(srv.URL)
+	if !IsTLSError(err) || !IsHostnameMismatchError(err) || IsCertExpiredError(err) {
+		t.Errorf("Expected a hostname mismatch, got %v", err)
+	}
+	if IsTransientNetworkOrDNSIssueErr(err) {
+		t.Errorf("Expected a hostname mismatch not to be transient")
+	}
+
+	alert := fmt.Errorf("remote error: %w", tls.AlertError(40))
+	if !IsTLSError(alert) || IsCertificateError(alert) {
+		t.Errorf("Expected a TLS alert, got %v", alert)
+	}
+	if plain := errors.New("connection refused"); IsTLSError(plain) || IsCertExpiredError(nil) || IsHostnameMismatchError(nil) {
+		t.Error("Expected non-TLS errors not to match")
+	}
+}
+
+// TestClassify tests the built-in categories and rules registered on top of them
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		category Category
+	}{
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}, CategoryDial},
+		{"dial timeout", errors.New("dial tcp 10.0.0.1:443: i/o timeout"), CategoryTimeout},
+		{"no such host", &net.DNSError{Err: "no such host", Name: "api.example.com", IsNotFound: true}, CategoryDial},
+		{"read timeout", errors.New(`Get "https://api.example.com": net/http: request canceled (Client.Timeout exceeded while awaiting headers) read timeout`), CategoryTimeout},
+		{"reset", errors.New("read tcp 10.0.3.17:51234->52.1.2.3:443: read: connection reset by peer"), CategoryReset},
+		{"goaway", errors.New(`http2: server sent GOAWAY and closed the connection; LastStreamID=1999`), CategoryGoAway},
+		{"tls", errors.New("local error: tls: bad record MAC"), CategoryTLS},
+		{"truncated", fmt.Errorf("%w: read 32 of 64 bytes", ErrTruncatedBody), CategoryTransient},
+		{"certificate", x509.UnknownAuthorityError{}, CategoryNone},
+		{"plain", errors.New("invalid character '<' looking for beginning of value"), CategoryNone},
+		{"nil", nil, CategoryNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.category {
+				t.Errorf("Expected %q, got %q", tt.category, got)
+			}
+		})
+	}
+
+	throttled := errors.New("ThrottlingException: Rate exceeded")
+	busy := errors.New("upstream returned code=SERVER_BUSY retry=2")
+	classifier := NewClassifier(
+		SubstringRule(CategoryTransient, "throttlingexception"),
+		RegexRule(CategoryTransient, regexp.MustCompile(`code=SERVER_BUSY\b`)),
+		TypeRule[*net.DNSError](CategoryDial),
+	)
+	if classifier.Classify(throttled) != CategoryTransient || classifier.Classify(busy) != CategoryTransient ||
+		classifier.Classify(fmt.Errorf("lookup: %w", &net.DNSError{Err: "server misbehaving"})) != CategoryDial {
+		t.Error("Expected substring, regex and type rules to match")
+	}
+	if got := classifier.Classify(errors.New("connection reset by peer")); got != CategoryNone {
+		t.Errorf("Expected a classifier from NewClassifier to skip the built-in categories, got %q", got)
+	}
+
+	t.Cleanup(func() {
+		DefaultClassifier.rules = nil
+	})
+	if IsTransientNetworkOrDNSIssueErr(throttled) {
+		t.Fatal("Expected the throttling error not to be transient before registering a rule")
+	}
+	RegisterRule(SubstringRule(CategoryTransient, "ThrottlingException"))
+	RegisterRule(SubstringRule(CategoryNone, "Rate exceeded for account"))
+	if Classify(throttled) != CategoryTransient || !IsTransientNetworkOrDNSIssueErr(throttled) {
+		t.Error("Expected a registered rule to make the error transient")
+	}
+	if excluded := errors.New("ThrottlingException: Rate exceeded for account"); IsTransientNetworkOrDNSIssueErr(excluded) {
+		t.Error("Expected the later rule with CategoryNone to take precedence")
+	}
+	if rules := DefaultClassifier.Rules(); len(rules) != 2 || rules[0].Category != CategoryNone {
+		t.Errorf("Expected the last registered rule first, got %v", rules)
+	}
+}
+
+// TestIsCausedByContext tests separating caller cancellation from network failures
+func TestIsCausedByContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := (&net.Dialer{}).DialContext(ctx, "tcp", "127.0.0.1:1")
+	if !IsCausedByContext(err) {
+		t.Fatalf("Expected a dial with a cancelled context to be caused by it, got %v", err)
+	}
+	if !IsDialError(err) || IsDialErrorWith(err, ExcludeContextErrors()) {
+		t.Errorf("Expected ExcludeContextErrors to rule out %v", err)
+	}
+
+	expired := fmt.Errorf("read body: %w", context.DeadlineExceeded)
+	if !IsIOTimeoutError(expired) || IsIOTimeoutErrorWith(expired, ExcludeContextErrors()) {
+		t.Errorf("Expected ExcludeContextErrors to rule out %v", expired)
+	}
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}
+	if IsCausedByContext(refused) || !IsDialErrorWith(refused, ExcludeContextErrors()) {
+		t.Errorf("Expected %v to stay a dial error", refused)
+	}
+	if IsCausedByContext(nil) {
+		t.Error("Expected nil not to be caused by a context")
+	}
+}
+
+// TestAsDNSFailure tests the DNS failure details and that the predicates no longer log them
+func TestAsDNSFailure(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+	})
+
+	notFound := &url.Error{Op: "Get", URL: "https://api.example.invalid", Err: &net.OpError{Op: "dial", Net: "tcp",
+		Err: &net.DNSError{Err: "no such host", Name: "api.example.invalid", Server: "10.0.0.2:53", IsNotFound: true}}}
+	failure, ok := AsDNSFailure(notFound)
+	if !ok || failure.Name != "api.example.invalid" || failure.Server != "10.0.0.2:53" || !failure.IsNotFound || failure.Retryable() {
+		t.Errorf("Expected a non-retryable NXDOMAIN failure, got %+v", failure)
+	}
+
+	timeout := fmt.Errorf("lookup: %w", &net.DNSError{Err: "i/o timeout", Name: "api.example.com", IsTimeout: true})
+	if failure, ok := AsDNSFailure(timeout); !ok || !failure.Retryable() || !IsDialError(timeout) {
+		t.Errorf("Expected a retryable timeout classified as a dial error, got %+v", failure)
+	}
+	if _, ok := AsDNSFailure(errors.New("no such host")); ok {
+		t.Error("Expected only *net.DNSError to be reported")
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected the predicates not to log, got %q", logs.String())
+	}
+}
+
+// TestTransportErrors tests stream, pool, idle connection and proxy detection against real transports
+func TestTransportErrors(t *testing.T) {
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/abort":
+			panic(http.ErrAbortHandler)
+		case "/block":
+			<-release
+		}
+	})
+
+	h2 := httptest.NewUnstartedServer(handler)
+	h2.Config.ErrorLog = log.New(io.Discard, "", 0)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+
+	_, err := h2.Client().Get(h2.URL + "/abort")
+	if !IsStreamError(err) || Classify(err) != CategoryStream || !IsTransientNetworkOrDNSIssueErr(err) || IsDialError(err) {
+		t.Errorf("Expected a transient stream error, got %v", err)
+	}
+	if !hasStreamErrorType(err) {
+		t.Errorf("Expected the StreamError bundled in net/http matched by type name, got %T in %v", errors.Unwrap(err), err)
+	}
+
+	h1 := httptest.NewServer(handler)
+	defer h1.Close()
+	defer close(release)
+
+	client := NewClient(ClientConfig{Transport: &http.Transport{MaxConnsPerHost: 1}, TracePhases: true})
+	go func() {
+		if resp, err := client.Get(h1.URL + "/block"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, h1.URL, nil)
+	_, err = client.Do(req)
+	if !IsConnectionPoolExhausted(err) {
+		t.Errorf("Expected pool exhaustion, got %v", err)
+	}
+
+	proxied := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: "127.0.0.1:1"})}}
+	_, err = proxied.Get("https://example.com")
+	if !IsProxyError(err) || IsProxyError(dialErrorFor("example.com:443")) {
+		t.Errorf("Expected a proxy error, got %v", err)
+	}
+	if !IsProxyError(&StatusError{Code: http.StatusProxyAuthRequired}) {
+		t.Error("Expected 407 to be a proxy error")
+	}
+
+	idle := fmt.Errorf(`Post "https://api.example.com/v1/events": %w`, errors.New("http: server closed idle connection"))
+	if !IsIdleConnClosed(idle) || Classify(idle) != CategoryIdleConn || IsIdleConnClosed(nil) {
+		t.Errorf("Expected an idle connection closed, got %v", idle)
+	}
+}
+
+func dialErrorFor(addr string) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Addr: &net.TCPAddr{}, Err: fmt.Errorf("connect %s: connection refused", addr)}
+}
+
+// TestPredicateSignatures tests that the predicates keep the func(error) bool shape callers pass around
+func TestPredicateSignatures(t *testing.T) {
+	predicates := []func(error) bool{IsDialError, IsIOTimeoutError, IsCausedByContext}
+	for _, predicate := range predicates {
+		if predicate(nil) {
+			t.Error("Expected nil to match no predicate")
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package httpext
+
+import (
+	"github.com/mhpenta/app"
+	"net/http"
+)
+
+// HandlerFunc is an http.Handler that returns an error instead of writing failure responses itself. Errors are
+// answered with WriteError: the status is taken from app.HTTPStatus, defaulting to 500, client errors (4xx) carry
+// the error message, and server errors are logged, passed to app.Report and, in app.ReleaseMode, answered without it
+// so internals are not exposed.
+//
+// Example usage:
+//
+//	mux.Handle("/filings/", httpext.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		filing, err := store.Filing(r.Context(), path.Base(r.URL.Path))
+//		if err != nil {
+//			return err // store returns app.NewMetaError(err).WithHTTPStatus(http.StatusNotFound) for unknown IDs
+//		}
+//		httpext.WriteJSON(w, http.StatusOK, filing)
+//		return nil
+//	}))
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP implements http.Handler.
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// StatusForError returns the HTTP status carried by err, see app.HTTPStatus, or 500 when it carries none.
+func StatusForError(err error) int {
+	if status, ok := app.HTTPStatus(err); ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,121 @@
+package httpext
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrHostNotAllowed is wrapped by the error HostPolicy returns for requests to hosts outside its allowlist or on
+// its denylist. The error is marked permanent, so retry loops do not repeat the request.
+var ErrHostNotAllowed = errors.New("outbound host not allowed")
+
+// HostPolicy restricts outbound requests to approved hosts. Patterns are host names, matched case-insensitively and
+// without the port; a "*." prefix matches any subdomain but not the domain itself, so "*.example.com" matches
+// "api.example.com" and "a.b.example.com" but not "example.com".
+type HostPolicy struct {
+	// Allow lists the permitted hosts. When empty every host not denied is permitted.
+	Allow []string
+	// Deny lists hosts that are always rejected, even when allowed
+	Deny []string
+	// Audit, when set, is called with every decision, e.g. to forward it to a compliance log
+	Audit func(decision HostDecision)
+}
+
+// HostDecision records one decision made by a HostPolicy.
+type HostDecision struct {
+	Host    string    `json:"host"`
+	URL     string    `json:"url"`
+	Allowed bool      `json:"allowed"`
+	Rule    string    `json:"rule,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Check reports whether requests to host are permitted, returning an error wrapping ErrHostNotAllowed when not.
+func (p *HostPolicy) Check(host string) error {
+	_, err := p.decide(host)
+	return err
+}
+
+// decide returns the rule that decided host and an error when the host is rejected.
+func (p *HostPolicy) decide(host string) (string, error) {
+	host = normalizeHost(host)
+
+	for _, pattern := range p.Deny {
+		if matchHost(pattern, host) {
+			return pattern, fmt.Errorf("%w: %s is denied by %q", ErrHostNotAllowed, host, pattern)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return "", nil
+	}
+	for _, pattern := range p.Allow {
+		if matchHost(pattern, host) {
+			return pattern, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s is not in the allowlist", ErrHostNotAllowed, host)
+}
+
+// Transport returns an http.RoundTripper that checks every request, including each redirect followed by an
+// http.Client, before passing it to base. Rejected requests are logged and never reach the network.
+//
+// Example usage:
+//
+//	policy := &httpext.HostPolicy{Allow: []string{"*.sec.gov", "api.example.com"}}
+//	client := &http.Client{Transport: policy.Transport(nil)}
+func (p *HostPolicy) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &hostPolicyTransport{policy: p, base: base}
+}
+
+type hostPolicyTransport struct {
+	policy *HostPolicy
+	base   http.RoundTripper
+}
+
+func (t *hostPolicyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := normalizeHost(req.URL.Host)
+	rule, err := t.policy.decide(host)
+
+	if t.policy.Audit != nil {
+		t.policy.Audit(HostDecision{
+			Host:    host,
+			URL:     req.URL.Redacted(),
+			Allowed: err == nil,
+			Rule:    rule,
+			At:      app.Now(),
+		})
+	}
+
+	if err != nil {
+		slog.Warn("Outbound request blocked by host policy", "host", host, "method", req.Method, "error", err)
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, app.MarkPermanent(err)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+}
+
+func matchHost(pattern string, host string) bool {
+	pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
@@ -0,0 +1,51 @@
+package httpext
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHostPolicy tests allowlist wildcards, denylist precedence and enforcement on redirects
+func TestHostPolicy(t *testing.T) {
+	policy := &HostPolicy{
+		Allow: []string{"*.example.com", "127.0.0.1"},
+		Deny:  []string{"internal.example.com"},
+	}
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"api.example.com", true},
+		{"A.B.Example.com:8443", true},
+		{"example.com", false},
+		{"internal.example.com", false},
+		{"evil-example.com", false},
+	}
+	for _, tt := range tests {
+		if err := policy.Check(tt.host); (err == nil) != tt.allowed {
+			t.Errorf("Expected %s allowed=%v, got %v", tt.host, tt.allowed, err)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://internal.example.com/secrets", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	var decisions []HostDecision
+	policy.Audit = func(decision HostDecision) {
+		decisions = append(decisions, decision)
+	}
+
+	client := NewClient(ClientConfig{HostPolicy: policy})
+	_, err := client.Get(srv.URL)
+	if !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("Expected redirect to a denied host to fail with ErrHostNotAllowed, got %v", err)
+	}
+	if len(decisions) != 2 || !decisions[0].Allowed || decisions[1].Allowed || decisions[1].Rule != "internal.example.com" {
+		t.Errorf("Expected an allowed then a denied decision, got %+v", decisions)
+	}
+}
@@ -0,0 +1,323 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"github.com/mhpenta/app/jsonext"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrStopPagination can be returned by a page handler to end Paginate early without an error.
+	ErrStopPagination = errors.New("stop pagination")
+
+	// ErrTooManyPages is reported by Paginate when a listing has more pages than PaginateConfig.MaxPages.
+	ErrTooManyPages = errors.New("pagination exceeded max pages")
+)
+
+// Page is one page fetched by Paginate. Body has been read and closed.
+type Page struct {
+	// Number is the 1-based page number
+	Number     int
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// PaginateConfig holds configuration for PaginateWithConfig
+type PaginateConfig struct {
+	// MaxPages caps the number of pages fetched, guarding against listings that never end
+	MaxPages int
+	// CursorPath is the path, in jsonext.Get syntax, of the next-page cursor in the response body, e.g.
+	// "meta.next_cursor". When set and the response has no Link rel="next" header, the next page is the first URL
+	// with CursorParam set to the cursor; an empty cursor ends the listing.
+	CursorPath string
+	// CursorParam is the query parameter carrying the cursor
+	CursorParam string
+	// Retry controls the retries of each page. Responses with status 429, 502, 503 and 504 are retried as well as
+	// transport errors accepted by Retry.Retryable, waiting for Retry-After when the server sends it.
+	Retry RetryTransportConfig
+	// MaxRateLimitWait caps the pause taken between pages when rate-limit headers report an exhausted quota, and the
+	// wait for a Retry-After sent with a retried page
+	MaxRateLimitWait time.Duration
+}
+
+// DefaultPaginateConfig provides sensible default values for PaginateConfig
+var DefaultPaginateConfig = PaginateConfig{
+	MaxPages:         1000,
+	CursorParam:      "cursor",
+	Retry:            DefaultRetryTransportConfig,
+	MaxRateLimitWait: time.Minute,
+}
+
+// Paginate fetches firstURL and every following page, calling handlePage for each, using DefaultPaginateConfig. See
+// PaginateWithConfig.
+//
+// Example usage:
+//
+//	err := httpext.Paginate(ctx, client, "https://api.example.com/filings?limit=100", func(ctx context.Context, page httpext.Page) error {
+//		var batch []Filing
+//		if err := json.Unmarshal(page.Body, &batch); err != nil {
+//			return err
+//		}
+//		return store.Save(ctx, batch)
+//	})
+func Paginate(ctx context.Context, client *http.Client, firstURL string, handlePage func(ctx context.Context, page Page) error) error {
+	return PaginateWithConfig(ctx, client, firstURL, handlePage, DefaultPaginateConfig)
+}
+
+// PaginateWithConfig walks a paginated listing. The next page is taken from the Link rel="next" header, or from the
+// cursor field described by config. Each page is retried on its own, and when X-RateLimit-Remaining or
+// RateLimit-Remaining reports an exhausted quota the walk pauses until the matching reset header allows more
+// requests.
+//
+// A handler error does not stop the walk; it is collected, labelled with the page number and URL, into the returned
+// *app.MultiError. A page that cannot be fetched ends the walk, since the following pages are unknown. Returning
+// ErrStopPagination from handlePage ends the walk early.
+func PaginateWithConfig(ctx context.Context, client *http.Client, firstURL string, handlePage func(ctx context.Context, page Page) error, config PaginateConfig) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if config.MaxPages <= 0 {
+		config.MaxPages = DefaultPaginateConfig.MaxPages
+	}
+	if config.CursorParam == "" {
+		config.CursorParam = DefaultPaginateConfig.CursorParam
+	}
+	if config.Retry.MaxAttempts <= 0 {
+		config.Retry.MaxAttempts = DefaultPaginateConfig.Retry.MaxAttempts
+	}
+	if config.Retry.Backoff == nil {
+		config.Retry.Backoff = DefaultPaginateConfig.Retry.Backoff
+	}
+	if config.Retry.Retryable == nil {
+		config.Retry.Retryable = IsTransientNetworkOrDNSIssueErr
+	}
+	if config.MaxRateLimitWait <= 0 {
+		config.MaxRateLimitWait = DefaultPaginateConfig.MaxRateLimitWait
+	}
+
+	var mErr app.MultiError
+	pageURL := firstURL
+
+	for number := 1; pageURL != ""; number++ {
+		label := fmt.Sprintf("page %d (%s)", number, pageURL)
+		if number > config.MaxPages {
+			mErr.Append(fmt.Errorf("%w: %d", ErrTooManyPages, config.MaxPages))
+			break
+		}
+
+		page, err := fetchPage(ctx, client, pageURL, config.Retry, config.MaxRateLimitWait)
+		if err != nil {
+			mErr.AppendWrapped(label, err)
+			break
+		}
+		page.Number = number
+
+		if err := handlePage(ctx, page); err != nil {
+			if errors.Is(err, ErrStopPagination) {
+				break
+			}
+			mErr.AppendWrapped(label, err)
+		}
+
+		next, err := nextPageURL(page, firstURL, config)
+		if err != nil {
+			mErr.AppendWrapped(label, err)
+			break
+		}
+		if next == pageURL {
+			break
+		}
+
+		if wait, limited := rateLimitWait(page.Header, app.Now()); limited && next != "" {
+			if wait > config.MaxRateLimitWait {
+				wait = config.MaxRateLimitWait
+			}
+			if err := sleepContext(ctx, wait); err != nil {
+				mErr.AppendWrapped(label, err)
+				break
+			}
+		}
+		pageURL = next
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// fetchPage GETs pageURL, retrying transient failures according to config. A Retry-After longer than maxServerDelay
+// is cut down to it.
+func fetchPage(ctx context.Context, client *http.Client, pageURL string, config RetryTransportConfig, maxServerDelay time.Duration) (Page, error) {
+	maxAttempts, err := gateAttempts(config.MaxAttempts)
+	if err != nil {
+		return Page{}, err
+	}
+	config.MaxAttempts = maxAttempts
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return Page{}, err
+		}
+
+		var delay time.Duration
+		var serverDelay bool
+		resp, err := client.Do(req)
+		switch {
+		case err != nil:
+			if !config.Retryable(err) {
+				return Page{}, err
+			}
+		case isRetryableStatus(resp.StatusCode):
+			delay, serverDelay = retryAfter(resp.Header, app.Now())
+			err = NewStatusError(resp)
+		case resp.StatusCode < 200 || resp.StatusCode > 299:
+			return Page{}, NewStatusError(resp)
+		default:
+			var body []byte
+			body, err = ReadVerifiedBody(resp)
+			if err == nil {
+				return Page{URL: pageURL, StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+			}
+			if !errors.Is(err, ErrTruncatedBody) {
+				return Page{}, err
+			}
+		}
+
+		if attempt >= config.MaxAttempts {
+			return Page{}, fmt.Errorf("after %d attempts: %w", attempt, err)
+		}
+		if !serverDelay {
+			delay = config.Backoff(attempt)
+		} else if delay > maxServerDelay {
+			delay = maxServerDelay
+		}
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return Page{}, err
+		}
+	}
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// nextPageURL returns the URL of the page after page, or "" when it is the last one.
+func nextPageURL(page Page, firstURL string, config PaginateConfig) (string, error) {
+	if next := linkNext(page.Header); next != "" {
+		base, err := url.Parse(page.URL)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(next)
+		if err != nil {
+			return "", fmt.Errorf("%w: Link header: %v", ErrInvalidURL, err)
+		}
+		return base.ResolveReference(ref).String(), nil
+	}
+
+	if config.CursorPath == "" {
+		return "", nil
+	}
+	cursor, ok := jsonext.GetString(page.Body, config.CursorPath)
+	if !ok || cursor == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(firstURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set(config.CursorParam, cursor)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// linkNext returns the target of the rel="next" entry of the Link headers (RFC 8288), or "".
+func linkNext(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, _ := strings.Cut(strings.TrimSpace(link), ";")
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(val, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as an HTTP date.
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// rateLimitWait reports how long to pause when the rate-limit headers announce that no requests remain. Reset values
+// above one billion are read as Unix timestamps, smaller ones as seconds from now.
+func rateLimitWait(header http.Header, now time.Time) (time.Duration, bool) {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		remaining, err := strconv.Atoi(strings.TrimSpace(header.Get(prefix + "Remaining")))
+		if err != nil || remaining > 0 {
+			continue
+		}
+
+		reset, err := strconv.ParseInt(strings.TrimSpace(header.Get(prefix+"Reset")), 10, 64)
+		if err != nil || reset < 0 {
+			return 0, false
+		}
+		if reset > 1_000_000_000 {
+			wait := time.Unix(reset, 0).Sub(now)
+			return wait, wait > 0
+		}
+		return time.Duration(reset) * time.Second, reset > 0
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-app.After(d):
+		return nil
+	}
+}
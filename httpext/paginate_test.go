@@ -0,0 +1,88 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPaginate tests following Link headers and cursors, retrying a throttled page and labelling handler failures
+func TestPaginate(t *testing.T) {
+	throttled := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Header().Set("Link", `</items?cursor=b>; rel="next", </items>; rel="first"`)
+			_, _ = w.Write([]byte(`{"items":["a"]}`))
+		case "b":
+			if !throttled {
+				throttled = true
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(`{"items":["b"],"next":"c"}`))
+		case "c":
+			_, _ = w.Write([]byte(`{"items":["c"],"next":""}`))
+		}
+	}))
+	defer srv.Close()
+
+	config := DefaultPaginateConfig
+	config.CursorPath = "next"
+
+	var pages []string
+	err := PaginateWithConfig(context.Background(), srv.Client(), srv.URL+"/items", func(ctx context.Context, page Page) error {
+		pages = append(pages, string(page.Body))
+		if page.Number == 2 {
+			return errors.New("store unavailable")
+		}
+		return nil
+	}, config)
+
+	if len(pages) != 3 || !throttled {
+		t.Fatalf("Expected 3 pages after a throttled retry, got %d: %v", len(pages), pages)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "page 2 (") || !strings.HasSuffix(err.Error(), "): store unavailable") {
+		t.Errorf("Expected labelled page 2 failure, got %v", err)
+	}
+}
+
+// TestPaginateCapsRetryAfter tests that a Retry-After longer than MaxRateLimitWait is cut down to it
+func TestPaginateCapsRetryAfter(t *testing.T) {
+	app.TestMode(t)
+
+	throttled := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !throttled {
+			throttled = true
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":["a"]}`))
+	}))
+	defer srv.Close()
+
+	config := DefaultPaginateConfig
+	config.MaxRateLimitWait = 30 * time.Second
+
+	start := app.Now()
+	pages := 0
+	err := PaginateWithConfig(context.Background(), srv.Client(), srv.URL+"/items", func(ctx context.Context, page Page) error {
+		pages++
+		return nil
+	}, config)
+
+	if err != nil || pages != 1 || !throttled {
+		t.Fatalf("Expected the page fetched after a retry, got %d pages, %v", pages, err)
+	}
+	if waited := app.Since(start); waited != config.MaxRateLimitWait {
+		t.Errorf("Expected a wait of %s, got %s", config.MaxRateLimitWait, waited)
+	}
+}
@@ -0,0 +1,138 @@
+package httpext
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request phases reported by PhaseTransport.
+const (
+	PhaseWaitConn     = "waiting for connection"
+	PhaseDNS          = "dns"
+	PhaseConnect      = "connect"
+	PhaseTLS          = "tls"
+	PhaseWriteRequest = "write request"
+	PhaseWaitHeaders  = "waiting for headers"
+	PhaseReadBody     = "reading body"
+)
+
+// PhaseField is the MetaError field holding the phase a request timed out in.
+const PhaseField = "phase"
+
+// PhaseTransport is an http.RoundTripper that follows each request through httptrace and, when it times out,
+// returns a *app.MetaError naming the phase it was in: waiting for a pooled connection, DNS, connect, TLS, writing the request, waiting for headers
+// or reading the body. The phase is stored in the PhaseField field and in the message, e.g. "timeout during tls:
+// net/http: TLS handshake timeout", and the original error stays reachable through errors.Is and errors.As.
+type PhaseTransport struct {
+	Base http.RoundTripper
+}
+
+var phases = []string{PhaseWaitConn, PhaseDNS, PhaseConnect, PhaseTLS, PhaseWriteRequest, PhaseWaitHeaders, PhaseReadBody}
+
+// TimeoutPhase returns the phase recorded by PhaseTransport on a timeout error. When http.Client.Timeout fires, the
+// client replaces the transport error with a plain message, so the phase is then recovered from the message.
+func TimeoutPhase(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	var metaErr *app.MetaError
+	if errors.As(err, &metaErr) {
+		if phase, ok := metaErr.Fields[PhaseField].(string); ok {
+			return phase, true
+		}
+	}
+
+	msg := err.Error()
+	for _, phase := range phases {
+		if strings.Contains(msg, "timeout during "+phase+": ") {
+			return phase, true
+		}
+	}
+	return "", false
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *PhaseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	tracker := &phaseTracker{phase: PhaseConnect, start: app.Now()}
+	trace := &httptrace.ClientTrace{
+		GetConn:           func(string) { tracker.set(PhaseWaitConn) },
+		DNSStart:          func(httptrace.DNSStartInfo) { tracker.set(PhaseDNS) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { tracker.set(PhaseConnect) },
+		ConnectStart:      func(string, string) { tracker.set(PhaseConnect) },
+		TLSHandshakeStart: func() { tracker.set(PhaseTLS) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { tracker.set(PhaseWriteRequest) },
+		GotConn:           func(httptrace.GotConnInfo) { tracker.set(PhaseWriteRequest) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { tracker.set(PhaseWaitHeaders) },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, tracker.wrap(req.Context(), err)
+	}
+
+	tracker.set(PhaseReadBody)
+	resp.Body = &phaseBody{ReadCloser: resp.Body, ctx: req.Context(), tracker: tracker}
+	return resp, nil
+}
+
+type phaseTracker struct {
+	mu    sync.Mutex
+	phase string
+	start time.Time
+}
+
+func (p *phaseTracker) set(phase string) {
+	p.mu.Lock()
+	p.phase = phase
+	p.mu.Unlock()
+}
+
+func (p *phaseTracker) get() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.phase
+}
+
+// wrap attributes err to the current phase when it is a timeout, and returns it unchanged otherwise.
+func (p *phaseTracker) wrap(ctx context.Context, err error) error {
+	timedOut := IsIOTimeoutError(err) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if !timedOut {
+		return err
+	}
+
+	phase := p.get()
+	return app.NewMetaErrorOptions(fmt.Errorf("timeout during %s: %w", phase, err), 2, true, true).
+		WithField(PhaseField, phase).
+		WithField("elapsed", app.Since(p.start).String())
+}
+
+type phaseBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	tracker *phaseTracker
+}
+
+func (b *phaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = b.tracker.wrap(b.ctx, err)
+	}
+	return n, err
+}
@@ -0,0 +1,49 @@
+package httpext
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestPhaseTransportTimeout tests that timeouts are attributed to the phase they occurred in
+func TestPhaseTransportTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/body" {
+			_, _ = w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+		}
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := NewClient(ClientConfig{TracePhases: true})
+
+	get := func(path string) (*http.Response, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		t.Cleanup(cancel)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		return client.Do(req)
+	}
+
+	_, err := get("/headers")
+	if phase, ok := TimeoutPhase(err); !ok || phase != PhaseWaitHeaders {
+		t.Errorf("Expected phase %q, got %q (%v)", PhaseWaitHeaders, phase, err)
+	}
+	if !IsTransientNetworkOrDNSIssueErr(err) {
+		t.Error("Expected phase-attributed timeout to stay transient")
+	}
+
+	resp, err := get("/body")
+	if err != nil {
+		t.Fatalf("Expected headers to arrive, got %v", err)
+	}
+	_, err = ReadVerifiedBody(resp)
+	if phase, ok := TimeoutPhase(err); !ok || phase != PhaseReadBody {
+		t.Errorf("Expected phase %q, got %q (%v)", PhaseReadBody, phase, err)
+	}
+}
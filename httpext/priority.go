@@ -0,0 +1,327 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrRequestShed is wrapped by the error PriorityQueue returns for low-priority requests dropped because their
+// context would expire before they leave the queue.
+var ErrRequestShed = errors.New("request shed by priority queue")
+
+// Priority orders outbound requests sharing a PriorityQueue. The zero value is PriorityNormal.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+func (p Priority) index() int {
+	switch {
+	case p < PriorityNormal:
+		return 0
+	case p > PriorityNormal:
+		return 2
+	}
+	return 1
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose requests are queued at priority p by PriorityQueue transports.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set with WithPriority, or PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// PriorityConfig holds configuration for a PriorityQueue
+type PriorityConfig struct {
+	// MaxConcurrent is the number of requests in flight across all priorities
+	MaxConcurrent int
+	// HighShare, NormalShare and LowShare are the fraction of MaxConcurrent each priority may occupy, between 0
+	// and 1. Every priority can always run at least one request.
+	HighShare   float64
+	NormalShare float64
+	LowShare    float64
+}
+
+// DefaultPriorityConfig provides sensible default values for PriorityConfig
+var DefaultPriorityConfig = PriorityConfig{
+	MaxConcurrent: 16,
+	HighShare:     1,
+	NormalShare:   0.75,
+	LowShare:      0.25,
+}
+
+// PriorityStats is a snapshot of one priority level of a PriorityQueue.
+type PriorityStats struct {
+	Priority string `json:"priority"`
+	InFlight int    `json:"inFlight"`
+	Queued   int    `json:"queued"`
+	Shed     int64  `json:"shed"`
+}
+
+// PriorityQueue limits the requests in flight through a shared client and hands free slots to the highest waiting
+// priority first, so interactive calls are not starved by background backfills. Each priority is capped at its share
+// of the slots, and low-priority requests whose context deadline would pass while queued are shed up front with
+// ErrRequestShed instead of occupying the queue.
+//
+// Example usage:
+//
+//	queue := httpext.NewPriorityQueue(httpext.DefaultPriorityConfig)
+//	client := httpext.NewClient(httpext.ClientConfig{Priority: queue})
+//
+//	req, _ := http.NewRequestWithContext(httpext.WithPriority(ctx, httpext.PriorityLow), http.MethodGet, u, nil)
+//	resp, err := client.Do(req)
+type PriorityQueue struct {
+	config PriorityConfig
+	limits [3]int
+
+	mu         sync.Mutex
+	inFlight   [3]int
+	total      int
+	waiting    [3][]*queuedRequest
+	shed       [3]int64
+	avgLatency time.Duration
+}
+
+type queuedRequest struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewPriorityQueue creates a PriorityQueue. Zero fields in config take their value from DefaultPriorityConfig.
+func NewPriorityQueue(config PriorityConfig) *PriorityQueue {
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = DefaultPriorityConfig.MaxConcurrent
+	}
+	if config.HighShare <= 0 || config.HighShare > 1 {
+		config.HighShare = DefaultPriorityConfig.HighShare
+	}
+	if config.NormalShare <= 0 || config.NormalShare > 1 {
+		config.NormalShare = DefaultPriorityConfig.NormalShare
+	}
+	if config.LowShare <= 0 || config.LowShare > 1 {
+		config.LowShare = DefaultPriorityConfig.LowShare
+	}
+
+	q := &PriorityQueue{config: config}
+	for i, share := range []float64{config.LowShare, config.NormalShare, config.HighShare} {
+		q.limits[i] = int(share * float64(config.MaxConcurrent))
+		if q.limits[i] < 1 {
+			q.limits[i] = 1
+		}
+	}
+	return q
+}
+
+// Acquire waits for a slot for a request at priority p. It returns an error wrapping ErrRequestShed when a
+// low-priority request cannot be served before its deadline, or the context error when ctx ends while waiting. On
+// success the returned function must be called once the request is done.
+func (q *PriorityQueue) Acquire(ctx context.Context, p Priority) (release func(), err error) {
+	idx := p.index()
+
+	q.mu.Lock()
+	if p < PriorityNormal {
+		if deadline, ok := ctx.Deadline(); ok {
+			if wait := q.estimatedWait(idx); wait > 0 && time.Until(deadline) < wait+q.avgLatency {
+				q.shed[idx]++
+				q.mu.Unlock()
+				return nil, fmt.Errorf("%w: estimated wait %v exceeds deadline in %v", ErrRequestShed,
+					wait.Round(time.Millisecond), time.Until(deadline).Round(time.Millisecond))
+			}
+		}
+	}
+
+	waiter := &queuedRequest{ready: make(chan struct{})}
+	q.waiting[idx] = append(q.waiting[idx], waiter)
+	q.dispatch()
+	q.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+	case <-ctx.Done():
+		q.mu.Lock()
+		if !waiter.granted {
+			q.remove(idx, waiter)
+			q.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		q.mu.Unlock()
+	}
+
+	start := app.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.release(idx, app.Since(start))
+		})
+	}, nil
+}
+
+// Stats returns a snapshot of every priority level, highest first.
+func (q *PriorityQueue) Stats() []PriorityStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]PriorityStats, 0, 3)
+	for i, p := range []Priority{PriorityHigh, PriorityNormal, PriorityLow} {
+		idx := 2 - i
+		out = append(out, PriorityStats{
+			Priority: p.String(),
+			InFlight: q.inFlight[idx],
+			Queued:   len(q.waiting[idx]),
+			Shed:     q.shed[idx],
+		})
+	}
+	return out
+}
+
+// estimatedWait returns how long a new request at idx is expected to queue, based on the requests ahead of it and
+// the average latency of completed requests. It returns 0 when the request would run immediately or no latency has
+// been observed yet.
+func (q *PriorityQueue) estimatedWait(idx int) time.Duration {
+	if q.avgLatency == 0 {
+		return 0
+	}
+
+	ahead := 0
+	for i := idx; i < len(q.waiting); i++ {
+		ahead += len(q.waiting[i])
+	}
+	if ahead == 0 && q.total < q.config.MaxConcurrent && q.inFlight[idx] < q.limits[idx] {
+		return 0
+	}
+
+	slots := q.limits[idx]
+	if free := q.config.MaxConcurrent - q.total + q.inFlight[idx]; free < slots {
+		slots = free
+	}
+	if slots < 1 {
+		slots = 1
+	}
+	return time.Duration(ahead/slots+1) * q.avgLatency
+}
+
+// dispatch grants free slots to waiting requests, highest priority first. It must be called with mu held.
+func (q *PriorityQueue) dispatch() {
+	for q.total < q.config.MaxConcurrent {
+		granted := false
+		for idx := len(q.waiting) - 1; idx >= 0; idx-- {
+			if len(q.waiting[idx]) == 0 || q.inFlight[idx] >= q.limits[idx] {
+				continue
+			}
+			waiter := q.waiting[idx][0]
+			q.waiting[idx] = q.waiting[idx][1:]
+			waiter.granted = true
+			close(waiter.ready)
+			q.inFlight[idx]++
+			q.total++
+			granted = true
+			break
+		}
+		if !granted {
+			return
+		}
+	}
+}
+
+func (q *PriorityQueue) remove(idx int, waiter *queuedRequest) {
+	for i, w := range q.waiting[idx] {
+		if w == waiter {
+			q.waiting[idx] = append(q.waiting[idx][:i], q.waiting[idx][i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *PriorityQueue) release(idx int, latency time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inFlight[idx]--
+	q.total--
+	if q.avgLatency == 0 {
+		q.avgLatency = latency
+	} else {
+		q.avgLatency = (q.avgLatency*7 + latency) / 8
+	}
+	q.dispatch()
+}
+
+// Transport returns an http.RoundTripper that queues every request by its context priority before passing it to
+// base. A slot is held until the response body is closed, so slow downloads count against their priority's share.
+func (q *PriorityQueue) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &priorityTransport{queue: q, base: base}
+}
+
+type priorityTransport struct {
+	queue *PriorityQueue
+	base  http.RoundTripper
+}
+
+func (t *priorityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := PriorityFromContext(req.Context())
+	release, err := t.queue.Acquire(req.Context(), p)
+	if err != nil {
+		if errors.Is(err, ErrRequestShed) {
+			slog.Debug("Outbound request shed", "host", requestHost(req), "method", req.Method, "priority", p, "error", err)
+		}
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody calls release when the response body is closed, e.g. to free a PriorityQueue slot.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,62 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestPriorityQueue tests that freed slots go to the highest waiting priority and that low-priority requests which
+// cannot make their deadline are shed
+func TestPriorityQueue(t *testing.T) {
+	queue := NewPriorityQueue(PriorityConfig{MaxConcurrent: 1})
+
+	release, err := queue.Acquire(context.Background(), PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan Priority, 2)
+	for _, p := range []Priority{PriorityLow, PriorityHigh} {
+		p := p
+		go func() {
+			next, err := queue.Acquire(context.Background(), p)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- p
+			next()
+		}()
+	}
+
+	for queued := 0; queued < 2; {
+		time.Sleep(time.Millisecond)
+		queued = 0
+		for _, stats := range queue.Stats() {
+			queued += stats.Queued
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	release()
+	if first, second := <-order, <-order; first != PriorityHigh || second != PriorityLow {
+		t.Errorf("Expected high then low, got %v then %v", first, second)
+	}
+
+	release, err = queue.Acquire(context.Background(), PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := queue.Acquire(ctx, PriorityLow); !errors.Is(err, ErrRequestShed) {
+		t.Errorf("Expected low-priority request to be shed, got %v", err)
+	}
+	if stats := queue.Stats(); stats[2].Shed != 1 {
+		t.Errorf("Expected one shed low-priority request, got %+v", stats)
+	}
+}
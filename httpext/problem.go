@@ -0,0 +1,84 @@
+package httpext
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mhpenta/app"
+	"log/slog"
+	"net/http"
+)
+
+// ProblemContentType is the media type of the bodies written by WriteError.
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 problem details body written by WriteError. The location and stack fields are only set in
+// app.DebugMode.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+
+	File   string                 `json:"file,omitempty"`
+	Line   int                    `json:"line,omitempty"`
+	Func   string                 `json:"func,omitempty"`
+	Stack  []string               `json:"stack,omitempty"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// WriteError answers r with err as an RFC 7807 problem details body. The status is taken from StatusForError and
+// the code from app.CodeOf.
+//
+// Client errors (4xx) carry the error message as their detail. Server errors are logged and passed to app.Report,
+// and their message is hidden in app.ReleaseMode, so internals are not exposed. In app.DebugMode the body also
+// carries the file, line, function, stack and fields of the MetaError in err.
+//
+// Example usage:
+//
+//	filing, err := store.Filing(r.Context(), id)
+//	if err != nil {
+//		httpext.WriteError(w, r, err)
+//		return
+//	}
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := StatusForError(err)
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: r.URL.Path,
+		Code:     app.CodeOf(err),
+	}
+	if id := r.Header.Get(CorrelationIDHeader); id != "" {
+		problem.RequestID = id
+	} else if id, ok := app.RequestIDFromContext(r.Context()); ok {
+		problem.RequestID = id
+	}
+
+	if status >= http.StatusInternalServerError {
+		slog.Error("HTTP handler failed", "method", r.Method, "path", r.URL.Path, "status", status, "err", err)
+		app.Report(r.Context(), err)
+	}
+	if status < http.StatusInternalServerError || app.Mode != app.ReleaseMode {
+		problem.Detail = err.Error()
+	}
+	if app.Mode == app.DebugMode {
+		if metaErr, ok := app.AsMetaError(err); ok {
+			problem.File, problem.Line, problem.Func = metaErr.File, metaErr.Line, metaErr.Func
+			for _, frame := range metaErr.Frames() {
+				problem.Stack = append(problem.Stack, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+			}
+			problem.Fields = metaErr.Fields
+		}
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.Error("Error writing problem response", "err", err)
+	}
+}
@@ -0,0 +1,58 @@
+package httpext
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mhpenta/app"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWriteError tests problem details bodies for client and server errors across application modes
+func TestWriteError(t *testing.T) {
+	previous := app.Mode
+	t.Cleanup(func() {
+		app.Mode = previous
+	})
+
+	serve := func(err error) (*httptest.ResponseRecorder, Problem) {
+		req := httptest.NewRequest(http.MethodGet, "/filings/42", nil)
+		req.Header.Set(CorrelationIDHeader, "req-1")
+		rec := httptest.NewRecorder()
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return err
+		}).ServeHTTP(rec, req)
+
+		var problem Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Expected a JSON body, got %q: %v", rec.Body.String(), err)
+		}
+		return rec, problem
+	}
+
+	app.Mode = app.ReleaseMode
+	notFound := app.NewMetaError(errors.New("filing 42 not found")).WithHTTPStatus(http.StatusNotFound).WithCode("FILING_NOT_FOUND")
+	rec, problem := serve(notFound)
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != ProblemContentType {
+		t.Errorf("Expected a 404 problem, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	want := Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "filing 42 not found",
+		Instance: "/filings/42", Code: "FILING_NOT_FOUND", RequestID: "req-1"}
+	if problem.Type != want.Type || problem.Title != want.Title || problem.Status != want.Status || problem.Detail != want.Detail ||
+		problem.Instance != want.Instance || problem.Code != want.Code || problem.RequestID != want.RequestID || problem.File != "" {
+		t.Errorf("Expected %+v, got %+v", want, problem)
+	}
+
+	internal := app.NewMetaError(errors.New("pq: password authentication failed")).WithField("table", "filings")
+	rec, problem = serve(internal)
+	if rec.Code != http.StatusInternalServerError || problem.Detail != "" || problem.Stack != nil {
+		t.Errorf("Expected internals hidden in release mode, got %d %+v", rec.Code, problem)
+	}
+
+	app.Mode = app.DebugMode
+	_, problem = serve(internal)
+	if problem.Detail == "" || problem.File == "" || problem.Line == 0 || len(problem.Stack) == 0 || problem.Fields["table"] != "filings" {
+		t.Errorf("Expected location, stack and fields in debug mode, got %+v", problem)
+	}
+}
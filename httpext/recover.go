@@ -0,0 +1,74 @@
+package httpext
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/mhpenta/app"
+	"log/slog"
+	"net/http"
+)
+
+// CorrelationIDHeader is the header used to read and return the correlation ID of a request.
+const CorrelationIDHeader = "X-Request-ID"
+
+// PanicReporter, when set, is called with every panic recovered by RecoverMiddleware, after it has been logged.
+var PanicReporter func(r *http.Request, err *app.MetaError, correlationID string)
+
+// RecoverMiddleware recovers panics raised by next, converts them to a MetaError with app.FromPanic, logs and
+// reports them through PanicReporter and app.Report, and responds with a 500 carrying a correlation ID in the
+// CorrelationIDHeader header.
+//
+// The correlation ID is taken from the incoming request header, then from app.RequestIDFromContext, otherwise one is
+// generated. It is also added to the MetaError as its "requestId" field, along with any trace ID and user in the
+// request context.
+// http.ErrAbortHandler is re-panicked so net/http can abort the response as intended.
+func RecoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			metaErr := app.FromPanic(rec)
+			id := correlationID(r)
+			ctx := app.WithRequestID(r.Context(), id)
+			metaErr = app.NewMetaErrorCtx(ctx, metaErr)
+
+			slog.Error("Recovered panic in HTTP handler",
+				"correlationId", id,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"err", metaErr,
+				"stack", metaErr.StackTrace())
+
+			if PanicReporter != nil {
+				PanicReporter(r, metaErr, id)
+			}
+			app.Report(ctx, metaErr)
+
+			w.Header().Set(CorrelationIDHeader, id)
+			http.Error(w, InternalServerError, http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func correlationID(r *http.Request) string {
+	if id := r.Header.Get(CorrelationIDHeader); id != "" {
+		return id
+	}
+	if id, ok := app.RequestIDFromContext(r.Context()); ok {
+		return id
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
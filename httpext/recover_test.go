@@ -0,0 +1,55 @@
+package httpext
+
+import (
+	"github.com/mhpenta/app"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRecoverMiddleware tests answering panics with a 500 and a correlation ID, and re-panicking aborted handlers
+func TestRecoverMiddleware(t *testing.T) {
+	var reported *app.MetaError
+	var reportedID string
+	PanicReporter = func(r *http.Request, err *app.MetaError, correlationID string) {
+		reported, reportedID = err, correlationID
+	}
+	t.Cleanup(func() {
+		PanicReporter = nil
+	})
+
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map write")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/filings", nil)
+	req.Header.Set(CorrelationIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get(CorrelationIDHeader) != "req-1" {
+		t.Errorf("Expected a 500 echoing the correlation ID, got %d %q", rec.Code, rec.Header().Get(CorrelationIDHeader))
+	}
+	if reported == nil || reportedID != "req-1" {
+		t.Fatalf("Expected the panic reported with its correlation ID, got %v %q", reported, reportedID)
+	}
+	if reported.Fields["requestId"] != "req-1" {
+		t.Errorf("Expected the correlation ID as the requestId field, got %v", reported.Fields)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/filings", nil))
+	if id := rec.Header().Get(CorrelationIDHeader); len(id) != 16 || id != reportedID {
+		t.Errorf("Expected a generated correlation ID, got %q", id)
+	}
+
+	aborting := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("Expected http.ErrAbortHandler re-panicked, got %v", rec)
+		}
+	}()
+	aborting.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/filings", nil))
+	t.Error("Expected ServeHTTP to panic")
+}
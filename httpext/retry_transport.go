@@ -0,0 +1,171 @@
+package httpext
+
+import (
+	"context"
+	"github.com/mhpenta/app"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryAttemptsHeader is set on responses that needed more than one attempt through RetryTransport.
+const RetryAttemptsHeader = "X-Httpext-Retry-Attempts"
+
+// RetryTransportConfig holds configuration for RetryTransport
+type RetryTransportConfig struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int
+	// Backoff returns the delay before the given retry, starting at 1
+	Backoff func(retry int) time.Duration
+	// Retryable reports whether a transport error is worth retrying. Defaults to IsTransientNetworkOrDNSIssueErr.
+	Retryable func(error) bool
+}
+
+// DefaultRetryTransportConfig provides sensible default values for RetryTransportConfig
+var DefaultRetryTransportConfig = RetryTransportConfig{
+	MaxAttempts: 3,
+	Backoff: func(retry int) time.Duration {
+		return time.Duration(500*(1<<(retry-1))) * time.Millisecond
+	},
+}
+
+// RetryInfo describes the retries a request went through in RetryTransport.
+type RetryInfo struct {
+	// Attempts is the number of attempts made, including the successful one
+	Attempts int
+	// TotalDelay is the time spent waiting between attempts
+	TotalDelay time.Duration
+	// LastErr is the last transient error seen before the final attempt, nil if the first attempt succeeded
+	LastErr error
+}
+
+type retryInfoKey struct{}
+
+// RetryGate, when set, is consulted each time RetryTransport or PaginateWithConfig starts retrying a request. A
+// positive maxAttempts lowers the configured number of attempts; a non-nil err is returned without sending the
+// request. The retry package sets it so that its kill switch also covers the retries made here.
+var RetryGate func() (maxAttempts int, err error)
+
+// gateAttempts applies RetryGate to the configured number of attempts.
+func gateAttempts(configured int) (int, error) {
+	if RetryGate == nil {
+		return configured, nil
+	}
+	maxAttempts, err := RetryGate()
+	if err != nil {
+		return 0, err
+	}
+	if maxAttempts > 0 && maxAttempts < configured {
+		return maxAttempts, nil
+	}
+	return configured, nil
+}
+
+// RetryInfoFromResponse returns the retry metadata of a response that went through RetryTransport, so latency
+// anomalies can be explained at the call site:
+//
+//	resp, err := client.Get(u)
+//	if info, ok := httpext.RetryInfoFromResponse(resp); ok && info.Attempts > 1 {
+//		slog.Info("Request needed retries", "attempts", info.Attempts, "delay", info.TotalDelay, "lastErr", info.LastErr)
+//	}
+func RetryInfoFromResponse(resp *http.Response) (RetryInfo, bool) {
+	if resp == nil || resp.Request == nil {
+		return RetryInfo{}, false
+	}
+	return RetryInfoFromContext(resp.Request.Context())
+}
+
+// RetryInfoFromContext returns the retry metadata stored in the context of a request sent through RetryTransport.
+func RetryInfoFromContext(ctx context.Context) (RetryInfo, bool) {
+	info, ok := ctx.Value(retryInfoKey{}).(*RetryInfo)
+	if !ok || info == nil {
+		return RetryInfo{}, false
+	}
+	return *info, true
+}
+
+// RetryTransport is an http.RoundTripper that retries transient transport errors for requests that are safe to
+// repeat: idempotent methods, or any method carrying an Idempotency-Key header, whose body can be rewound.
+type RetryTransport struct {
+	Base   http.RoundTripper
+	Config RetryTransportConfig
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	config := t.Config
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultRetryTransportConfig.MaxAttempts
+	}
+	if config.Backoff == nil {
+		config.Backoff = DefaultRetryTransportConfig.Backoff
+	}
+	if config.Retryable == nil {
+		config.Retryable = IsTransientNetworkOrDNSIssueErr
+	}
+
+	maxAttempts, err := gateAttempts(config.MaxAttempts)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	config.MaxAttempts = maxAttempts
+
+	info := &RetryInfo{}
+	ctx := context.WithValue(req.Context(), retryInfoKey{}, info)
+	req = req.WithContext(ctx)
+
+	replayable := isReplayable(req)
+
+	for {
+		info.Attempts++
+		resp, err := base.RoundTrip(req)
+		if err == nil {
+			if info.Attempts > 1 {
+				resp.Header.Set(RetryAttemptsHeader, strconv.Itoa(info.Attempts))
+			}
+			return resp, nil
+		}
+
+		if !replayable || info.Attempts >= config.MaxAttempts || !config.Retryable(err) {
+			return nil, err
+		}
+		info.LastErr = err
+
+		delay := config.Backoff(info.Attempts)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-app.After(delay):
+		}
+		info.TotalDelay += delay
+
+		if req.Body != nil && req.Body != http.NoBody {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+func isReplayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
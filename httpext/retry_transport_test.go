@@ -0,0 +1,98 @@
+package httpext
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyTransport fails the first failures round trips with a dial error after reading the request body, then
+// forwards to base
+type flakyTransport struct {
+	base     http.RoundTripper
+	failures int
+	calls    int
+	bodies   []string
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.calls++
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		req.Body.Close()
+		f.bodies = append(f.bodies, string(body))
+		req.Body = io.NopCloser(strings.NewReader(string(body)))
+	}
+	if f.calls <= f.failures {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}
+	}
+	return f.base.RoundTrip(req)
+}
+
+// TestRetryTransport tests retry metadata, the attempts header and replaying request bodies through GetBody
+func TestRetryTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+
+	newClient := func(failures int) (*http.Client, *flakyTransport) {
+		flaky := &flakyTransport{base: srv.Client().Transport, failures: failures}
+		return &http.Client{Transport: &RetryTransport{
+			Base:   flaky,
+			Config: RetryTransportConfig{MaxAttempts: 3, Backoff: func(int) time.Duration { return time.Millisecond }},
+		}}, flaky
+	}
+
+	client, _ := newClient(0)
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if info, ok := RetryInfoFromResponse(resp); !ok || info.Attempts != 1 || info.LastErr != nil || resp.Header.Get(RetryAttemptsHeader) != "" {
+		t.Errorf("Expected one attempt without the attempts header, got %+v %q", info, resp.Header.Get(RetryAttemptsHeader))
+	}
+
+	client, flaky := newClient(2)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"cik":"0000320193"}`))
+	req.Header.Set("Idempotency-Key", "k-1")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"cik":"0000320193"}` || len(flaky.bodies) != 3 || flaky.bodies[2] != string(body) {
+		t.Errorf("Expected the body replayed on every attempt, got %q after %q", body, flaky.bodies)
+	}
+	info, ok := RetryInfoFromResponse(resp)
+	if !ok || info.Attempts != 3 || info.TotalDelay != 2*time.Millisecond || !IsDialError(info.LastErr) {
+		t.Errorf("Expected three attempts after dial errors, got %+v", info)
+	}
+	if resp.Header.Get(RetryAttemptsHeader) != "3" {
+		t.Errorf("Expected the attempts header, got %q", resp.Header.Get(RetryAttemptsHeader))
+	}
+
+	client, flaky = newClient(1)
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{}`))
+	if _, err := client.Do(req); !IsDialError(err) || flaky.calls != 1 {
+		t.Errorf("Expected a POST without Idempotency-Key not retried, got %d calls (%v)", flaky.calls, err)
+	}
+
+	client, flaky = newClient(1)
+	req, _ = http.NewRequest(http.MethodPut, srv.URL, io.NopCloser(strings.NewReader(`{}`)))
+	if _, err := client.Do(req); !IsDialError(err) || flaky.calls != 1 {
+		t.Errorf("Expected a body without GetBody not retried, got %d calls (%v)", flaky.calls, err)
+	}
+
+	client, flaky = newClient(5)
+	if _, err := client.Get(srv.URL); !IsDialError(err) || flaky.calls != 3 {
+		t.Errorf("Expected MaxAttempts to bound the retries, got %d calls (%v)", flaky.calls, err)
+	}
+}
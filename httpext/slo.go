@@ -0,0 +1,274 @@
+package httpext
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrOutOfSLO is wrapped by the error returned from SLOTracker.Healthy when an endpoint misses its objective.
+var ErrOutOfSLO = errors.New("endpoint out of SLO")
+
+// SLOConfig holds configuration for an SLOTracker
+type SLOConfig struct {
+	// Percentile is the latency percentile evaluated, between 0 and 1
+	Percentile float64
+	// Threshold is the maximum latency allowed at Percentile
+	Threshold time.Duration
+	// MaxErrorRate is the maximum fraction of failed requests, between 0 and 1. Zero takes the default; set a
+	// negative value for an objective that allows no failures.
+	MaxErrorRate float64
+	// Window is the number of most recent requests kept per endpoint
+	Window int
+	// MinSamples is the number of samples an endpoint needs before it is evaluated
+	MinSamples int
+	// EvalInterval is how often endpoints are evaluated, checked as requests are recorded
+	EvalInterval time.Duration
+	// Endpoint maps a request to its endpoint label. Defaults to the request host; use a function returning a
+	// route template rather than the raw path to keep the number of endpoints bounded.
+	Endpoint func(*http.Request) string
+}
+
+// DefaultSLOConfig provides sensible default values for SLOConfig
+var DefaultSLOConfig = SLOConfig{
+	Percentile:   0.95,
+	Threshold:    2 * time.Second,
+	MaxErrorRate: 0.05,
+	Window:       500,
+	MinSamples:   20,
+	EvalInterval: time.Minute,
+}
+
+// SLOStatus is the result of evaluating one endpoint.
+type SLOStatus struct {
+	Endpoint    string        `json:"endpoint"`
+	Samples     int           `json:"samples"`
+	Latency     time.Duration `json:"latency"`
+	ErrorRate   float64       `json:"errorRate"`
+	InSLO       bool          `json:"inSlo"`
+	EvaluatedAt time.Time     `json:"evaluatedAt"`
+}
+
+// SLOTracker records per-endpoint latency and error rate and periodically evaluates them against an objective,
+// logging a warning when an endpoint goes out of SLO and exposing the state through Status and Healthy.
+type SLOTracker struct {
+	config SLOConfig
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointSamples
+	status    map[string]SLOStatus
+	lastEval  time.Time
+}
+
+type endpointSamples struct {
+	latencies []time.Duration
+	failed    []bool
+	next      int
+	full      bool
+}
+
+// NewSLOTracker creates an SLOTracker. Zero fields in config take their value from DefaultSLOConfig, and a negative
+// MaxErrorRate is stored as zero.
+func NewSLOTracker(config SLOConfig) *SLOTracker {
+	if config.Percentile <= 0 || config.Percentile > 1 {
+		config.Percentile = DefaultSLOConfig.Percentile
+	}
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultSLOConfig.Threshold
+	}
+	if config.MaxErrorRate == 0 {
+		config.MaxErrorRate = DefaultSLOConfig.MaxErrorRate
+	}
+	if config.MaxErrorRate < 0 {
+		config.MaxErrorRate = 0
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultSLOConfig.Window
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = DefaultSLOConfig.MinSamples
+	}
+	if config.EvalInterval <= 0 {
+		config.EvalInterval = DefaultSLOConfig.EvalInterval
+	}
+
+	return &SLOTracker{
+		config:    config,
+		endpoints: make(map[string]*endpointSamples),
+		status:    make(map[string]SLOStatus),
+		lastEval:  app.Now(),
+	}
+}
+
+// Record adds a request outcome for endpoint and evaluates all endpoints if EvalInterval has passed.
+func (t *SLOTracker) Record(endpoint string, latency time.Duration, failed bool) {
+	t.mu.Lock()
+	samples, ok := t.endpoints[endpoint]
+	if !ok {
+		samples = &endpointSamples{
+			latencies: make([]time.Duration, t.config.Window),
+			failed:    make([]bool, t.config.Window),
+		}
+		t.endpoints[endpoint] = samples
+	}
+
+	samples.latencies[samples.next] = latency
+	samples.failed[samples.next] = failed
+	samples.next++
+	if samples.next == len(samples.latencies) {
+		samples.next = 0
+		samples.full = true
+	}
+
+	due := app.Since(t.lastEval) >= t.config.EvalInterval
+	t.mu.Unlock()
+
+	if due {
+		t.Evaluate()
+	}
+}
+
+// Evaluate computes the SLO status of every endpoint with enough samples, logs endpoints that moved in or out of
+// SLO, and returns the statuses sorted by endpoint.
+func (t *SLOTracker) Evaluate() []SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := app.Now()
+	t.lastEval = now
+
+	for endpoint, samples := range t.endpoints {
+		n := samples.next
+		if samples.full {
+			n = len(samples.latencies)
+		}
+		if n < t.config.MinSamples {
+			continue
+		}
+
+		latencies := make([]time.Duration, n)
+		copy(latencies, samples.latencies[:n])
+		sort.Slice(latencies, func(i, j int) bool {
+			return latencies[i] < latencies[j]
+		})
+
+		failures := 0
+		for _, failed := range samples.failed[:n] {
+			if failed {
+				failures++
+			}
+		}
+
+		idx := int(float64(n)*t.config.Percentile+0.5) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= n {
+			idx = n - 1
+		}
+
+		status := SLOStatus{
+			Endpoint:    endpoint,
+			Samples:     n,
+			Latency:     latencies[idx],
+			ErrorRate:   float64(failures) / float64(n),
+			EvaluatedAt: now,
+		}
+		status.InSLO = status.Latency <= t.config.Threshold && status.ErrorRate <= t.config.MaxErrorRate
+
+		previous, seen := t.status[endpoint]
+		switch {
+		case !status.InSLO && (!seen || previous.InSLO):
+			slog.Warn("Endpoint out of SLO",
+				"endpoint", endpoint,
+				"percentile", t.config.Percentile,
+				"latency", status.Latency,
+				"threshold", t.config.Threshold,
+				"errorRate", status.ErrorRate,
+				"maxErrorRate", t.config.MaxErrorRate,
+				"samples", n)
+		case status.InSLO && seen && !previous.InSLO:
+			slog.Info("Endpoint back within SLO", "endpoint", endpoint, "latency", status.Latency, "errorRate", status.ErrorRate)
+		}
+		t.status[endpoint] = status
+	}
+
+	return t.sortedStatus()
+}
+
+// Status returns the result of the last evaluation, sorted by endpoint.
+func (t *SLOTracker) Status() []SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sortedStatus()
+}
+
+// Healthy returns nil when every evaluated endpoint is within SLO, otherwise an error wrapping ErrOutOfSLO that
+// names the failing endpoints. It is meant to be plugged into health checks.
+func (t *SLOTracker) Healthy() error {
+	var failing []string
+	for _, status := range t.Status() {
+		if !status.InSLO {
+			failing = append(failing, status.Endpoint)
+		}
+	}
+	if len(failing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrOutOfSLO, strings.Join(failing, ", "))
+}
+
+func (t *SLOTracker) sortedStatus() []SLOStatus {
+	out := make([]SLOStatus, 0, len(t.status))
+	for _, status := range t.status {
+		out = append(out, status)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Endpoint < out[j].Endpoint
+	})
+	return out
+}
+
+// Transport returns an http.RoundTripper that records every request sent through base. Transport errors and 5xx
+// responses count as failures. The latency of a response runs until its body is closed, so slow downloads count
+// against the objective, and responses whose body is never closed are not recorded.
+func (t *SLOTracker) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &sloTransport{tracker: t, base: base}
+}
+
+type sloTransport struct {
+	tracker *SLOTracker
+	base    http.RoundTripper
+}
+
+func (s *sloTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := requestHost(req)
+	if s.tracker.config.Endpoint != nil {
+		endpoint = s.tracker.config.Endpoint(req)
+	}
+
+	start := app.Now()
+	resp, err := s.base.RoundTrip(req)
+	if err != nil {
+		s.tracker.Record(endpoint, app.Since(start), true)
+		return nil, err
+	}
+
+	failed := resp.StatusCode >= http.StatusInternalServerError
+	var once sync.Once
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() {
+		once.Do(func() {
+			s.tracker.Record(endpoint, app.Since(start), failed)
+		})
+	}}
+	return resp, nil
+}
@@ -0,0 +1,123 @@
+package httpext
+
+import (
+	"errors"
+	"github.com/mhpenta/app"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSLOTracker tests periodic evaluation on the fake clock, a percentile breach and the return within SLO
+func TestSLOTracker(t *testing.T) {
+	clock := app.TestMode(t)
+	tracker := NewSLOTracker(SLOConfig{Percentile: 0.9, Threshold: 100 * time.Millisecond, Window: 10, MinSamples: 5, EvalInterval: time.Minute})
+
+	for i := 0; i < 10; i++ {
+		tracker.Record("edgar", 20*time.Millisecond, false)
+	}
+	if status := tracker.Status(); len(status) != 0 {
+		t.Fatalf("Expected no evaluation before EvalInterval, got %+v", status)
+	}
+
+	clock.Advance(time.Minute)
+	tracker.Record("edgar", 20*time.Millisecond, false)
+	status := tracker.Status()
+	if len(status) != 1 || !status[0].InSLO || status[0].Samples != 10 || !status[0].EvaluatedAt.Equal(clock.Now()) {
+		t.Fatalf("Expected edgar evaluated within SLO, got %+v", status)
+	}
+	if err := tracker.Healthy(); err != nil {
+		t.Errorf("Expected healthy, got %v", err)
+	}
+
+	// Two slow requests out of ten put the 90th percentile over the threshold
+	tracker.Record("edgar", time.Second, false)
+	tracker.Record("edgar", time.Second, false)
+	clock.Advance(time.Minute)
+	tracker.Record("edgar", 20*time.Millisecond, false)
+	status = tracker.Status()
+	if status[0].InSLO || status[0].Latency != time.Second {
+		t.Errorf("Expected a breach at the 90th percentile, got %+v", status[0])
+	}
+	if err := tracker.Healthy(); !errors.Is(err, ErrOutOfSLO) || err.Error() != "endpoint out of SLO: edgar" {
+		t.Errorf("Expected ErrOutOfSLO naming edgar, got %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		tracker.Record("edgar", 20*time.Millisecond, false)
+	}
+	clock.Advance(time.Minute)
+	if status := tracker.Evaluate(); !status[0].InSLO || tracker.Healthy() != nil {
+		t.Errorf("Expected edgar back within SLO, got %+v", status[0])
+	}
+}
+
+// TestSLOTrackerTransport tests that the transport counts 5xx responses as failures
+func TestSLOTrackerTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	tracker := NewSLOTracker(SLOConfig{MaxErrorRate: 0.2, MinSamples: 4, Endpoint: func(r *http.Request) string {
+		return r.URL.Path
+	}})
+	client := &http.Client{Transport: tracker.Transport(nil)}
+	for _, path := range []string{"/ok", "/ok", "/ok", "/ok", "/fail", "/fail", "/fail", "/fail"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	status := tracker.Evaluate()
+	if len(status) != 2 || status[0].Endpoint != "/fail" || status[0].InSLO || status[0].ErrorRate != 1 || !status[1].InSLO {
+		t.Errorf("Expected /fail out of SLO and /ok within, got %+v", status)
+	}
+}
+
+// TestSLOTrackerTransportTimesBody tests that latency runs until the response body is closed
+func TestSLOTrackerTransportTimesBody(t *testing.T) {
+	clock := app.TestMode(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	tracker := NewSLOTracker(SLOConfig{Percentile: 0.5, Threshold: time.Second, MinSamples: 1})
+	client := &http.Client{Transport: tracker.Transport(nil)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := tracker.Evaluate(); len(status) != 0 {
+		t.Fatalf("Expected nothing recorded before the body is closed, got %+v", status)
+	}
+
+	clock.Advance(2 * time.Second)
+	resp.Body.Close()
+	resp.Body.Close()
+	status := tracker.Evaluate()
+	if len(status) != 1 || status[0].Samples != 1 || status[0].Latency != 2*time.Second || status[0].InSLO {
+		t.Errorf("Expected one sample timed until the body was closed, got %+v", status)
+	}
+}
+
+// TestSLOTrackerZeroErrorRate tests that a negative MaxErrorRate sets an objective allowing no failures
+func TestSLOTrackerZeroErrorRate(t *testing.T) {
+	app.TestMode(t)
+	if tracker := NewSLOTracker(SLOConfig{}); tracker.config.MaxErrorRate != DefaultSLOConfig.MaxErrorRate {
+		t.Errorf("Expected the default error rate for zero, got %v", tracker.config.MaxErrorRate)
+	}
+
+	tracker := NewSLOTracker(SLOConfig{MaxErrorRate: -1, Window: 100, MinSamples: 100})
+	for i := 0; i < 99; i++ {
+		tracker.Record("edgar", 20*time.Millisecond, false)
+	}
+	tracker.Record("edgar", 20*time.Millisecond, true)
+	if status := tracker.Evaluate(); len(status) != 1 || status[0].InSLO {
+		t.Errorf("Expected a single failure to break a zero error objective, got %+v", status)
+	}
+}
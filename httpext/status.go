@@ -0,0 +1,94 @@
+package httpext
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxStatusErrorBody bounds the part of an error response kept in StatusError.Body.
+const maxStatusErrorBody = 1024
+
+// StatusError is a non-2xx response turned into an error, so callers and the retry package can branch on its status
+// code instead of matching message text. It matches ErrUnexpectedStatus with errors.Is.
+//
+// Example usage:
+//
+//	if resp.StatusCode >= 400 {
+//		return httpext.NewStatusError(resp)
+//	}
+type StatusError struct {
+	// Code is the response status code
+	Code int
+	// Body is the start of the response body, at most 1 KiB, for error pages and API error messages
+	Body string
+	// URL is the request URL
+	URL string
+	// Method is the request method
+	Method string
+	// RetryAfter is the wait announced by the Retry-After header, zero when the response has none
+	RetryAfter time.Duration
+}
+
+// NewStatusError reads the start of resp.Body into a *StatusError and closes the body.
+func NewStatusError(resp *http.Response) *StatusError {
+	e := &StatusError{Code: resp.StatusCode}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		if resp.Request.URL != nil {
+			e.URL = resp.Request.URL.Redacted()
+		}
+	}
+	e.RetryAfter, _ = retryAfter(resp.Header, app.Now())
+
+	if resp.Body != nil {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxStatusErrorBody))
+		resp.Body.Close()
+		e.Body = strings.TrimSpace(strings.ToValidUTF8(string(snippet), string(utf8.RuneError)))
+	}
+	return e
+}
+
+// Error renders the error as "unexpected response status: 503 Service Unavailable (GET https://...): <body>".
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s: %d %s", ErrUnexpectedStatus, e.Code, http.StatusText(e.Code))
+	if e.Method != "" || e.URL != "" {
+		msg += fmt.Sprintf(" (%s)", strings.TrimSpace(e.Method+" "+e.URL))
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Is reports whether target is ErrUnexpectedStatus.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrUnexpectedStatus
+}
+
+// IsRetryableStatus reports whether err is a *StatusError for a status worth retrying: 408 Request Timeout, 429 Too
+// Many Requests, 502 Bad Gateway, 503 Service Unavailable or 504 Gateway Timeout.
+func IsRetryableStatus(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.Code == http.StatusRequestTimeout || isRetryableStatus(statusErr.Code)
+}
+
+// IsRateLimited reports whether err is a *StatusError for 429 Too Many Requests.
+func IsRateLimited(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Code == http.StatusTooManyRequests
+}
+
+// IsServerError reports whether err is a *StatusError for a 5xx status.
+func IsServerError(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Code >= 500 && statusErr.Code < 600
+}
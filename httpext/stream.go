@@ -0,0 +1,124 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ErrBodyTooLarge is returned when a response body exceeds the size cap. It is not transient, so retry loops stop
+// on it.
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// DefaultMaxStreamSize is the size cap used by StreamToFile. Zero or less means no cap.
+var DefaultMaxStreamSize int64 = 1 << 30
+
+// StreamToFile writes resp.Body to path, capped at DefaultMaxStreamSize, see StreamToFileWithLimit.
+func StreamToFile(ctx context.Context, resp *http.Response, path string) (int64, error) {
+	return StreamToFileWithLimit(ctx, resp, path, DefaultMaxStreamSize)
+}
+
+// StreamToFileWithLimit writes resp.Body to a temporary file next to path, syncs it and renames it into place, so
+// path only ever holds a complete download. It closes the body and returns the number of bytes written.
+//
+// The file gets the permissions of the file it replaces, or 0644 for a new file. On failure the temporary file is
+// removed and path is left untouched. A body ending before Content-Length returns
+// an error wrapping ErrTruncatedBody, which IsTransientNetworkOrDNSIssueErr treats as retryable; a body larger than
+// maxBytes (when positive) returns an error wrapping ErrBodyTooLarge.
+func StreamToFileWithLimit(ctx context.Context, resp *http.Response, path string, maxBytes int64) (int64, error) {
+	if resp == nil || resp.Body == nil {
+		return 0, errors.New("response has no body")
+	}
+	defer resp.Body.Close()
+
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return 0, fmt.Errorf("%w: Content-Length %d exceeds %d bytes", ErrBodyTooLarge, resp.ContentLength, maxBytes)
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := streamBody(ctx, tmp, resp, maxBytes)
+	if err == nil {
+		// CreateTemp creates the file with mode 0600; give the download the mode of the file it replaces, or 0644
+		err = tmp.Chmod(targetMode(path))
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return written, err
+	}
+
+	syncDir(dir)
+	return written, nil
+}
+
+// targetMode returns the permission bits of the existing file at path, or 0644 when there is none.
+func targetMode(path string) os.FileMode {
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		return info.Mode().Perm()
+	}
+	return 0o644
+}
+
+func streamBody(ctx context.Context, w io.Writer, resp *http.Response, maxBytes int64) (int64, error) {
+	var r io.Reader = &contextReader{ctx: ctx, r: resp.Body}
+	if maxBytes > 0 {
+		r = io.LimitReader(r, maxBytes+1)
+	}
+
+	written, err := io.Copy(w, r)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return written, fmt.Errorf("%w: received %d of %d bytes: %w", ErrTruncatedBody, written, resp.ContentLength, err)
+		}
+		return written, err
+	}
+
+	if maxBytes > 0 && written > maxBytes {
+		return written, fmt.Errorf("%w: exceeds %d bytes", ErrBodyTooLarge, maxBytes)
+	}
+	if resp.ContentLength >= 0 && written < resp.ContentLength {
+		return written, fmt.Errorf("%w: received %d of %d bytes", ErrTruncatedBody, written, resp.ContentLength)
+	}
+	return written, nil
+}
+
+// contextReader stops reading once ctx is done, so long downloads honour cancellation even when the request was
+// not created with ctx.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// syncDir makes the rename durable where the platform supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,83 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStreamToFile tests atomic downloads and that failures leave the destination untouched
+func TestStreamToFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filing.xml")
+	response := func(body io.ReadCloser, contentLength int64) *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Body: body, ContentLength: contentLength}
+	}
+
+	body := &errReader{body: strings.NewReader("<filing/>"), err: io.EOF}
+	written, err := StreamToFile(context.Background(), response(body, 9), path)
+	if err != nil || written != 9 || !body.closed {
+		t.Fatalf("Expected 9 bytes written and the body closed, got %d, %v", written, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "<filing/>" {
+		t.Errorf("Expected the body in %s, got %q", path, data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("Expected a new file readable by others (0644), got %v", info.Mode().Perm())
+	}
+
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StreamToFile(context.Background(), response(io.NopCloser(strings.NewReader("<filing/>")), 9), path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("Expected the mode of the replaced file kept, got %v, %v", info, err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		resp  *http.Response
+		limit int64
+		errs  []error
+	}{
+		{"content length too large", context.Background(), response(io.NopCloser(strings.NewReader("<filing/>")), 9), 4, []error{ErrBodyTooLarge}},
+		{"streamed too large", context.Background(), response(io.NopCloser(strings.NewReader("<filing/>")), -1), 4, []error{ErrBodyTooLarge}},
+		{"short body", context.Background(), response(io.NopCloser(strings.NewReader("<fil")), 9), 0, []error{ErrTruncatedBody}},
+		{"unexpected EOF", context.Background(), response(&errReader{body: strings.NewReader("<fil"), err: io.ErrUnexpectedEOF}, 9), 0, []error{ErrTruncatedBody, io.ErrUnexpectedEOF}},
+		{"cancelled", cancelled, response(io.NopCloser(strings.NewReader("<filing/>")), 9), 0, []error{context.Canceled}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := StreamToFileWithLimit(tt.ctx, tt.resp, path, tt.limit)
+			for _, want := range tt.errs {
+				if !errors.Is(err, want) {
+					t.Errorf("Expected %v, got %v", want, err)
+				}
+			}
+			if data, _ := os.ReadFile(path); string(data) != "<filing/>" {
+				t.Errorf("Expected %s left untouched, got %q", path, data)
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected temporary files removed, got %v, %v", entries, err)
+	}
+}
@@ -0,0 +1,137 @@
+package httpext
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrPinMismatch is wrapped by the error returned during the handshake when no certificate in the verified chain
+	// matches a configured pin.
+	ErrPinMismatch = errors.New("certificate pin mismatch")
+	// ErrInvalidPin is returned by TLSConfig for pins that are not base64 SHA-256 digests.
+	ErrInvalidPin = errors.New("invalid certificate pin")
+)
+
+// TLSOptions holds configuration for TLSConfig
+type TLSOptions struct {
+	// MinVersion is the minimum TLS version accepted. Defaults to TLS 1.2.
+	MinVersion uint16
+	// RootCAs is the pool used to verify servers. Defaults to the system pool.
+	RootCAs *x509.CertPool
+	// CAFiles are PEM files whose certificates are added to RootCAs, or to an empty pool when RootCAs is nil
+	CAFiles []string
+	// Pins are base64 SHA-256 digests of a SubjectPublicKeyInfo, optionally prefixed with "sha256/". The connection
+	// is accepted when any certificate in the verified chain matches any pin, so list the next key alongside the
+	// current one before rotating.
+	Pins []string
+	// ServerName overrides the name used to verify the server certificate
+	ServerName string
+}
+
+// TLSConfig builds a *tls.Config that enforces opts. Chain verification stays on; pinning is checked in addition to
+// it, and a failure is reported as a *app.MetaError wrapping ErrPinMismatch that lists the presented and expected
+// pins.
+//
+// Example usage:
+//
+//	tlsConfig, err := httpext.TLSConfig(httpext.TLSOptions{
+//		MinVersion: tls.VersionTLS13,
+//		Pins:       []string{currentPin, nextPin},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	transport := http.DefaultTransport.(*http.Transport).Clone()
+//	transport.TLSClientConfig = tlsConfig
+//	client := httpext.NewClient(httpext.ClientConfig{Transport: transport})
+func TLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: opts.MinVersion,
+		RootCAs:    opts.RootCAs,
+		ServerName: opts.ServerName,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	if len(opts.CAFiles) > 0 {
+		pool := x509.NewCertPool()
+		if opts.RootCAs != nil {
+			pool = opts.RootCAs.Clone()
+		}
+		for _, file := range opts.CAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("reading CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA file %s", file)
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if len(opts.Pins) > 0 {
+		pins := make(map[string]struct{}, len(opts.Pins))
+		for _, pin := range opts.Pins {
+			pin = strings.TrimPrefix(pin, "sha256/")
+			digest, err := base64.StdEncoding.DecodeString(pin)
+			if err != nil || len(digest) != sha256.Size {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPin, pin)
+			}
+			pins[pin] = struct{}{}
+		}
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPins(state, pins)
+		}
+	}
+
+	return config, nil
+}
+
+// SPKIPin returns the pin of cert in the format accepted by TLSOptions.Pins.
+func SPKIPin(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func verifyPins(state tls.ConnectionState, pins map[string]struct{}) error {
+	var presented []string
+	seen := make(map[string]bool)
+
+	for _, chain := range state.VerifiedChains {
+		for _, cert := range chain {
+			pin := strings.TrimPrefix(SPKIPin(cert), "sha256/")
+			if _, ok := pins[pin]; ok {
+				return nil
+			}
+			if !seen[pin] {
+				seen[pin] = true
+				presented = append(presented, pin)
+			}
+		}
+	}
+
+	expected := make([]string, 0, len(pins))
+	for pin := range pins {
+		expected = append(expected, pin)
+	}
+	sort.Strings(expected)
+
+	host := state.ServerName
+	if host == "" && len(state.PeerCertificates) > 0 {
+		host = state.PeerCertificates[0].Subject.String()
+	}
+
+	return app.NewMetaError(fmt.Errorf("%w: host %q presented [%s], expected one of [%s]",
+		ErrPinMismatch, host, strings.Join(presented, ", "), strings.Join(expected, ", ")))
+}
@@ -0,0 +1,116 @@
+package httpext
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"github.com/mhpenta/app"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTLSConfigPinning tests that connections are accepted only when the verified chain matches a pin
+func TestTLSConfigPinning(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	serverPin := SPKIPin(srv.Certificate())
+	otherDigest := sha256.Sum256([]byte("retired key"))
+	otherPin := base64.StdEncoding.EncodeToString(otherDigest[:])
+
+	get := func(opts TLSOptions) error {
+		config, err := TLSConfig(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		defer transport.CloseIdleConnections()
+
+		resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get(TLSOptions{RootCAs: roots, Pins: []string{otherPin, serverPin}}); err != nil {
+		t.Errorf("Expected any matching pin to be accepted, got %v", err)
+	}
+	if err := get(TLSOptions{RootCAs: roots, Pins: []string{strings.TrimPrefix(serverPin, "sha256/")}}); err != nil {
+		t.Errorf("Expected a pin without prefix to be accepted, got %v", err)
+	}
+
+	err := get(TLSOptions{RootCAs: roots, Pins: []string{otherPin}})
+	if !errors.Is(err, ErrPinMismatch) {
+		t.Fatalf("Expected ErrPinMismatch, got %v", err)
+	}
+	if _, ok := app.AsMetaError(err); !ok || !strings.Contains(err.Error(), strings.TrimPrefix(serverPin, "sha256/")) ||
+		!strings.Contains(err.Error(), otherPin) {
+		t.Errorf("Expected a MetaError listing presented and expected pins, got %v", err)
+	}
+	if IsTransientTLSError(err) {
+		t.Errorf("Expected a pin mismatch not to be transient")
+	}
+
+	if err := get(TLSOptions{Pins: []string{serverPin}}); !IsTLSError(err) || errors.Is(err, ErrPinMismatch) {
+		t.Errorf("Expected chain verification to fail before pinning, got %v", err)
+	}
+}
+
+// TestTLSConfigOptions tests defaults, CA files and pin validation
+func TestTLSConfigOptions(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, block, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := TLSConfig(TLSOptions{CAFiles: []string{caFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MinVersion != tls.VersionTLS12 || config.VerifyConnection != nil {
+		t.Errorf("Expected TLS 1.2 minimum and no pin check, got %x", config.MinVersion)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	defer transport.CloseIdleConnections()
+	resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Expected the CA file to be trusted, got %v", err)
+	}
+	resp.Body.Close()
+
+	notPEM := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TLSConfig(TLSOptions{CAFiles: []string{notPEM}}); err == nil {
+		t.Error("Expected an error for a CA file without certificates")
+	}
+	if _, err := TLSConfig(TLSOptions{CAFiles: []string{filepath.Join(t.TempDir(), "missing.pem")}}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing CA file reported, got %v", err)
+	}
+
+	for _, pin := range []string{"sha256/not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := TLSConfig(TLSOptions{Pins: []string{pin}}); !errors.Is(err, ErrInvalidPin) {
+			t.Errorf("Expected ErrInvalidPin for %q, got %v", pin, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// UnixScheme is the URL scheme of requests sent over a Unix domain socket by clients created with NewClient. The
+// socket path and the request path are separated by a colon:
+//
+//	unix:///var/run/docker.sock:/v1.43/containers/json?all=1
+//
+// The request path defaults to "/" when omitted. Use UnixSocketURL to build such URLs.
+const UnixScheme = "unix"
+
+// UnixSocketURL returns the URL requesting path over the Unix domain socket at socketPath.
+//
+// Example usage:
+//
+//	client := httpext.NewClient(httpext.DefaultClientConfig)
+//	resp, err := client.Get(httpext.UnixSocketURL("/var/run/docker.sock", "/v1.43/info"))
+func UnixSocketURL(socketPath string, path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return UnixScheme + "://" + socketPath + ":" + path
+}
+
+// IsUnixSocketUnavailableError determines if the given error comes from dialing a Unix domain socket that does not
+// exist, is not accepted by the process behind it or may not be opened. These point at a local service that is not
+// running or a wrong socket path, not at a network or DNS problem.
+func IsUnixSocketUnavailableError(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || !strings.HasPrefix(opErr.Net, "unix") {
+		return false
+	}
+	return errors.Is(opErr.Err, syscall.ENOENT) ||
+		errors.Is(opErr.Err, syscall.ECONNREFUSED) ||
+		errors.Is(opErr.Err, syscall.EACCES)
+}
+
+// splitUnixURL returns the socket path and request path of a unix:// URL.
+func splitUnixURL(u *url.URL) (string, string, error) {
+	socketPath, path, _ := strings.Cut(u.Path, ":")
+	if socketPath == "" || u.Host != "" {
+		return "", "", fmt.Errorf("%w: %q must have the form unix:///path/to.sock:/request/path", ErrInvalidURL, u.Redacted())
+	}
+	if path == "" {
+		path = "/"
+	}
+	return socketPath, path, nil
+}
+
+// requestHost returns the key identifying the destination of req for breakers and SLO tracking: the host, or the
+// socket path for unix:// requests.
+func requestHost(req *http.Request) string {
+	if req.URL.Scheme == UnixScheme {
+		if socketPath, _, err := splitUnixURL(req.URL); err == nil {
+			return UnixScheme + ":" + socketPath
+		}
+	}
+	return req.URL.Host
+}
+
+// unixSocketTransport sends unix:// requests over the named socket, keeping a connection pool per socket, and passes
+// every other request to base.
+type unixSocketTransport struct {
+	base       http.RoundTripper
+	template   *http.Transport
+	transports sync.Map
+}
+
+func newUnixSocketTransport(base http.RoundTripper) *unixSocketTransport {
+	template, ok := base.(*http.Transport)
+	if !ok {
+		template = http.DefaultTransport.(*http.Transport)
+	}
+	return &unixSocketTransport{base: base, template: template}
+}
+
+func (t *unixSocketTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != UnixScheme {
+		return t.base.RoundTrip(req)
+	}
+
+	socketPath, path, err := splitUnixURL(req.URL)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Scheme = "http"
+	out.URL.Host = "localhost"
+	out.URL.Path = path
+	out.URL.RawPath = ""
+	out.Host = "localhost"
+	return t.transportFor(socketPath).RoundTrip(out)
+}
+
+func (t *unixSocketTransport) transportFor(socketPath string) *http.Transport {
+	if transport, ok := t.transports.Load(socketPath); ok {
+		return transport.(*http.Transport)
+	}
+
+	transport := t.template.Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+	actual, _ := t.transports.LoadOrStore(socketPath, transport)
+	return actual.(*http.Transport)
+}
@@ -0,0 +1,62 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+// TestClientUnixSocket tests that NewClient sends unix:// requests over the socket
+func TestClientUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("Unix domain sockets unavailable: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path+"?"+r.URL.RawQuery)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := NewClient(ClientConfig{Breaker: &BreakerConfig{}})
+	resp, err := client.Get(UnixSocketURL(socketPath, "/v1/info") + "?all=1")
+	if err != nil {
+		t.Fatalf("Expected the request to reach the socket, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "/v1/info?all=1" {
+		t.Errorf("Expected the request path and query, got %q", body)
+	}
+
+	_, err = client.Get(UnixSocketURL(filepath.Join(t.TempDir(), "missing.sock"), "/"))
+	if !IsUnixSocketUnavailableError(err) {
+		t.Errorf("Expected a missing socket to be unavailable, got %v", err)
+	}
+	if IsTransientNetworkOrDNSIssueErr(err) {
+		t.Error("Expected a missing socket not to be a network or DNS issue")
+	}
+
+	if _, err := client.Get("unix://host/path"); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("Expected ErrInvalidURL for a unix URL with a host, got %v", err)
+	}
+}
+
+// TestClientDialContext tests that a custom dialer opens the client's connections
+func TestClientDialContext(t *testing.T) {
+	dialErr := errors.New("tunnel down")
+	client := NewClient(ClientConfig{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return nil, dialErr
+		},
+	})
+
+	if _, err := client.Get("http://example.invalid/"); !errors.Is(err, dialErr) {
+		t.Errorf("Expected the custom dialer error, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package httpext
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidURL is returned by BuildURL when the base, path or resulting URL is not a valid absolute http(s) URL.
+var ErrInvalidURL = errors.New("invalid URL")
+
+// BuildURL joins path onto base and appends params as an encoded query string.
+//
+// The path is joined with exactly one slash and may not contain ".." segments. Query values are encoded by type:
+// time.Time as RFC 3339, time.Duration and fmt.Stringer via String, numbers and booleans with strconv, and slices or
+// arrays as repeated keys. Nil values and nil pointers are skipped. Query parameters already present on base are
+// kept.
+//
+// Example usage:
+//
+//	u, err := httpext.BuildURL("https://api.example.com/v1", "filings", map[string]any{
+//		"cik":   []int{320193, 789019},
+//		"since": time.Now().Add(-24 * time.Hour),
+//	})
+func BuildURL(base string, path string, params map[string]interface{}) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("%w: base %q: %v", ErrInvalidURL, base, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w: base %q must use http or https", ErrInvalidURL, base)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("%w: base %q has no host", ErrInvalidURL, base)
+	}
+
+	if path != "" {
+		for _, seg := range strings.Split(path, "/") {
+			if seg == ".." {
+				return "", fmt.Errorf("%w: path %q may not contain '..'", ErrInvalidURL, path)
+			}
+		}
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + strings.TrimLeft(path, "/")
+		u.RawPath = ""
+	}
+
+	query := u.Query()
+	for key, value := range params {
+		values, err := queryValues(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: param %q: %v", ErrInvalidURL, key, err)
+		}
+		for _, v := range values {
+			query.Add(key, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	result := u.String()
+	if _, err := url.ParseRequestURI(result); err != nil {
+		return "", fmt.Errorf("%w: %q: %v", ErrInvalidURL, result, err)
+	}
+	return result, nil
+}
+
+func queryValues(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case time.Time:
+		return []string{v.Format(time.RFC3339)}, nil
+	case time.Duration:
+		return []string{v.String()}, nil
+	case fmt.Stringer:
+		return []string{v.String()}, nil
+	case []byte:
+		return []string{string(v)}, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return queryValues(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		var out []string
+		for i := 0; i < rv.Len(); i++ {
+			values, err := queryValues(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, values...)
+		}
+		return out, nil
+	case reflect.Bool:
+		return []string{strconv.FormatBool(rv.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return []string{strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits())}, nil
+	case reflect.String:
+		return []string{rv.String()}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported query value type %T", value)
+}
@@ -0,0 +1,63 @@
+package httpext
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestBuildURL tests path joining, query encoding by type and rejection of invalid input
+func TestBuildURL(t *testing.T) {
+	cik := 320193
+	var missing *int
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		base   string
+		path   string
+		params map[string]interface{}
+		want   string
+	}{
+		{"slashes", "https://api.example.com/v1/", "/filings", nil, "https://api.example.com/v1/filings"},
+		{"no path", "https://api.example.com/v1?key=abc", "", map[string]interface{}{"q": "a b&c"}, "https://api.example.com/v1?key=abc&q=a+b%26c"},
+		{"escaped path", "https://api.example.com", "search/a b", nil, "https://api.example.com/search/a%20b"},
+		{"numbers and bools", "http://localhost:8080", "items", map[string]interface{}{"limit": 50, "ratio": 0.25, "all": true, "id": uint8(7)}, "http://localhost:8080/items?all=true&id=7&limit=50&ratio=0.25"},
+		{"time and duration", "https://api.example.com", "events", map[string]interface{}{"since": since, "window": 90 * time.Second}, "https://api.example.com/events?since=2024-03-01T12%3A00%3A00Z&window=1m30s"},
+		{"stringer", "https://api.example.com", "hosts", map[string]interface{}{"ip": net.IPv4(10, 0, 0, 1)}, "https://api.example.com/hosts?ip=10.0.0.1"},
+		{"slice", "https://api.example.com", "filings", map[string]interface{}{"cik": []int{320193, 789019}}, "https://api.example.com/filings?cik=320193&cik=789019"},
+		{"pointers and nil", "https://api.example.com", "filings", map[string]interface{}{"cik": &cik, "form": missing, "q": nil}, "https://api.example.com/filings?cik=320193"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildURL(tt.base, tt.path, tt.params)
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %q, got %q, %v", tt.want, got, err)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name   string
+		base   string
+		path   string
+		params map[string]interface{}
+	}{
+		{"unparsable base", "https://api.example.com/%zz", "", nil},
+		{"scheme", "ftp://files.example.com", "", nil},
+		{"relative base", "/v1/filings", "", nil},
+		{"no host", "https://", "filings", nil},
+		{"dot dot", "https://api.example.com/v1", "../admin", nil},
+		{"unsupported value", "https://api.example.com", "", map[string]interface{}{"filter": map[string]string{"a": "b"}}},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildURL(tt.base, tt.path, tt.params); !errors.Is(err, ErrInvalidURL) {
+				t.Errorf("Expected ErrInvalidURL, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// InitTiming records how long a registered initializer took and how it ended.
+type InitTiming struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Err      error         `json:"-"`
+}
+
+type initializer struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+var (
+	initMu       sync.Mutex
+	initializers []initializer
+	initTimings  []InitTiming
+)
+
+// RegisterInit adds fn to the initializers that Run executes, in registration order, before calling main. Use it
+// instead of init() side effects such as opening pools or loading config, so startup order and cost are visible:
+// each initializer is timed and logged, and the totals are logged once all have run.
+//
+// Every initializer runs even when an earlier one fails; failures are collected into a MultiError, each prefixed
+// with its name, and Run exits with ExitCode of that error without calling main.
+//
+// Example usage:
+//
+//	func init() {
+//		app.RegisterInit("db", func(ctx context.Context) error {
+//			return db.Connect(ctx)
+//		})
+//	}
+func RegisterInit(name string, fn func(ctx context.Context) error) {
+	initMu.Lock()
+	defer initMu.Unlock()
+	initializers = append(initializers, initializer{name: name, fn: fn})
+}
+
+// InitTimings returns the timings of the initializers run by Run, in the order they ran.
+func InitTimings() []InitTiming {
+	initMu.Lock()
+	defer initMu.Unlock()
+	return append([]InitTiming(nil), initTimings...)
+}
+
+// runInits runs the registered initializers, converting panics into errors, and logs a startup summary.
+func runInits(ctx context.Context) error {
+	initMu.Lock()
+	pending := append([]initializer(nil), initializers...)
+	initMu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var mErr MultiError
+	timings := make([]InitTiming, 0, len(pending))
+	start := time.Now()
+
+	for _, step := range pending {
+		began := time.Now()
+		err := runMain(ctx, step.fn)
+		timing := InitTiming{Name: step.name, Duration: time.Since(began), Err: err}
+		timings = append(timings, timing)
+
+		if err != nil {
+			slog.Error("Initializer failed", "name", step.name, "duration", timing.Duration, "error", err)
+			mErr.AppendWrapped(step.name, err)
+			continue
+		}
+		slog.Info("Initializer completed", "name", step.name, "duration", timing.Duration)
+	}
+
+	initMu.Lock()
+	initTimings = timings
+	initMu.Unlock()
+
+	steps := make([]interface{}, 0, len(timings))
+	for _, timing := range timings {
+		steps = append(steps, slog.Duration(timing.Name, timing.Duration))
+	}
+	slog.Info("Startup initializers finished",
+		"count", len(timings),
+		"failed", len(mErr.Errors),
+		"total", time.Since(start),
+		slog.Group("steps", steps...),
+	)
+
+	return mErr.ErrorOrNil()
+}
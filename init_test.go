@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestRunInitializers tests that registered initializers run in order and abort Run when one fails
+func TestRunInitializers(t *testing.T) {
+	var code int
+	osExit = func(c int) { code = c }
+	defer func() {
+		osExit = os.Exit
+		initializers = nil
+	}()
+
+	var order []string
+	RegisterInit("config", func(ctx context.Context) error {
+		order = append(order, "config")
+		return fmt.Errorf("%w: missing DSN", ErrConfig)
+	})
+	RegisterInit("cache", func(ctx context.Context) error {
+		order = append(order, "cache")
+		return nil
+	})
+
+	mainCalled := false
+	Run(func(ctx context.Context) error {
+		mainCalled = true
+		return nil
+	})
+
+	if mainCalled {
+		t.Error("Expected main not to run after a failed initializer")
+	}
+	if strings.Join(order, ",") != "config,cache" {
+		t.Errorf("Expected initializers in registration order, got %v", order)
+	}
+	if code != ExitConfig {
+		t.Errorf("Expected exit code %d, got %d", ExitConfig, code)
+	}
+	if timings := InitTimings(); len(timings) != 2 || timings[0].Name != "config" || timings[0].Err == nil {
+		t.Errorf("Expected timings for both initializers, got %+v", timings)
+	}
+}
@@ -0,0 +1,190 @@
+package jsonext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrRequiredField is wrapped by the violations reported for fields tagged `jsonext:"required"` that are empty
+// after decoding.
+var ErrRequiredField = errors.New("required field missing")
+
+// Validator is implemented by types that check their own invariants after decoding. Unmarshal and Decode call it
+// automatically.
+type Validator interface {
+	Validate() error
+}
+
+// Unmarshal decodes data into v like json.Unmarshal and then validates the result, see Validate.
+func Unmarshal(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	return Validate(v)
+}
+
+// Decode reads the next JSON value from r into v and then validates the result, see Validate.
+func Decode(r io.Reader, v interface{}) error {
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return err
+	}
+	return Validate(v)
+}
+
+// Validate checks a decoded value so that payloads which decode cleanly but are semantically empty do not flow
+// downstream. Struct fields tagged `jsonext:"required"` must not hold their zero value, and every value implementing
+// Validator, including nested structs and the elements of slices, arrays and maps, has its Validate method called.
+// Violations are labelled with their path, such as "items[3].id" or "byCik.0000320193.form".
+//
+// All violations are collected into an *app.MultiError; nil is returned when there are none.
+//
+// Example usage:
+//
+//	type Filing struct {
+//		CIK  string `json:"cik" jsonext:"required"`
+//		Form string `json:"form" jsonext:"required"`
+//	}
+//
+//	var f Filing
+//	if err := jsonext.Unmarshal(data, &f); err != nil {
+//		return err
+//	}
+func Validate(v interface{}) error {
+	var mErr app.MultiError
+	validateValue(reflect.ValueOf(v), "", &mErr)
+	return mErr.ErrorOrNil()
+}
+
+func validateValue(rv reflect.Value, path string, mErr *app.MultiError) {
+	if !rv.IsValid() {
+		return
+	}
+
+	if validator, ok := validatorOf(rv); ok {
+		if err := validator.Validate(); err != nil {
+			if path != "" {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+			mErr.Append(err)
+		}
+	}
+
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if !mayNeedValidation(rv.Type().Elem()) {
+			return
+		}
+		for i := 0; i < rv.Len(); i++ {
+			validateValue(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), mErr)
+		}
+		return
+	case reflect.Map:
+		if !mayNeedValidation(rv.Type().Elem()) {
+			return
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			keyPath := fmt.Sprint(key.Interface())
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+			// Copy the value so Validate methods with a pointer receiver are found, as for slice elements
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			elem.Set(rv.MapIndex(key))
+			validateValue(elem, keyPath, mErr)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldPath := jsonFieldName(field)
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		fv := rv.Field(i)
+		if hasTagOption(field.Tag.Get("jsonext"), "required") && fv.IsZero() {
+			mErr.Append(fmt.Errorf("%w: %s", ErrRequiredField, fieldPath))
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Struct, reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Array, reflect.Map:
+			validateValue(fv, fieldPath, mErr)
+		}
+	}
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// mayNeedValidation reports whether values of type t can hold a Validator or a field tagged required, so collections
+// of plain values such as []byte are not walked element by element.
+func mayNeedValidation(t reflect.Type) bool {
+	if t.Implements(validatorType) || reflect.PointerTo(t).Implements(validatorType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func hasTagOption(tag string, option string) bool {
+	for _, opt := range strings.Split(tag, ",") {
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
+	}
+	return false
+}
+
+func validatorOf(rv reflect.Value) (Validator, bool) {
+	if (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return nil, false
+	}
+	if rv.CanInterface() {
+		if validator, ok := rv.Interface().(Validator); ok {
+			return validator, true
+		}
+	}
+	if rv.CanAddr() && rv.Addr().CanInterface() {
+		if validator, ok := rv.Addr().Interface().(Validator); ok {
+			return validator, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,97 @@
+package jsonext
+
+import (
+	"errors"
+	"github.com/mhpenta/app"
+	"strings"
+	"testing"
+)
+
+type testAddress struct {
+	City string `json:"city" jsonext:"required"`
+}
+
+type testFiling struct {
+	CIK     string       `json:"cik" jsonext:"required"`
+	Form    string       `json:"form" jsonext:"required"`
+	Pages   int          `json:"pages"`
+	Address *testAddress `json:"address"`
+}
+
+func (f testFiling) Validate() error {
+	if f.Pages < 0 {
+		return errors.New("pages must not be negative")
+	}
+	return nil
+}
+
+// TestUnmarshalValidates tests required fields, nested structs and Validator implementations
+func TestUnmarshalValidates(t *testing.T) {
+	var filing testFiling
+	if err := Unmarshal([]byte(`{"cik":"0000320193","form":"10-K","address":{"city":"Cupertino"}}`), &filing); err != nil {
+		t.Fatalf("Expected a valid filing, got %v", err)
+	}
+
+	filing = testFiling{}
+	err := Unmarshal([]byte(`{"form":"","pages":-1,"address":{}}`), &filing)
+	var mErr *app.MultiError
+	if !errors.As(err, &mErr) || len(mErr.Errors) != 4 {
+		t.Fatalf("Expected four violations, got %v", err)
+	}
+	for _, want := range []string{"cik", "form", "address.city", "pages must not be negative"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected a violation for %s, got %v", want, err)
+		}
+	}
+	if !errors.Is(err, ErrRequiredField) {
+		t.Errorf("Expected ErrRequiredField in the chain, got %v", err)
+	}
+
+	if err := Unmarshal([]byte(`{"cik":`), &filing); err == nil || errors.Is(err, ErrRequiredField) {
+		t.Errorf("Expected the syntax error returned, got %v", err)
+	}
+}
+
+// TestDecodeValidates tests that Decode validates the value it reads
+func TestDecodeValidates(t *testing.T) {
+	var filing testFiling
+	if err := Decode(strings.NewReader(`{"cik":"0000320193","form":"10-K"}`), &filing); err != nil || filing.Form != "10-K" {
+		t.Fatalf("Expected the filing decoded, got %+v, %v", filing, err)
+	}
+
+	filing = testFiling{}
+	if err := Decode(strings.NewReader(`{"cik":"0000789019"}`), &filing); !errors.Is(err, ErrRequiredField) || !strings.Contains(err.Error(), "form") {
+		t.Errorf("Expected the missing form reported, got %v", err)
+	}
+}
+
+type testBatch struct {
+	Filings []testFiling          `json:"filings"`
+	ByCIK   map[string]testFiling `json:"byCik"`
+	Raw     []byte                `json:"raw"`
+}
+
+// TestValidateElements tests that slice, array and map elements are validated and labelled with their path
+func TestValidateElements(t *testing.T) {
+	var batch testBatch
+	err := Unmarshal([]byte(`{"filings":[{"cik":"1","form":"10-K"},{"cik":"2"}],"byCik":{"3":{"form":"8-K"}}}`), &batch)
+	for _, want := range []string{"filings[1].form", "byCik.3.cik"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected a violation for %s, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "filings[0]") {
+		t.Errorf("Expected the valid element accepted, got %v", err)
+	}
+
+	var filings []testFiling
+	err = Unmarshal([]byte(`[{"cik":"1","form":"10-K"},{"cik":"2","form":"10-Q","pages":-1}]`), &filings)
+	if !strings.Contains(err.Error(), "[1]") || !strings.Contains(err.Error(), "pages must not be negative") {
+		t.Errorf("Expected the Validate error of the second element, got %v", err)
+	}
+
+	var pair [2]testAddress
+	if err := Unmarshal([]byte(`[{"city":"Cupertino"},{}]`), &pair); !errors.Is(err, ErrRequiredField) || !strings.Contains(err.Error(), "[1].city") {
+		t.Errorf("Expected the missing city of the second element reported, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package jsonext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrDuplicateKey is wrapped by the error UnmarshalStrict returns when an object repeats a key.
+var ErrDuplicateKey = errors.New("duplicate key in JSON object")
+
+// UnmarshalStrict is Unmarshal that first rejects documents in which any object repeats a key. encoding/json keeps
+// the last value silently, which hides upstream bugs; the error lists every duplicate as a path in Get syntax, e.g.
+// "duplicate key in JSON object: items[2].id, meta.source".
+func UnmarshalStrict(data []byte, v interface{}) error {
+	duplicates, err := FindDuplicateKeys(data)
+	if err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateKey, strings.Join(duplicates, ", "))
+	}
+	return Unmarshal(data, v)
+}
+
+// FindDuplicateKeys reports the path of every repeated object key in data, in document order, for callers that want
+// to log duplicates rather than reject the payload. It returns an error if data is not valid JSON.
+func FindDuplicateKeys(data []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var duplicates []string
+	if err := scanDuplicates(dec, "", &duplicates); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// scanDuplicates consumes one JSON value from dec, recording duplicate keys found under path.
+func scanDuplicates(dec *json.Decoder, path string, duplicates *[]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		seen := make(map[string]bool)
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := keyTok.(string)
+
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if seen[key] {
+				*duplicates = append(*duplicates, keyPath)
+			}
+			seen[key] = true
+
+			if err := scanDuplicates(dec, keyPath, duplicates); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			if err := scanDuplicates(dec, path+"["+strconv.Itoa(i)+"]", duplicates); err != nil {
+				return err
+			}
+		}
+	}
+
+	// closing delimiter
+	_, err = dec.Token()
+	return err
+}
@@ -0,0 +1,54 @@
+package jsonext
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFindDuplicateKeys tests reporting repeated keys by path in nested objects and arrays
+func TestFindDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"none", `{"id":1,"meta":{"id":2}}`, nil},
+		{"top level", `{"id":1,"id":2}`, []string{"id"}},
+		{"nested", `{"meta":{"source":"a","source":"b"}}`, []string{"meta.source"}},
+		{"array", `{"items":[{"id":1},{"id":2,"id":3}]}`, []string{"items[1].id"}},
+		{"document order", `{"a":{"x":1,"x":2},"a":{}}`, []string{"a.x", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindDuplicateKeys([]byte(tt.data))
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %q, got %q, %v", tt.want, got, err)
+			}
+		})
+	}
+
+	if _, err := FindDuplicateKeys([]byte(`{"id":1,`)); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+// TestUnmarshalStrict tests rejecting duplicate keys before decoding
+func TestUnmarshalStrict(t *testing.T) {
+	var v struct {
+		ID int `json:"id"`
+	}
+	if err := UnmarshalStrict([]byte(`{"id":7}`), &v); err != nil || v.ID != 7 {
+		t.Fatalf("Expected id 7, got %d, %v", v.ID, err)
+	}
+
+	v.ID = 0
+	err := UnmarshalStrict([]byte(`{"id":1,"id":2,"meta":{"source":"a","source":"b"}}`), &v)
+	if !errors.Is(err, ErrDuplicateKey) || !strings.HasSuffix(err.Error(), ": id, meta.source") {
+		t.Errorf("Expected both duplicates listed, got %v", err)
+	}
+	if v.ID != 0 {
+		t.Errorf("Expected nothing decoded from a rejected document, got id %d", v.ID)
+	}
+}
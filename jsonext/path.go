@@ -0,0 +1,109 @@
+package jsonext
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Get extracts a single value from a JSON document using a dotted path with optional array indices, without
+// requiring a struct definition for the whole payload.
+//
+// Supported path forms:
+//
+//	error.code
+//	errors[0].message
+//	errors.0.message
+//	data.items[2][0]
+//
+// Objects are returned as map[string]interface{}, arrays as []interface{} and numbers as json.Number. The boolean
+// result is false when data is not valid JSON or the path does not exist. An empty path returns the whole document.
+func Get(data []byte, path string) (interface{}, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, false
+	}
+
+	segments, ok := splitPath(path)
+	if !ok {
+		return nil, false
+	}
+
+	current := doc
+	for _, seg := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, exists := node[seg]
+			if !exists {
+				return nil, false
+			}
+			current = v
+		case []interface{}:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			current = node[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// GetString is like Get but returns the value as a string. Strings are returned as-is, numbers and booleans in their
+// JSON text form. Objects, arrays and null report false.
+func GetString(data []byte, path string) (string, bool) {
+	v, ok := Get(data, path)
+	if !ok {
+		return "", false
+	}
+
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	case bool:
+		return strconv.FormatBool(val), true
+	default:
+		return "", false
+	}
+}
+
+// splitPath turns "a.b[0].c" into ["a", "b", "0", "c"]. Empty segments, as in "a..b" or "a.", and empty or
+// unterminated brackets, as in "a[]" or "a[0", make the path invalid.
+func splitPath(path string) ([]string, bool) {
+	if path == "" {
+		return nil, true
+	}
+
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			return nil, false
+		}
+		open := strings.IndexByte(part, '[')
+		if open == -1 {
+			segments = append(segments, part)
+			continue
+		}
+		if open > 0 {
+			segments = append(segments, part[:open])
+		}
+		for rest := part[open:]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end <= 1 {
+				return nil, false
+			}
+			segments = append(segments, rest[1:end])
+			rest = rest[end+1:]
+		}
+	}
+	return segments, true
+}
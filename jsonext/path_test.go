@@ -0,0 +1,71 @@
+package jsonext
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestGet tests dotted paths, array indices and missing values
+func TestGet(t *testing.T) {
+	data := []byte(`{"error":{"code":"RATE_LIMITED","retry":true},"errors":[{"message":"bad cik"}],"data":{"items":[[1],[2],[3,4]]},"count":42}`)
+
+	tests := []struct {
+		path string
+		want interface{}
+		ok   bool
+	}{
+		{"error.code", "RATE_LIMITED", true},
+		{"errors[0].message", "bad cik", true},
+		{"errors.0.message", "bad cik", true},
+		{"data.items[2][1]", json.Number("4"), true},
+		{"count", json.Number("42"), true},
+		{"errors[1].message", nil, false},
+		{"errors[-1]", nil, false},
+		{"error.code.value", nil, false},
+		{"missing", nil, false},
+		{"errors[0", nil, false},
+		{"error..code", nil, false},
+		{"error.code.", nil, false},
+		{".error", nil, false},
+		{"errors[]", nil, false},
+		{"errors[0]message", nil, false},
+		{"data.items[2][]", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := Get(data, tt.path)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%q): expected %v %v, got %v %v", tt.path, tt.want, tt.ok, got, ok)
+		}
+	}
+
+	if doc, ok := Get(data, ""); !ok || doc.(map[string]interface{})["count"] != json.Number("42") {
+		t.Errorf("Expected the whole document for an empty path, got %v", doc)
+	}
+	if _, ok := Get([]byte(`{"error":`), "error"); ok {
+		t.Error("Expected invalid JSON to report false")
+	}
+}
+
+// TestGetString tests the string forms of scalar values
+func TestGetString(t *testing.T) {
+	data := []byte(`{"cik":"0000320193","count":42,"final":false,"meta":{},"note":null}`)
+
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"cik", "0000320193", true},
+		{"count", "42", true},
+		{"final", "false", true},
+		{"meta", "", false},
+		{"note", "", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := GetString(data, tt.path); got != tt.want || ok != tt.ok {
+			t.Errorf("GetString(%q): expected %q %v, got %q %v", tt.path, tt.want, tt.ok, got, ok)
+		}
+	}
+}
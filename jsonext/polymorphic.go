@@ -0,0 +1,99 @@
+package jsonext
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownType is wrapped by the error DecodePoly returns when the discriminator names no registered type.
+	ErrUnknownType = errors.New("unknown polymorphic type")
+	// ErrMissingDiscriminator is wrapped by the error DecodePoly returns when the discriminator field is absent or
+	// not a string.
+	ErrMissingDiscriminator = errors.New("missing type discriminator")
+)
+
+// Polymorphic maps discriminator values to the Go types of payloads whose shape depends on a field such as "type".
+// It is safe for concurrent use; register types during initialization.
+type Polymorphic struct {
+	mu        sync.RWMutex
+	factories map[string]func() interface{}
+}
+
+// NewPolymorphic creates an empty registry.
+func NewPolymorphic() *Polymorphic {
+	return &Polymorphic{factories: make(map[string]func() interface{})}
+}
+
+// RegisterType makes DecodePoly decode payloads whose discriminator is value into the pointer returned by factory.
+// Registering a value again replaces its factory. It panics if factory is nil.
+func (p *Polymorphic) RegisterType(value string, factory func() interface{}) {
+	if factory == nil {
+		panic("jsonext: RegisterType with nil factory for " + value)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.factories[value] = factory
+}
+
+// DecodePoly reads the string at discriminatorField, a path in Get syntax such as "type" or "meta.kind", and decodes
+// data into a new value from the factory registered for it, validating it like Unmarshal. The result is the
+// factory's value, typically a pointer to a struct, ready for a type switch.
+//
+// Example usage:
+//
+//	events := jsonext.NewPolymorphic()
+//	events.RegisterType("filing.created", func() interface{} { return &FilingCreated{} })
+//	events.RegisterType("filing.amended", func() interface{} { return &FilingAmended{} })
+//
+//	event, err := events.DecodePoly(data, "type")
+//	if err != nil {
+//		return err
+//	}
+//	switch e := event.(type) {
+//	case *FilingCreated:
+//		...
+//	}
+func (p *Polymorphic) DecodePoly(data []byte, discriminatorField string) (interface{}, error) {
+	raw, ok := Get(data, discriminatorField)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q not found", ErrMissingDiscriminator, discriminatorField)
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not a string", ErrMissingDiscriminator, discriminatorField)
+	}
+
+	p.mu.RLock()
+	factory, ok := p.factories[value]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (registered: %s)", ErrUnknownType, value, strings.Join(p.Types(), ", "))
+	}
+
+	v := factory()
+	if rv := reflect.ValueOf(v); !rv.IsValid() || rv.Kind() != reflect.Pointer {
+		return nil, fmt.Errorf("jsonext: factory for %q returned %T, want a pointer", value, v)
+	}
+	if err := Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("decode %q payload: %w", value, err)
+	}
+	return v, nil
+}
+
+// Types returns the registered discriminator values in sorted order.
+func (p *Polymorphic) Types() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	types := make([]string, 0, len(p.factories))
+	for value := range p.factories {
+		types = append(types, value)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,65 @@
+package jsonext
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type filingCreated struct {
+	Type string `json:"type"`
+	CIK  string `json:"cik" jsonext:"required"`
+}
+
+type filingAmended struct {
+	Type     string `json:"type"`
+	Original string `json:"original"`
+}
+
+// TestDecodePoly tests decoding by discriminator and the errors for missing, unknown and invalid payloads
+func TestDecodePoly(t *testing.T) {
+	events := NewPolymorphic()
+	events.RegisterType("filing.created", func() interface{} { return &filingCreated{} })
+	events.RegisterType("filing.amended", func() interface{} { return &filingAmended{} })
+	events.RegisterType("broken", func() interface{} { return filingAmended{} })
+
+	event, err := events.DecodePoly([]byte(`{"type":"filing.created","cik":"0000320193"}`), "type")
+	if created, ok := event.(*filingCreated); err != nil || !ok || created.CIK != "0000320193" {
+		t.Fatalf("Expected a *filingCreated, got %#v, %v", event, err)
+	}
+
+	event, err = events.DecodePoly([]byte(`{"meta":{"kind":"filing.amended"},"original":"a-1"}`), "meta.kind")
+	if amended, ok := event.(*filingAmended); err != nil || !ok || amended.Original != "a-1" {
+		t.Errorf("Expected a *filingAmended from a nested discriminator, got %#v, %v", event, err)
+	}
+
+	if _, err := events.DecodePoly([]byte(`{"cik":"1"}`), "type"); !errors.Is(err, ErrMissingDiscriminator) {
+		t.Errorf("Expected ErrMissingDiscriminator for an absent field, got %v", err)
+	}
+	if _, err := events.DecodePoly([]byte(`{"type":7}`), "type"); !errors.Is(err, ErrMissingDiscriminator) {
+		t.Errorf("Expected ErrMissingDiscriminator for a number, got %v", err)
+	}
+	if _, err := events.DecodePoly([]byte(`{"type":"filing.deleted"}`), "type"); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Expected ErrUnknownType, got %v", err)
+	}
+	if _, err := events.DecodePoly([]byte(`{"type":"filing.created"}`), "type"); !errors.Is(err, ErrRequiredField) {
+		t.Errorf("Expected the payload validated, got %v", err)
+	}
+	if _, err := events.DecodePoly([]byte(`{"type":"broken"}`), "type"); err == nil {
+		t.Error("Expected an error for a factory returning a non-pointer")
+	}
+
+	if types := events.Types(); !reflect.DeepEqual(types, []string{"broken", "filing.amended", "filing.created"}) {
+		t.Errorf("Expected sorted types, got %q", types)
+	}
+}
+
+// TestRegisterTypeNilFactory tests that registering a nil factory panics
+func TestRegisterTypeNilFactory(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic")
+		}
+	}()
+	NewPolymorphic().RegisterType("filing.created", nil)
+}
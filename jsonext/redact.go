@@ -0,0 +1,239 @@
+package jsonext
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrInvalidRedactPath is returned by RedactStream for paths that cannot be parsed.
+var ErrInvalidRedactPath = errors.New("invalid redact path")
+
+// RedactConfig holds configuration for RedactStreamWithConfig
+type RedactConfig struct {
+	// Paths selects the values to redact, in Get syntax. A "*" segment matches any key or array index, e.g.
+	// "users[*].ssn" or "*.password".
+	Paths []string
+	// Mask replaces redacted values. Defaults to DefaultRedactConfig.Mask.
+	Mask string
+	// Remove drops redacted object members and array elements instead of masking them
+	Remove bool
+}
+
+// DefaultRedactConfig provides sensible default values for RedactConfig
+var DefaultRedactConfig = RedactConfig{
+	Mask: "[REDACTED]",
+}
+
+// RedactStream copies the JSON read from r to w, replacing the values at paths with DefaultRedactConfig.Mask. The
+// input is processed token by token, so arbitrarily large documents are never held in memory; a stream of
+// concatenated or newline-delimited documents is written out one document per line. Output is compact.
+//
+// Example usage:
+//
+//	err := jsonext.RedactStream(resp.Body, logFile, []string{"customer.ssn", "cards[*].number", "*.apiKey"})
+func RedactStream(r io.Reader, w io.Writer, paths []string) error {
+	return RedactStreamWithConfig(r, w, RedactConfig{Paths: paths})
+}
+
+// RedactStreamWithConfig is RedactStream with a custom mask or removal of redacted values, see RedactConfig.
+func RedactStreamWithConfig(r io.Reader, w io.Writer, config RedactConfig) error {
+	if config.Mask == "" {
+		config.Mask = DefaultRedactConfig.Mask
+	}
+
+	patterns := make([][]string, 0, len(config.Paths))
+	for _, path := range config.Paths {
+		segments, ok := splitPath(path)
+		if !ok || len(segments) == 0 {
+			return fmt.Errorf("%w: %q", ErrInvalidRedactPath, path)
+		}
+		patterns = append(patterns, segments)
+	}
+
+	mask, err := encodeJSONString(config.Mask)
+	if err != nil {
+		return err
+	}
+
+	redactor := &redactor{
+		dec:      json.NewDecoder(r),
+		out:      bufio.NewWriter(w),
+		patterns: patterns,
+		mask:     mask,
+		remove:   config.Remove,
+	}
+	redactor.dec.UseNumber()
+
+	for {
+		tok, err := redactor.dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := redactor.value(tok, nil); err != nil {
+			return err
+		}
+		redactor.out.WriteByte('\n')
+	}
+	return redactor.out.Flush()
+}
+
+type redactor struct {
+	dec      *json.Decoder
+	out      *bufio.Writer
+	patterns [][]string
+	mask     []byte
+	remove   bool
+}
+
+// matches reports whether path is selected by one of the redact patterns.
+func (r *redactor) matches(path []string) bool {
+	for _, pattern := range r.patterns {
+		if len(pattern) != len(path) {
+			continue
+		}
+		matched := true
+		for i, seg := range pattern {
+			if seg != "*" && seg != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// value copies the JSON value starting with tok to the output, redacting selected descendants of path.
+func (r *redactor) value(tok json.Token, path []string) error {
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return r.scalar(tok)
+	}
+
+	closing := byte('}')
+	if delim == '[' {
+		closing = ']'
+	}
+	r.out.WriteByte(byte(delim))
+
+	written := 0
+	for i := 0; r.dec.More(); i++ {
+		var key string
+		if delim == '{' {
+			keyTok, err := r.dec.Token()
+			if err != nil {
+				return err
+			}
+			key = keyTok.(string)
+		} else {
+			key = strconv.Itoa(i)
+		}
+
+		child := append(path[:len(path):len(path)], key)
+		redact := r.matches(child)
+		if redact && r.remove {
+			if err := r.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if written > 0 {
+			r.out.WriteByte(',')
+		}
+		written++
+		if delim == '{' {
+			if err := r.scalar(key); err != nil {
+				return err
+			}
+			r.out.WriteByte(':')
+		}
+
+		if redact {
+			if err := r.skip(); err != nil {
+				return err
+			}
+			r.out.Write(r.mask)
+			continue
+		}
+
+		next, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := r.value(next, child); err != nil {
+			return err
+		}
+	}
+
+	// closing delimiter
+	if _, err := r.dec.Token(); err != nil {
+		return err
+	}
+	return r.out.WriteByte(closing)
+}
+
+// skip consumes the next JSON value without writing it.
+func (r *redactor) skip() error {
+	depth := 0
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			default:
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (r *redactor) scalar(tok json.Token) error {
+	switch v := tok.(type) {
+	case string:
+		encoded, err := encodeJSONString(v)
+		if err != nil {
+			return err
+		}
+		_, err = r.out.Write(encoded)
+		return err
+	case json.Number:
+		_, err := r.out.WriteString(v.String())
+		return err
+	case bool:
+		_, err := r.out.WriteString(strconv.FormatBool(v))
+		return err
+	case nil:
+		_, err := r.out.WriteString("null")
+		return err
+	}
+	return fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// encodeJSONString quotes s as a JSON string without escaping HTML characters.
+func encodeJSONString(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,107 @@
+package jsonext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestRedactStream tests masking and removing values selected by nested and wildcard paths
+func TestRedactStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		config RedactConfig
+		want   string
+	}{
+		{
+			name:   "nested path",
+			input:  `{"customer":{"name":"Ada","ssn":"123-45-6789"},"ssn":"kept"}`,
+			config: RedactConfig{Paths: []string{"customer.ssn"}},
+			want:   `{"customer":{"name":"Ada","ssn":"[REDACTED]"},"ssn":"kept"}`,
+		},
+		{
+			name:   "wildcard array index",
+			input:  `{"cards":[{"number":"4111","exp":"12/30"},{"number":"5500"}]}`,
+			config: RedactConfig{Paths: []string{"cards[*].number"}},
+			want:   `{"cards":[{"number":"[REDACTED]","exp":"12/30"},{"number":"[REDACTED]"}]}`,
+		},
+		{
+			name:   "wildcard key",
+			input:  `{"db":{"password":"p1","host":"h"},"smtp":{"password":{"value":"p2"}},"password":"top"}`,
+			config: RedactConfig{Paths: []string{"*.password"}},
+			want:   `{"db":{"password":"[REDACTED]","host":"h"},"smtp":{"password":"[REDACTED]"},"password":"top"}`,
+		},
+		{
+			name:   "custom mask without HTML escaping",
+			input:  `{"token":"abc","note":"a<b & c"}`,
+			config: RedactConfig{Paths: []string{"token"}, Mask: "<hidden>"},
+			want:   `{"token":"<hidden>","note":"a<b & c"}`,
+		},
+		{
+			name:   "remove object members",
+			input:  `{"apiKey":"k","name":"svc","nested":{"apiKey":"k2"}}`,
+			config: RedactConfig{Paths: []string{"apiKey", "nested.apiKey"}, Remove: true},
+			want:   `{"name":"svc","nested":{}}`,
+		},
+		{
+			name:   "remove array elements",
+			input:  `{"tags":["public",{"secret":true},"internal"]}`,
+			config: RedactConfig{Paths: []string{"tags[1]", "tags[2]"}, Remove: true},
+			want:   `{"tags":["public"]}`,
+		},
+		{
+			name:   "remove first element",
+			input:  `[{"id":1},{"id":2}]`,
+			config: RedactConfig{Paths: []string{"[0]"}, Remove: true},
+			want:   `[{"id":2}]`,
+		},
+		{
+			name:   "scalars and numbers kept verbatim",
+			input:  `{"amount":12.50,"big":12345678901234567890,"ok":true,"none":null}`,
+			config: RedactConfig{Paths: []string{"missing"}},
+			want:   `{"amount":12.50,"big":12345678901234567890,"ok":true,"none":null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := RedactStreamWithConfig(strings.NewReader(tt.input), &out, tt.config); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSuffix(out.String(), "\n"); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestRedactStreamNDJSON tests that each document of a stream is redacted and written on its own line
+func TestRedactStreamNDJSON(t *testing.T) {
+	input := "{\"user\":\"a\",\"ssn\":\"1\"}\n{\"user\":\"b\",\"ssn\":\"2\"}\n\n{\"user\":\"c\"} {\"ssn\":\"3\"}"
+	var out strings.Builder
+	if err := RedactStream(strings.NewReader(input), &out, []string{"ssn"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"user":"a","ssn":"[REDACTED]"}` + "\n" + `{"user":"b","ssn":"[REDACTED]"}` + "\n" + `{"user":"c"}` + "\n" + `{"ssn":"[REDACTED]"}` + "\n"
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
+
+// TestRedactStreamErrors tests malformed input and invalid paths
+func TestRedactStreamErrors(t *testing.T) {
+	for _, input := range []string{`{"ssn":`, `{"ssn":"1"`, `{"a" 1}`, `[1,]`, `{"ssn":"1"}}`} {
+		var out strings.Builder
+		if err := RedactStream(strings.NewReader(input), &out, []string{"ssn"}); err == nil {
+			t.Errorf("Expected an error for %q, got output %q", input, out.String())
+		}
+	}
+
+	for _, path := range []string{"", "items[0"} {
+		err := RedactStream(strings.NewReader(`{}`), &strings.Builder{}, []string{path})
+		if !errors.Is(err, ErrInvalidRedactPath) {
+			t.Errorf("Expected ErrInvalidRedactPath for %q, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package jsonext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrWriterClosed is returned by RotatingWriter after Close.
+var ErrWriterClosed = errors.New("rotating writer is closed")
+
+// RotatingWriter appends newline-delimited JSON records to a file and rotates it once it reaches a size limit.
+// Rotated files are renamed path.1, path.2, ... with path.1 the most recent, and only maxFiles of them are kept.
+// Each record is written with a single write call under a lock, so records from concurrent writers never interleave.
+//
+// Example usage:
+//
+//	w, err := jsonext.NewRotatingWriter("audit.log", 10<<20, 5)
+//	if err != nil {
+//		return err
+//	}
+//	defer app.CloseWithLog(w, "audit log")
+//	err = w.WriteRecord(map[string]interface{}{"user": user, "action": "delete"})
+type RotatingWriter struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+}
+
+// NewRotatingWriter opens path for appending, creating it if needed. A maxSize of zero or less disables rotation,
+// and a maxFiles of zero or less discards the current file on rotation instead of keeping it.
+func NewRotatingWriter(path string, maxSize int64, maxFiles int) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteRecord marshals v as JSON and writes it as one line.
+func (w *RotatingWriter) WriteRecord(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Write writes p as one record, adding a trailing newline if p does not end with one. It implements io.Writer, so
+// the writer can back a slog.JSONHandler. If the file is due for rotation and rotating fails, p is not written and
+// the error is returned; the writer keeps the current file and retries the rotation on the next write.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n == 0 || p[n-1] != '\n' {
+		line := make([]byte, n+1)
+		copy(line, p)
+		line[n] = '\n'
+		p = line
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	written, err := w.file.Write(p)
+	w.size += int64(written)
+	if err != nil {
+		return written, err
+	}
+	return n, nil
+}
+
+// Close closes the current file. Later writes return ErrWriterClosed.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.file.Close()
+}
+
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate moves the current file aside and opens a new one. If the backups cannot be shifted, the current file is
+// reopened so later writes append to it and retry the rotation.
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	if err := w.shiftBackups(); err != nil {
+		if openErr := w.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+	return w.open()
+}
+
+func (w *RotatingWriter) shiftBackups() error {
+	if w.maxFiles <= 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.Remove(w.backupName(w.maxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := w.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(w.backupName(i), w.backupName(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, w.backupName(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (w *RotatingWriter) backupName(i int) string {
+	return fmt.Sprintf("%s.%d", w.path, i)
+}
@@ -0,0 +1,128 @@
+package jsonext
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// TestRotatingWriter tests size-based rotation, the number of backups kept and writes after Close
+func TestRotatingWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// Each record is 10 bytes with its newline, so a file holds three before the 30 byte limit
+	w, err := NewRotatingWriter(path, 30, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := w.WriteRecord(map[string]int{"seq": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := readLines(t, path); len(lines) != 1 || lines[0] != `{"seq":9}` {
+		t.Errorf("Expected the last record in the current file, got %q", lines)
+	}
+	if lines := readLines(t, path+".1"); len(lines) != 3 || lines[0] != `{"seq":6}` {
+		t.Errorf("Expected the most recent backup in .1, got %q", lines)
+	}
+	if lines := readLines(t, path+".2"); len(lines) != 3 || lines[0] != `{"seq":3}` {
+		t.Errorf("Expected the older backup in .2, got %q", lines)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only two backups kept, got %v", err)
+	}
+
+	if _, err := w.Write([]byte(`{"seq":10}`)); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("Expected ErrWriterClosed, got %v", err)
+	}
+}
+
+// TestRotatingWriterRotateFailure tests that a failed rotation keeps the current file open and is retried
+func TestRotatingWriterRotateFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	w, err := NewRotatingWriter(path, 30, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for i := 0; i < 3; i++ {
+		if err := w.WriteRecord(map[string]int{"seq": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A non-empty directory in place of the oldest backup cannot be removed, so the rotation fails
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocked"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRecord(map[string]int{"seq": 3}); err == nil {
+		t.Fatal("Expected the failed rotation reported")
+	}
+	if lines := readLines(t, path); len(lines) != 3 || lines[2] != `{"seq":2}` {
+		t.Errorf("Expected the current file left intact, got %q", lines)
+	}
+
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRecord(map[string]int{"seq": 4}); err != nil {
+		t.Fatalf("Expected the rotation retried once unblocked, got %v", err)
+	}
+	if lines := readLines(t, path); len(lines) != 1 || lines[0] != `{"seq":4}` {
+		t.Errorf("Expected the record written to a fresh file, got %q", lines)
+	}
+	if lines := readLines(t, path+".1"); len(lines) != 3 || lines[0] != `{"seq":0}` {
+		t.Errorf("Expected the previous file rotated to .1, got %q", lines)
+	}
+}
+
+// TestRotatingWriterConcurrent tests that records written concurrently never interleave
+func TestRotatingWriterConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	w, err := NewRotatingWriter(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, _ = w.Write([]byte(`{"action":"delete","user":"` + strings.Repeat("u", 100) + `"}`))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := readLines(t, path)
+	if len(lines) != 400 {
+		t.Fatalf("Expected 400 records, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, `{"action"`) || !strings.HasSuffix(line, `"}`) {
+			t.Fatalf("Expected whole records, got %q", line)
+		}
+	}
+}
@@ -2,15 +2,17 @@ package app
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 )
 
-const initialStackSize = 64
 const maxStackDepth = 1024 // To prevent excessive memory usage
 
 var ErrNotMetaError = errors.New("error is not a MetaError")
@@ -18,14 +20,30 @@ var ErrNotMetaError = errors.New("error is not a MetaError")
 // MetaError wraps an error with additional context information such as file,
 // line number, function name, package name, and stack trace.
 type MetaError struct {
-	Err              error
-	File             string
-	Line             int
-	Func             string
-	Package          string
-	stackTrace       []uintptr
-	stackTraceString string
-	asCSV            bool
+	Err     error
+	File    string
+	Line    int
+	Func    string
+	Package string
+	// Receiver is the receiver type name for methods, or the enclosing function for closures
+	Receiver string
+	// ReceiverPtr reports whether the method has a pointer receiver
+	ReceiverPtr bool
+	// TypeGeneric holds the type parameters of a generic receiver, e.g. "int" for (*List[int]).Push
+	TypeGeneric string
+	// FuncGeneric holds the type parameters of a generic function
+	FuncGeneric string
+	// Fields holds structured context attached with WithField, such as the phase a request timed out in
+	Fields map[string]interface{}
+
+	code         string
+	retryability retryability
+	httpStatus   int
+	stackTrace   []uintptr
+	stack        *stackCache
+	decodedStack []stackFrameJSON
+	stackSkipped bool
+	asCSV        bool
 }
 
 // Errorf creates a new MetaError with the given format and arguments and captures the stack trace.
@@ -44,6 +62,90 @@ func NewMetaError(err error) *MetaError {
 	return NewMetaErrorOptions(err, 2, true, true) // Skip 2 frames
 }
 
+// Wrap prepends msg to err, producing a readable cause chain such as "load config: read file: permission denied".
+// When err contains a MetaError, the result keeps its capture site, stack, code and fields; otherwise the location
+// of the Wrap call is captured. The original error stays reachable through errors.Is and errors.As. Wrap returns nil
+// for a nil err.
+func Wrap(err error, msg string) *MetaError {
+	return wrap(err, msg, 3)
+}
+
+// Wrapf is Wrap with a formatted message.
+func Wrapf(err error, format string, args ...interface{}) *MetaError {
+	return wrap(err, fmt.Sprintf(format, args...), 3)
+}
+
+func wrap(err error, msg string, skip int) *MetaError {
+	if err == nil {
+		return nil
+	}
+
+	// err.Error() rather than %w formatting, which would render a MetaError with its location
+	wrapped := &messageError{msg: msg + ": " + err.Error(), err: err}
+
+	var origin *MetaError
+	if !errors.As(err, &origin) {
+		return NewMetaErrorOptions(wrapped, skip, true, true)
+	}
+
+	metaErr := *origin
+	metaErr.Err = wrapped
+	if origin.Fields != nil {
+		metaErr.Fields = make(map[string]interface{}, len(origin.Fields))
+		for k, v := range origin.Fields {
+			metaErr.Fields[k] = v
+		}
+	}
+	return &metaErr
+}
+
+// messageError is an error with a fixed message that unwraps to err.
+type messageError struct {
+	msg string
+	err error
+}
+
+func (m *messageError) Error() string {
+	return m.msg
+}
+
+func (m *messageError) Unwrap() error {
+	return m.err
+}
+
+// LogValue implements slog.LogValuer, so logging a MetaError emits its message, location, code and fields as a group:
+//
+//	slog.Error("Sync failed", "err", metaErr)
+//	// err.message=... err.file=sync.go err.line=42 err.func=Run err.package=github.com/org/sync
+//
+// The keys match MarshalJSON, so records written by slog's JSON handler can be read back with FromSlogMap.
+func (e *MetaError) LogValue() slog.Value {
+	if e == nil {
+		return slog.StringValue("<nil>")
+	}
+
+	attrs := []slog.Attr{
+		slog.String("message", e.Error()),
+		slog.String("file", e.File),
+		slog.Int("line", e.Line),
+		slog.String("func", e.Func),
+		slog.String("package", e.Package),
+	}
+	if e.code != "" {
+		attrs = append(attrs, slog.String("code", e.code))
+	}
+	if len(e.Fields) > 0 {
+		fields := make([]interface{}, 0, len(e.Fields))
+		for k, v := range e.Fields {
+			fields = append(fields, slog.Any(k, v))
+		}
+		attrs = append(attrs, slog.Group("fields", fields...))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// Slog returns flat *_meta key/value pairs describing err at the caller's location. Logging a MetaError directly
+// is usually simpler, see LogValue; Slog remains for plain errors and for pipelines that index the *_meta keys.
 func Slog(err error) []interface{} {
 	metaError := NewMetaError(err)
 
@@ -73,114 +175,436 @@ func Slog(err error) []interface{} {
 //   - captureStack: Whether to capture and store the stack trace
 //   - asCSV: Whether error should be formatted as CSV
 func NewMetaErrorOptions(err error, skip int, captureStack bool, asCSV bool) *MetaError {
-	pc, file, line, ok := runtime.Caller(skip)
-	if !ok {
-		file = "unknown"
-		line = 0
+	stackLimit := 0
+	if captureStack {
+		stackLimit = maxStackDepth
 	}
+	return newMetaError(err, skip+1, stackLimit, asCSV)
+}
 
-	fn := runtime.FuncForPC(pc)
-	funcName := "unknown"
-	packageName := "unknown"
+// NewMetaErrorLite creates a MetaError for hot paths. It captures the caller location like NewMetaError but at most
+// LiteStackDepth stack frames, which keeps the cost of wrapping errors in tight loops low.
+//
+// Example usage:
+//
+//	for _, row := range rows {
+//		if err := parse(row); err != nil {
+//			mErr.Append(app.NewMetaErrorLite(err))
+//		}
+//	}
+func NewMetaErrorLite(err error) *MetaError {
+	return newMetaError(err, 2, LiteStackDepth, true)
+}
 
-	if fn != nil {
-		fullFuncName := fn.Name()
-		lastDotIndex := strings.LastIndex(fullFuncName, ".")
-		if lastDotIndex != -1 {
-			packageName = fullFuncName[:lastDotIndex]
-			funcName = fullFuncName[lastDotIndex+1:]
-		} else {
-			funcName = fullFuncName
-		}
-	}
+// LiteStackDepth is the number of stack frames captured by NewMetaErrorLite.
+var LiteStackDepth = 8
 
+// newMetaError creates a MetaError located skip frames above it, as counted by runtime.Caller, capturing at most
+// stackLimit stack frames.
+func newMetaError(err error, skip int, stackLimit int, asCSV bool) *MetaError {
+	info, skipped := caller(skip + 1)
 	metaErr := &MetaError{
 		Err:     err,
-		File:    filepath.Base(file),
-		Line:    line,
-		Func:    funcName,
-		Package: packageName,
+		File:    "unknown",
+		Line:    info.Line,
+		Func:    "unknown",
+		Package: "unknown",
 		asCSV:   asCSV,
 	}
+	if info.File != "" {
+		metaErr.File = filepath.Base(info.File)
+	}
+	metaErr.setFuncInfo(info)
 
-	if captureStack {
-		pcs := make([]uintptr, initialStackSize)
-		n := runtime.Callers(skip, pcs)
-		for n == len(pcs) && len(pcs) < maxStackDepth {
-			pcs = make([]uintptr, len(pcs)*2)
-			n = runtime.Callers(skip, pcs)
-		}
-		if len(pcs) > maxStackDepth {
-			pcs = pcs[:maxStackDepth]
+	annotateClockJump(metaErr)
+
+	if stackLimit > 0 {
+		if allowStackCapture() {
+			pcs := captureStack(skip+1+skipped, stackLimit)
+			chargeStack(pcs)
+			metaErr.setStack(pcs)
+		} else {
+			metaErr.stackSkipped = true
 		}
-		metaErr.stackTrace = pcs[:n]
 	}
 
 	return metaErr
 }
 
+// pcPool holds scratch buffers for captureStack, so capturing a stack allocates only the final, exactly sized slice.
+var pcPool = sync.Pool{
+	New: func() interface{} {
+		pcs := make([]uintptr, maxStackDepth)
+		return &pcs
+	},
+}
+
+// captureStack returns at most limit program counters, starting skip frames above its caller as counted by
+// runtime.Caller.
+func captureStack(skip int, limit int) []uintptr {
+	if limit > maxStackDepth {
+		limit = maxStackDepth
+	}
+
+	buf := pcPool.Get().(*[]uintptr)
+	defer pcPool.Put(buf)
+
+	// runtime.Callers counts itself as frame 0, one more than runtime.Caller
+	n := runtime.Callers(skip+1, (*buf)[:limit])
+	pcs := make([]uintptr, n)
+	copy(pcs, (*buf)[:n])
+	return pcs
+}
+
+// setFuncName fills the function and package fields from a fully qualified runtime function name, see funcInfo.
+func (e *MetaError) setFuncName(fullFuncName string) {
+	e.setFuncInfo(funcInfo(fullFuncName))
+}
+
+// setFuncInfo fills the function and package fields from info, leaving them untouched when info has no name.
+func (e *MetaError) setFuncInfo(info FuncInfo) {
+	if info.Name == "" {
+		return
+	}
+	e.Func = info.Name
+	if info.PkgPath != "" {
+		e.Package = info.PkgPath
+	}
+	e.Receiver = info.Qualifier
+	e.ReceiverPtr = info.Ptr
+	e.TypeGeneric = info.TypeGeneric
+	e.FuncGeneric = info.FuncGeneric
+}
+
 // Error returns the error message with context.
 func (e *MetaError) Error() string {
-	if e.Err == nil {
+	if e == nil || e.Err == nil {
 		return "<nil>"
 	}
 	return e.Err.Error()
 }
 
+// Format implements fmt.Formatter.
+//
+// %s and %v print the message with its capture site (or the CSV record for CSV errors), %+v adds the stack trace
+// and %q quotes the message. A precision truncates the message, e.g. %.20s, and a width pads the whole output,
+// left-justified with the '-' flag. %#v prints a Go-syntax representation of all fields for debugging. A nil
+// *MetaError prints as <nil> for every verb.
 func (e *MetaError) Format(s fmt.State, verb rune) {
-	if e.asCSV {
-		switch verb {
-		case 'v':
-			if s.Flag('+') {
-				fmt.Fprintf(s, "%s|%s", e.ToCSV(), e.StackTrace())
-			}
-			fallthrough
-		default:
-			fmt.Fprintf(s, "%s", e.ToCSV())
-		}
+	if e == nil {
+		writePadded(s, "<nil>")
+		return
+	}
+
+	if verb == 'v' && s.Flag('#') {
+		writePadded(s, e.goString())
 		return
 	}
 
-	var errMsg string
-	if e.Err != nil {
-		errMsg = e.Err.Error()
-	} else {
-		errMsg = "<nil>"
+	msg := e.Error()
+	if precision, ok := s.Precision(); ok {
+		msg = truncateRunes(msg, precision)
+	}
+
+	writePadded(s, e.render(verb, s.Flag('+'), msg))
+}
+
+// render produces the output for verb using msg as the message, applying installed templates first, then the CSV
+// layout, then the default text layout.
+func (e *MetaError) render(verb rune, plus bool, msg string) string {
+	if verb == 'v' || verb == 's' {
+		if out, ok := e.formatWithTemplate(verb == 'v' && plus, msg); ok {
+			return out
+		}
+	}
+
+	if e.asCSV {
+		if verb == 'v' && plus {
+			return e.toCSV(msg) + "|" + e.StackTrace()
+		}
+		return e.toCSV(msg)
 	}
 
 	switch verb {
 	case 'v':
-		if s.Flag('+') {
-			fmt.Fprintf(s, "%s\n\tat %s:%d (%s) [package: %s]%s",
-				errMsg, e.File, e.Line, e.Func, e.Package, e.StackTrace())
-			return
+		if plus {
+			return fmt.Sprintf("%s\n\tat %s:%d (%s) [package: %s]%s",
+				msg, e.File, e.Line, e.Func, e.Package, e.StackTrace())
 		}
 		fallthrough
 	case 's':
-		fmt.Fprintf(s, "%s\n\tat %s:%d (%s) [package: %s]",
-			errMsg, e.File, e.Line, e.Func, e.Package)
+		return fmt.Sprintf("%s\n\tat %s:%d (%s) [package: %s]",
+			msg, e.File, e.Line, e.Func, e.Package)
 	case 'q':
-		fmt.Fprintf(s, "%q\n\tat %s:%d (%s) [package: %s]",
-			errMsg, e.File, e.Line, e.Func, e.Package)
+		return fmt.Sprintf("%q\n\tat %s:%d (%s) [package: %s]",
+			msg, e.File, e.Line, e.Func, e.Package)
+	}
+	return fmt.Sprintf("%%!%c(*app.MetaError=%s)", verb, msg)
+}
+
+// goString returns a Go-syntax-like dump of e for %#v.
+func (e *MetaError) goString() string {
+	return fmt.Sprintf("&app.MetaError{Err:%#v, File:%q, Line:%d, Func:%q, Package:%q, Receiver:%q, ReceiverPtr:%t, TypeGeneric:%q, FuncGeneric:%q, Code:%q, StackDepth:%d}",
+		e.Err, e.File, e.Line, e.Func, e.Package, e.Receiver, e.ReceiverPtr, e.TypeGeneric, e.FuncGeneric, e.code, len(e.stackTrace))
+}
+
+// writePadded writes out to s honoring the width and '-' flag of the verb.
+func writePadded(s fmt.State, out string) {
+	width, ok := s.Width()
+	if !ok {
+		fmt.Fprint(s, out)
+		return
+	}
+	if s.Flag('-') {
+		fmt.Fprintf(s, "%-*s", width, out)
+		return
+	}
+	fmt.Fprintf(s, "%*s", width, out)
+}
+
+func truncateRunes(str string, n int) string {
+	if n < 0 {
+		return str
+	}
+	i := 0
+	for pos := range str {
+		if i == n {
+			return str[:pos]
+		}
+		i++
 	}
+	return str
 }
 
-// StackTrace returns the formatted stack trace if captured.
+// StackTrace returns the formatted stack trace if captured, or the one decoded by UnmarshalJSON. Frames are filtered
+// and trimmed according to SetStackOptions.
 func (e *MetaError) StackTrace() string {
-	if len(e.stackTrace) == 0 {
+	if e.stack == nil {
+		return e.renderStack()
+	}
+
+	gen := stackOptionsGen.Load()
+	e.stack.mu.Lock()
+	defer e.stack.mu.Unlock()
+	if !e.stack.renderedOK || e.stack.renderedGen != gen {
+		e.stack.rendered = e.renderStack()
+		e.stack.renderedGen = gen
+		e.stack.renderedOK = true
+	}
+	return e.stack.rendered
+}
+
+func (e *MetaError) renderStack() string {
+	frames, omitted := applyStackOptions(e.Frames())
+	if len(frames) == 0 {
 		return ""
 	}
+
 	var builder strings.Builder
-	frames := runtime.CallersFrames(e.stackTrace)
-	for {
-		frame, more := frames.Next()
-		fmt.Fprintf(&builder, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
-		if !more {
-			break
+	for _, frame := range frames {
+		file, url := sourceLocation(frame)
+		if url != "" {
+			fmt.Fprintf(&builder, "\n%s\n\t%s", frame.Function, url)
+			continue
+		}
+		fmt.Fprintf(&builder, "\n%s\n\t%s:%d", frame.Function, file, frame.Line)
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&builder, "\n\t... %d more frames", omitted)
+	}
+	return builder.String()
+}
+
+// WithField sets a structured context field on e and returns e, so calls can be chained:
+//
+//	return app.NewMetaError(err).WithField("phase", "dns").WithField("host", host)
+func (e *MetaError) WithField(key string, value interface{}) *MetaError {
+	if e.Fields == nil {
+		e.Fields = make(map[string]interface{})
+	}
+	e.Fields[key] = value
+	return e
+}
+
+// WithCode sets a machine-readable error code, such as "DB_TIMEOUT", and returns e. Codes survive CSV and JSON
+// round-trips, so handlers can branch on them instead of matching messages.
+func (e *MetaError) WithCode(code string) *MetaError {
+	e.code = code
+	return e
+}
+
+// Code returns the error code set with WithCode, or an empty string.
+func (e *MetaError) Code() string {
+	if e == nil {
+		return ""
+	}
+	return e.code
+}
+
+// HasCode reports whether any MetaError in err's tree carries code.
+//
+// Example usage:
+//
+//	if app.HasCode(err, "DB_TIMEOUT") {
+//		return retryLater(job)
+//	}
+func HasCode(err error, code string) bool {
+	found := false
+	walkMetaErrors(err, func(metaErr *MetaError) bool {
+		found = metaErr.code == code
+		return !found
+	})
+	return found
+}
+
+// CodeOf returns the first non-empty error code in err's tree, or an empty string.
+func CodeOf(err error) string {
+	code := ""
+	walkMetaErrors(err, func(metaErr *MetaError) bool {
+		code = metaErr.code
+		return code == ""
+	})
+	return code
+}
+
+// WithHTTPStatus sets the HTTP status a handler should respond with when e reaches it, e.g. http.StatusNotFound for
+// a missing record, and returns e. It lets the service layer decide the status without depending on net/http.
+func (e *MetaError) WithHTTPStatus(code int) *MetaError {
+	e.httpStatus = code
+	return e
+}
+
+// HTTPStatus returns the status set with WithHTTPStatus on the outermost MetaError in err's tree that has one, or
+// else the status of the first error created with Define in its chain.
+//
+// Example usage:
+//
+//	status, ok := app.HTTPStatus(err)
+//	if !ok {
+//		status = http.StatusInternalServerError
+//	}
+func HTTPStatus(err error) (int, bool) {
+	status := 0
+	walkMetaErrors(err, func(metaErr *MetaError) bool {
+		status = metaErr.httpStatus
+		return status == 0
+	})
+	if status == 0 {
+		var definedErr *Error
+		if errors.As(err, &definedErr) {
+			status = definedErr.status
+		}
+	}
+	return status, status != 0
+}
+
+// retryability is an explicit retry decision recorded on a MetaError.
+type retryability int8
+
+const (
+	retryUnset retryability = iota
+	retryAllowed
+	retryDenied
+)
+
+// MarkRetryable declares err safe to retry and returns it as a MetaError. The retry package honours the flag ahead
+// of its own classification, so an error its heuristics would reject is still retried. When err is already a
+// MetaError it is flagged in place; otherwise the location of the call is captured. MarkRetryable returns nil for a
+// nil err.
+//
+// Example usage:
+//
+//	if resp.StatusCode == http.StatusConflict {
+//		return app.MarkRetryable(fmt.Errorf("version conflict on %s", id))
+//	}
+func MarkRetryable(err error) *MetaError {
+	return markRetryability(err, retryAllowed)
+}
+
+// MarkPermanent declares that retrying err cannot succeed, e.g. a validation failure, and returns it as a MetaError.
+// Retry loops return a permanent error immediately even when it looks transient. MarkPermanent returns nil for a
+// nil err.
+func MarkPermanent(err error) *MetaError {
+	return markRetryability(err, retryDenied)
+}
+
+func markRetryability(err error, flag retryability) *MetaError {
+	if err == nil {
+		return nil
+	}
+	metaErr, ok := err.(*MetaError)
+	if !ok {
+		metaErr = NewMetaErrorOptions(err, 3, true, true)
+	}
+	metaErr.retryability = flag
+	return metaErr
+}
+
+// IsRetryable returns the retry decision recorded with MarkRetryable or MarkPermanent on the outermost flagged
+// MetaError in err's tree. marked is false when no MetaError carries a flag, leaving the decision to the caller's
+// own classification.
+func IsRetryable(err error) (retryable bool, marked bool) {
+	walkMetaErrors(err, func(metaErr *MetaError) bool {
+		if metaErr.retryability == retryUnset {
+			return true
+		}
+		retryable, marked = metaErr.retryability == retryAllowed, true
+		return false
+	})
+	return retryable, marked
+}
+
+// AsMetaError returns the deepest MetaError in err's tree, searching wrapped errors and every branch of joined
+// errors such as MultiError. The deepest one is closest to where the failure originated; among MetaErrors at the
+// same depth the first found wins. The boolean is false when err contains no MetaError.
+//
+// Example usage:
+//
+//	if metaErr, ok := app.AsMetaError(err); ok {
+//		slog.Error("Import failed", "err", metaErr)
+//	}
+func AsMetaError(err error) (*MetaError, bool) {
+	var deepest *MetaError
+	deepestLevel := -1
+
+	var walk func(err error, level int)
+	walk = func(err error, level int) {
+		for ; err != nil; level++ {
+			if metaErr, ok := err.(*MetaError); ok && level > deepestLevel {
+				deepest, deepestLevel = metaErr, level
+			}
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, e := range joined.Unwrap() {
+					walk(e, level+1)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	walk(err, 0)
+
+	return deepest, deepest != nil
+}
+
+// walkMetaErrors calls f for each MetaError in err's tree, depth first, until f returns false. It reports whether
+// the walk completed.
+func walkMetaErrors(err error, f func(*MetaError) bool) bool {
+	for err != nil {
+		if metaErr, ok := err.(*MetaError); ok && !f(metaErr) {
+			return false
 		}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				if !walkMetaErrors(e, f) {
+					return false
+				}
+			}
+			return true
+		}
+		err = errors.Unwrap(err)
 	}
-	e.stackTraceString = builder.String()
-	return e.stackTraceString
+	return true
 }
 
 // Unwrap returns the underlying error.
@@ -211,14 +635,21 @@ func RootCause(err error) error {
 }
 
 func (e *MetaError) ToCSV() string {
+	return e.toCSV(e.Error())
+}
+
+func (e *MetaError) toCSV(msg string) string {
 	record := []string{
-		e.Err.Error(),
+		msg,
 		e.File,
 		strconv.Itoa(e.Line),
 		e.Func,
 		e.Package,
 		//e.StackTrace(),
 	}
+	if e.code != "" {
+		record = append(record, e.code)
+	}
 
 	var buf strings.Builder
 	w := csv.NewWriter(&buf)
@@ -229,35 +660,8 @@ func (e *MetaError) ToCSV() string {
 	return strings.TrimSpace(buf.String())
 }
 
-func MetaErrorFromCSV(csvStr string) (*MetaError, error) {
-	r := csv.NewReader(strings.NewReader(csvStr))
-	r.Comma = '|' // Use pipe as separator
-	r.FieldsPerRecord = 5
-
-	record, err := r.Read()
-	if err != nil {
-		return nil, ErrNotMetaError
-	}
-
-	if len(record) != 5 {
-		return nil, ErrNotMetaError
-	}
-
-	line, err := strconv.Atoi(record[2])
-	if err != nil {
-		return nil, ErrNotMetaError
-	}
-
-	return &MetaError{
-		Err:     errors.New(record[0]),
-		File:    record[1],
-		Line:    line,
-		Func:    record[3],
-		Package: record[4],
-		//stackTraceString: record[5],
-	}, nil
-}
-
+// FromSlogMap rebuilds a MetaError from a decoded slog record. The error attribute ("err", "error" or "metaErr") may
+// hold a CSV record, a JSON string, or the object written by slog's JSON handler through MarshalJSON.
 func FromSlogMap(slogError map[string]interface{}) (*MetaError, error) {
 	msgVal, ok := slogError["err"]
 	if !ok {
@@ -270,10 +674,18 @@ func FromSlogMap(slogError map[string]interface{}) (*MetaError, error) {
 		}
 	}
 
-	metaErrorCSV, ok := msgVal.(string)
-	if !ok {
-		return nil, ErrNotMetaError
+	switch v := msgVal.(type) {
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "{") {
+			return FromJSON(v)
+		}
+		return MetaErrorFromCSV(v)
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, ErrNotMetaError
+		}
+		return FromJSON(string(data))
 	}
-
-	return MetaErrorFromCSV(metaErrorCSV)
+	return nil, ErrNotMetaError
 }
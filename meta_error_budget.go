@@ -0,0 +1,80 @@
+package app
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// StackBudget limits how much stack capturing new MetaErrors may do per second across the process. Once either limit
+// is reached, MetaErrors created in the same second skip stack capture and report StackSkipped, so hot error paths
+// producing tens of thousands of errors per second do not pay for runtime.Callers on every one. Zero fields are
+// unlimited.
+type StackBudget struct {
+	// CapturesPerSecond is the number of stacks captured per second
+	CapturesPerSecond int64
+	// BytesPerSecond is the number of bytes of program counters captured per second
+	BytesPerSecond int64
+}
+
+var (
+	stackBudget       atomic.Pointer[StackBudget]
+	stackWindowSecond atomic.Int64
+	stackWindowCount  atomic.Int64
+	stackWindowBytes  atomic.Int64
+	stacksSkipped     atomic.Uint64
+)
+
+// SetStackBudget installs budget process-wide. A zero StackBudget removes the limits.
+//
+// Example usage:
+//
+//	app.SetStackBudget(app.StackBudget{CapturesPerSecond: 1000, BytesPerSecond: 4 << 20})
+func SetStackBudget(budget StackBudget) {
+	if budget.CapturesPerSecond <= 0 && budget.BytesPerSecond <= 0 {
+		stackBudget.Store(nil)
+		return
+	}
+	stackBudget.Store(&budget)
+}
+
+// SkippedStacks returns the number of MetaErrors created without a stack because the StackBudget was exhausted.
+func SkippedStacks() uint64 {
+	return stacksSkipped.Load()
+}
+
+// StackSkipped reports whether the stack of e was not captured because the StackBudget was exhausted.
+func (e *MetaError) StackSkipped() bool {
+	return e != nil && e.stackSkipped
+}
+
+// allowStackCapture reports whether the budget allows capturing one more stack in the current second. The window is
+// reset without a lock, so the limits are approximate under contention.
+func allowStackCapture() bool {
+	budget := stackBudget.Load()
+	if budget == nil {
+		return true
+	}
+
+	second := Now().Unix()
+	if window := stackWindowSecond.Load(); window != second && stackWindowSecond.CompareAndSwap(window, second) {
+		stackWindowCount.Store(0)
+		stackWindowBytes.Store(0)
+	}
+
+	if budget.BytesPerSecond > 0 && stackWindowBytes.Load() >= budget.BytesPerSecond {
+		stacksSkipped.Add(1)
+		return false
+	}
+	if budget.CapturesPerSecond > 0 && stackWindowCount.Add(1) > budget.CapturesPerSecond {
+		stacksSkipped.Add(1)
+		return false
+	}
+	return true
+}
+
+// chargeStack counts a captured stack of pcs against the byte budget.
+func chargeStack(pcs []uintptr) {
+	if stackBudget.Load() != nil {
+		stackWindowBytes.Add(int64(len(pcs)) * int64(unsafe.Sizeof(uintptr(0))))
+	}
+}
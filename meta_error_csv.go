@@ -0,0 +1,160 @@
+package app
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrPartialMetaError is wrapped by the error MetaErrorFromCSVLenient returns alongside a MetaError rebuilt from a
+// damaged or unrecognized record. The error lists what could not be decoded.
+var ErrPartialMetaError = errors.New("partially decoded MetaError")
+
+// The CSV layouts written by ToCSV and the %+v verb over time, told apart by their number of fields:
+//
+//	5: message|file|line|func|package
+//	6: message|file|line|func|package|code, or ...|package|stack
+//	7: message|file|line|func|package|code|stack
+//
+// The stack is written unquoted by %+v, so it starts with a newline and spans the following lines.
+const (
+	minCSVFields = 5
+	maxCSVFields = 7
+)
+
+// MetaErrorFromCSV decodes a record written by ToCSV, or by the %+v verb for CSV errors, in any of the layouts above.
+// A stack trace in the record is restored, so StackTrace returns it again. It returns ErrNotMetaError for anything
+// else; use MetaErrorFromCSVLenient to salvage what it can from damaged records.
+func MetaErrorFromCSV(csvStr string) (*MetaError, error) {
+	metaErr, problems := parseMetaErrorCSV(csvStr)
+	if metaErr == nil || len(problems) > 0 {
+		return nil, ErrNotMetaError
+	}
+	return metaErr, nil
+}
+
+// MetaErrorFromCSVLenient decodes csvStr like MetaErrorFromCSV, but returns whatever it can decode from records
+// with missing or extra fields, a malformed line number or unreadable stack frames, together with an error wrapping
+// ErrPartialMetaError that describes the problems. It returns ErrNotMetaError only when csvStr holds no record at
+// all.
+//
+// Example usage:
+//
+//	metaErr, err := app.MetaErrorFromCSVLenient(line)
+//	if errors.Is(err, app.ErrPartialMetaError) {
+//		slog.Warn("Partially decoded error log line", "warning", err)
+//	} else if err != nil {
+//		return err
+//	}
+func MetaErrorFromCSVLenient(csvStr string) (*MetaError, error) {
+	metaErr, problems := parseMetaErrorCSV(csvStr)
+	if metaErr == nil {
+		return nil, ErrNotMetaError
+	}
+	if len(problems) > 0 {
+		return metaErr, fmt.Errorf("%w: %s", ErrPartialMetaError, strings.Join(problems, "; "))
+	}
+	return metaErr, nil
+}
+
+// parseMetaErrorCSV decodes as much of csvStr as it can, returning nil when there is no record, and a description
+// of every part that does not match a known layout.
+func parseMetaErrorCSV(csvStr string) (*MetaError, []string) {
+	r := csv.NewReader(strings.NewReader(csvStr))
+	r.Comma = '|' // Use pipe as separator
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	record, err := r.Read()
+	if err != nil || len(record) == 0 || (len(record) == 1 && record[0] == "") {
+		return nil, []string{"no record"}
+	}
+	rest := csvStr[r.InputOffset():]
+
+	var problems []string
+	if len(record) < minCSVFields {
+		problems = append(problems, fmt.Sprintf("%d fields, want at least %d", len(record), minCSVFields))
+	}
+	if len(record) > maxCSVFields {
+		problems = append(problems, fmt.Sprintf("%d fields, want at most %d; extra fields ignored", len(record), maxCSVFields))
+		record = record[:maxCSVFields]
+	}
+	field := func(i int) string {
+		if i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	metaErr := &MetaError{
+		Err:     errors.New(field(0)),
+		File:    field(1),
+		Func:    field(3),
+		Package: field(4),
+	}
+	if len(record) > 2 {
+		if metaErr.Line, err = strconv.Atoi(field(2)); err != nil {
+			problems = append(problems, fmt.Sprintf("line %q is not a number", field(2)))
+		}
+	}
+
+	var stack string
+	switch {
+	case len(record) == 7:
+		metaErr.code = field(5)
+		stack = field(6)
+	case len(record) == 6 && (rest != "" || strings.Contains(field(5), "\n")):
+		stack = field(5)
+	case len(record) == 6:
+		metaErr.code = field(5)
+	}
+	if rest != "" {
+		stack += "\n" + rest
+	}
+	if stack != "" {
+		frames, bad := parseStackTrace(stack)
+		metaErr.decodedStack = frames
+		if bad > 0 {
+			problems = append(problems, fmt.Sprintf("%d unreadable stack lines", bad))
+		}
+	}
+	return metaErr, problems
+}
+
+// parseStackTrace reads frames rendered by StackTrace, a function line followed by a tab-indented file:line line,
+// returning them with the number of lines it could not read. The "... N more frames" summary is skipped.
+func parseStackTrace(stack string) ([]stackFrameJSON, int) {
+	var frames []stackFrameJSON
+	bad := 0
+	function := ""
+
+	for _, line := range strings.Split(stack, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.TrimSpace(line) == "":
+		case strings.HasPrefix(line, "\t... ") && strings.HasSuffix(line, " more frames"):
+		case strings.HasPrefix(line, "\t"):
+			location := strings.TrimPrefix(line, "\t")
+			i := strings.LastIndexByte(location, ':')
+			lineNo, err := strconv.Atoi(location[i+1:])
+			if function == "" || i < 0 || err != nil {
+				bad++
+				function = ""
+				continue
+			}
+			frames = append(frames, stackFrameJSON{Func: function, File: location[:i], Line: lineNo})
+			function = ""
+		default:
+			if function != "" {
+				bad++
+			}
+			function = line
+		}
+	}
+	if function != "" {
+		bad++
+	}
+	return frames, bad
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+var (
+	// sourceLinePattern matches file:line references such as "handler.go:123", whose line shifts between builds
+	sourceLinePattern = regexp.MustCompile(`(\.go):\d+`)
+	// pointerPattern matches addresses such as "0xc000123abc" printed for pointers, goroutine stacks and the like
+	pointerPattern = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	// uuidPattern matches UUIDs such as request and record identifiers
+	uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	// digitsPattern matches runs of digits such as IDs, counts and durations
+	digitsPattern = regexp.MustCompile(`[0-9]+`)
+)
+
+// EquivalentTo reports whether e and other describe the same failure: the same code, package and function, and the
+// same message once volatile parts are normalized. Line numbers, stack traces, fields and source line or pointer
+// references inside the message are ignored, so errors can be deduplicated across builds where lines shift. Two nil
+// errors are equivalent; a nil and a non-nil error are not.
+//
+// Example usage:
+//
+//	for _, seen := range known {
+//		if metaErr.EquivalentTo(seen) {
+//			return // already reported
+//		}
+//	}
+func (e *MetaError) EquivalentTo(other *MetaError) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	return e.code == other.code &&
+		e.Package == other.Package &&
+		e.Func == other.Func &&
+		normalizeMessage(e.Error()) == normalizeMessage(other.Error())
+}
+
+// normalizeMessage strips the parts of an error message that vary between builds and runs: source line numbers,
+// pointer addresses and runs of whitespace.
+func normalizeMessage(msg string) string {
+	msg = sourceLinePattern.ReplaceAllString(msg, "$1:_")
+	msg = pointerPattern.ReplaceAllString(msg, "0x_")
+	return strings.Join(strings.Fields(msg), " ")
+}
+
+// Fingerprint returns a stable 16 character hex hash of the package, function and message of e, with UUIDs and
+// digits stripped from the message along with the parts EquivalentTo ignores. Occurrences of the same logical
+// failure, such as "user 42 not found" and "user 97 not found" raised at the same site, share a fingerprint, so
+// error-tracking backends and in-process counters can group them. It returns an empty string for a nil e.
+//
+// Example usage:
+//
+//	counts[metaErr.Fingerprint()]++
+func (e *MetaError) Fingerprint() string {
+	if e == nil {
+		return ""
+	}
+
+	msg := uuidPattern.ReplaceAllString(e.Error(), "<uuid>")
+	msg = digitsPattern.ReplaceAllString(normalizeMessage(msg), "#")
+
+	h := sha256.New()
+	h.Write([]byte(e.Package))
+	h.Write([]byte{'|'})
+	h.Write([]byte(e.Func))
+	h.Write([]byte{'|'})
+	h.Write([]byte(msg))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// MetaErrorFormat holds text/template layouts that control how every MetaError is rendered by the fmt package.
+//
+// Short is used for %v and %s, Verbose is used for %+v. Templates receive a MetaErrorFields value, so a layout can
+// pick the fields and ordering a log pipeline expects:
+//
+//	err := app.SetMetaErrorFormat(app.MetaErrorFormat{
+//		Short:   "{{.Package}}.{{.Func}} {{.File}}:{{.Line}} {{.Message}}",
+//		Verbose: "{{.Message}} ({{.File}}:{{.Line}}){{.Stack}}",
+//	})
+//
+// An empty template leaves the default rendering in place for that verb.
+type MetaErrorFormat struct {
+	Short   string
+	Verbose string
+}
+
+// MetaErrorFields is the data passed to MetaError format templates.
+type MetaErrorFields struct {
+	Message string
+	File    string
+	Line    int
+	Func    string
+	Package string
+	Stack   string
+}
+
+var (
+	formatMu      sync.RWMutex
+	shortFormat   *template.Template
+	verboseFormat *template.Template
+)
+
+// SetMetaErrorFormat parses and installs the given templates globally. If either template fails to parse, the
+// current format is left unchanged and the parse error is returned.
+func SetMetaErrorFormat(f MetaErrorFormat) error {
+	var short, verbose *template.Template
+	var err error
+
+	if f.Short != "" {
+		short, err = template.New("metaErrorShort").Parse(f.Short)
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.Verbose != "" {
+		verbose, err = template.New("metaErrorVerbose").Parse(f.Verbose)
+		if err != nil {
+			return err
+		}
+	}
+
+	formatMu.Lock()
+	defer formatMu.Unlock()
+	shortFormat = short
+	verboseFormat = verbose
+	return nil
+}
+
+// ResetMetaErrorFormat removes any installed templates and restores the default MetaError rendering.
+func ResetMetaErrorFormat() {
+	formatMu.Lock()
+	defer formatMu.Unlock()
+	shortFormat = nil
+	verboseFormat = nil
+}
+
+// formatWithTemplate renders e, with msg as its message, using the installed template for the verbose or short
+// layout. It returns false when no template is installed or the template fails to execute, in which case the caller
+// falls back to the default output.
+func (e *MetaError) formatWithTemplate(verbose bool, msg string) (string, bool) {
+	formatMu.RLock()
+	tmpl := shortFormat
+	if verbose {
+		tmpl = verboseFormat
+	}
+	formatMu.RUnlock()
+
+	if tmpl == nil {
+		return "", false
+	}
+
+	fields := MetaErrorFields{
+		Message: msg,
+		File:    e.File,
+		Line:    e.Line,
+		Func:    e.Func,
+		Package: e.Package,
+	}
+	if verbose {
+		fields.Stack = e.StackTrace()
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, fields); err != nil {
+		return "", false
+	}
+	return sb.String(), true
+}
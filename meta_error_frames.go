@@ -0,0 +1,161 @@
+package app
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// StackOptions controls which frames StackTrace, %+v and MarshalJSON include. Frames always returns every frame.
+type StackOptions struct {
+	// SkipPackages drops frames whose package equals one of the entries or is nested under it, e.g. "runtime" also
+	// drops "runtime/debug"
+	SkipPackages []string
+	// MaxDepth caps the number of frames rendered after filtering when positive; the rest are summarized as
+	// "... N more frames"
+	MaxDepth int
+}
+
+var (
+	stackOptionsMu sync.RWMutex
+	stackOptions   StackOptions
+	// stackOptionsGen changes with every SetStackOptions call, invalidating cached StackTrace strings
+	stackOptionsGen atomic.Uint64
+)
+
+// SetStackOptions installs opts globally.
+//
+// Example usage:
+//
+//	app.SetStackOptions(app.StackOptions{
+//		SkipPackages: []string{"runtime", "testing", "github.com/mhpenta/app"},
+//		MaxDepth:     20,
+//	})
+func SetStackOptions(opts StackOptions) {
+	stackOptionsMu.Lock()
+	defer stackOptionsMu.Unlock()
+	stackOptions = StackOptions{
+		SkipPackages: append([]string(nil), opts.SkipPackages...),
+		MaxDepth:     opts.MaxDepth,
+	}
+	stackOptionsGen.Add(1)
+}
+
+// applyStackOptions filters and trims frames according to the installed StackOptions and returns the frames kept
+// and the number dropped by MaxDepth.
+func applyStackOptions(frames []Frame) ([]Frame, int) {
+	stackOptionsMu.RLock()
+	opts := stackOptions
+	stackOptionsMu.RUnlock()
+
+	if len(opts.SkipPackages) > 0 {
+		kept := frames[:0:0]
+		for _, frame := range frames {
+			if !skipPackage(frame.Package, opts.SkipPackages) {
+				kept = append(kept, frame)
+			}
+		}
+		frames = kept
+	}
+
+	if opts.MaxDepth > 0 && len(frames) > opts.MaxDepth {
+		return frames[:opts.MaxDepth], len(frames) - opts.MaxDepth
+	}
+	return frames, 0
+}
+
+func skipPackage(pkg string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Frame is one stack frame of a MetaError.
+type Frame struct {
+	// Function is the fully qualified function name, e.g. "github.com/org/pkg.(*Store).Get"
+	Function string
+	File     string
+	Line     int
+	Package  string
+	// Receiver is the receiver type name for methods, or the enclosing function for closures
+	Receiver string
+}
+
+// Frames returns the captured stack, innermost frame first, with package and receiver parsed from each function
+// name. It returns frames decoded by UnmarshalJSON when the error was not captured in this process, and nil when no
+// stack was captured. Callers can forward frames to error trackers or drop frames by package:
+//
+//	for _, frame := range metaErr.Frames() {
+//		if frame.Package == "runtime" || frame.Package == "testing" {
+//			continue
+//		}
+//		...
+//	}
+func (e *MetaError) Frames() []Frame {
+	if e == nil {
+		return nil
+	}
+
+	if len(e.stackTrace) == 0 {
+		if len(e.decodedStack) == 0 {
+			return nil
+		}
+		frames := make([]Frame, 0, len(e.decodedStack))
+		for _, f := range e.decodedStack {
+			frames = append(frames, newFrame(f.Func, f.File, f.Line))
+		}
+		return frames
+	}
+
+	if e.stack == nil {
+		return resolveFrames(e.stackTrace)
+	}
+	e.stack.once.Do(func() {
+		e.stack.frames = resolveFrames(e.stackTrace)
+	})
+	return append([]Frame(nil), e.stack.frames...)
+}
+
+// stackCache holds the symbolized frames and rendered StackTrace of a captured stack, so logging the same error
+// repeatedly resolves its program counters once. It is shared by copies of a MetaError, which carry the same stack.
+type stackCache struct {
+	once   sync.Once
+	frames []Frame
+
+	mu          sync.Mutex
+	rendered    string
+	renderedGen uint64
+	renderedOK  bool
+}
+
+// setStack records pcs as the captured stack of e, with a fresh cache.
+func (e *MetaError) setStack(pcs []uintptr) {
+	e.stackTrace = pcs
+	e.stack = &stackCache{}
+}
+
+func resolveFrames(pcs []uintptr) []Frame {
+	frames := make([]Frame, 0, len(pcs))
+	callers := runtime.CallersFrames(pcs)
+	for {
+		f, more := callers.Next()
+		frames = append(frames, newFrame(f.Function, f.File, f.Line))
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+func newFrame(function, file string, line int) Frame {
+	frame := Frame{Function: function, File: file, Line: line}
+
+	info := funcInfo(function)
+	frame.Package = info.PkgPath
+	frame.Receiver = info.Qualifier
+	return frame
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// metaErrorJSON is the JSON form of a MetaError.
+type metaErrorJSON struct {
+	Message  string                 `json:"message"`
+	File     string                 `json:"file"`
+	Line     int                    `json:"line"`
+	Func     string                 `json:"func"`
+	Package  string                 `json:"package"`
+	Receiver string                 `json:"receiver,omitempty"`
+	Code     string                 `json:"code,omitempty"`
+	Status   int                    `json:"httpStatus,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	Stack    []stackFrameJSON       `json:"stack,omitempty"`
+	Skipped  bool                   `json:"stackSkipped,omitempty"`
+}
+
+type stackFrameJSON struct {
+	Func string `json:"func"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+	URL  string `json:"url,omitempty"`
+}
+
+// MarshalJSON encodes the error as an object holding the message, capture location and, when captured, the stack
+// frames as an array, filtered and trimmed according to SetStackOptions, with files mapped by SetModuleRoot. Unlike ToCSV it is safe for messages containing pipes, quotes or newlines.
+func (e *MetaError) MarshalJSON() ([]byte, error) {
+	out := metaErrorJSON{
+		Message:  e.Error(),
+		File:     e.File,
+		Line:     e.Line,
+		Func:     e.Func,
+		Package:  e.Package,
+		Receiver: e.Receiver,
+		Code:     e.code,
+		Status:   e.httpStatus,
+		Fields:   e.Fields,
+		Skipped:  e.stackSkipped,
+	}
+
+	frames, _ := applyStackOptions(e.Frames())
+	for _, frame := range frames {
+		file, url := sourceLocation(frame)
+		out.Stack = append(out.Stack, stackFrameJSON{Func: frame.Function, File: file, Line: frame.Line, URL: url})
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes the object produced by MarshalJSON. The message becomes a plain error, and decoded stack
+// frames are returned by Frames and StackTrace.
+func (e *MetaError) UnmarshalJSON(data []byte) error {
+	var in metaErrorJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*e = MetaError{
+		Err:          errors.New(in.Message),
+		File:         in.File,
+		Line:         in.Line,
+		Func:         in.Func,
+		Package:      in.Package,
+		Receiver:     in.Receiver,
+		Fields:       in.Fields,
+		code:         in.Code,
+		httpStatus:   in.Status,
+		decodedStack: in.Stack,
+		stackSkipped: in.Skipped,
+	}
+
+	return nil
+}
+
+// ToJSON returns the JSON encoding of the error, see MarshalJSON.
+func (e *MetaError) ToJSON() string {
+	data, err := e.MarshalJSON()
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// FromJSON decodes a MetaError produced by ToJSON or MarshalJSON. It returns ErrNotMetaError if jsonStr is not a
+// JSON object with a message.
+func FromJSON(jsonStr string) (*MetaError, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(jsonStr), &fields); err != nil {
+		return nil, ErrNotMetaError
+	}
+	if _, ok := fields["message"]; !ok {
+		return nil, ErrNotMetaError
+	}
+
+	metaErr := &MetaError{}
+	if err := metaErr.UnmarshalJSON([]byte(jsonStr)); err != nil {
+		return nil, ErrNotMetaError
+	}
+	return metaErr, nil
+}
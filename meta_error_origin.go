@@ -0,0 +1,106 @@
+package app
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+)
+
+// callersError is implemented by errors that record the stack where they were created, as returned by
+// runtime.Callers.
+type callersError interface {
+	Callers() []uintptr
+}
+
+// NewMetaErrorAt creates a MetaError whose File, Line and Func describe pc instead of the caller, for failures
+// detected away from where they originated. pc is a program counter as returned by runtime.Caller or an element of
+// runtime.Callers. The stack is still captured at the call to NewMetaErrorAt.
+//
+// Example usage:
+//
+//	pc, _, _, _ := runtime.Caller(0)
+//	jobs <- job{pc: pc, run: run}
+//	...
+//	if err := j.run(); err != nil {
+//		return app.NewMetaErrorAt(err, j.pc)
+//	}
+func NewMetaErrorAt(err error, pc uintptr) *MetaError {
+	metaErr := NewMetaErrorOptions(err, 2, true, true)
+	metaErr.setLocation(pc)
+	return metaErr
+}
+
+// CaptureFromError creates a MetaError that reports where err originated rather than where it is being wrapped,
+// which is often a generic handler. The site is taken from, in order:
+//
+//   - a MetaError inside err, whose location, stack, code and fields are kept
+//   - an error implementing Callers() []uintptr, whose innermost frame becomes the location and whose stack is used
+//   - the caller's stack, using the first frame outside the packages listed in StackOptions.SkipPackages, so
+//     registering a handler package there attributes errors to the code that called into it
+//
+// The returned error's message is err's message. CaptureFromError returns nil for a nil err.
+func CaptureFromError(err error) *MetaError {
+	if err == nil {
+		return nil
+	}
+
+	var origin *MetaError
+	if errors.As(err, &origin) {
+		if origin == err {
+			return origin
+		}
+		metaErr := *origin
+		metaErr.Err = err
+		if origin.Fields != nil {
+			metaErr.Fields = make(map[string]interface{}, len(origin.Fields))
+			for k, v := range origin.Fields {
+				metaErr.Fields[k] = v
+			}
+		}
+		return &metaErr
+	}
+
+	metaErr := NewMetaErrorOptions(err, 2, true, true)
+
+	var withCallers callersError
+	if errors.As(err, &withCallers) {
+		if pcs := withCallers.Callers(); len(pcs) > 0 {
+			metaErr.setStack(append([]uintptr(nil), pcs...))
+			metaErr.setLocation(pcs[0])
+			return metaErr
+		}
+	}
+
+	stackOptionsMu.RLock()
+	skip := stackOptions.SkipPackages
+	stackOptionsMu.RUnlock()
+	if len(skip) == 0 {
+		return metaErr
+	}
+
+	callers := runtime.CallersFrames(metaErr.stackTrace)
+	for {
+		f, more := callers.Next()
+		if !skipPackage(newFrame(f.Function, f.File, f.Line).Package, skip) {
+			metaErr.setLocation(f.PC + 1)
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	return metaErr
+}
+
+// setLocation replaces the capture site of e with the location of pc.
+func (e *MetaError) setLocation(pc uintptr) {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.Function == "" {
+		return
+	}
+
+	e.File = filepath.Base(frame.File)
+	e.Line = frame.Line
+	e.Receiver, e.ReceiverPtr, e.TypeGeneric, e.FuncGeneric = "", false, "", ""
+	e.setFuncName(frame.Function)
+}
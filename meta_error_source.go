@@ -0,0 +1,111 @@
+package app
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// moduleRoot maps the files of one module to repository paths and URLs.
+type moduleRoot struct {
+	module string
+	dir    string
+	url    string
+}
+
+var (
+	moduleRootsMu sync.RWMutex
+	// moduleRoots is sorted by descending module path length, so nested modules match before their parents
+	moduleRoots []moduleRoot
+)
+
+// SetModuleRoot registers where the files of module live in its repository, as a directory relative to the
+// repository root ("" or "." for a module at the root). Builds made with -trimpath record frame files as
+// "module/pkg/file.go", or "module@version/pkg/file.go" for dependencies; StackTrace, %+v and MarshalJSON rewrite
+// such files into repository-relative paths like "dir/pkg/file.go", which editors and terminals can open.
+//
+// Example usage:
+//
+//	app.SetModuleRoot("github.com/mhpenta/app", ".")
+//	app.SetModuleURL("github.com/mhpenta/app", "https://github.com/mhpenta/app/blob/"+commit+"/{path}#L{line}")
+func SetModuleRoot(module string, dir string) {
+	dir = path.Clean(strings.TrimPrefix(dir, "./"))
+	if dir == "." {
+		dir = ""
+	}
+	updateModuleRoot(module, func(root *moduleRoot) {
+		root.dir = dir
+	})
+}
+
+// SetModuleURL registers a source URL template for the files of module, in which "{path}" is replaced by the
+// repository-relative path (see SetModuleRoot) and "{line}" by the line number. StackTrace and %+v then print the URL
+// in place of the file and line, and MarshalJSON adds it to each frame as "url". An empty template removes it.
+func SetModuleURL(module string, template string) {
+	updateModuleRoot(module, func(root *moduleRoot) {
+		root.url = template
+	})
+}
+
+func updateModuleRoot(module string, update func(root *moduleRoot)) {
+	module = strings.TrimSuffix(module, "/")
+
+	moduleRootsMu.Lock()
+	defer moduleRootsMu.Unlock()
+
+	for i := range moduleRoots {
+		if moduleRoots[i].module == module {
+			update(&moduleRoots[i])
+			stackOptionsGen.Add(1)
+			return
+		}
+	}
+
+	root := moduleRoot{module: module}
+	update(&root)
+	moduleRoots = append(moduleRoots, root)
+	sort.SliceStable(moduleRoots, func(i, j int) bool {
+		return len(moduleRoots[i].module) > len(moduleRoots[j].module)
+	})
+	stackOptionsGen.Add(1)
+}
+
+// sourceLocation returns the repository-relative file of frame and its source URL when the file belongs to a module
+// registered with SetModuleRoot or SetModuleURL. Other files are returned unchanged with an empty URL.
+func sourceLocation(frame Frame) (file string, url string) {
+	moduleRootsMu.RLock()
+	defer moduleRootsMu.RUnlock()
+
+	for _, root := range moduleRoots {
+		rel, ok := moduleRelative(frame.File, root.module)
+		if !ok {
+			continue
+		}
+
+		file = path.Join(root.dir, rel)
+		if root.url != "" {
+			url = strings.NewReplacer("{path}", file, "{line}", strconv.Itoa(frame.Line)).Replace(root.url)
+		}
+		return file, url
+	}
+	return frame.File, ""
+}
+
+// moduleRelative returns the path of file within module for files recorded by -trimpath builds.
+func moduleRelative(file string, module string) (string, bool) {
+	rest, ok := strings.CutPrefix(file, module)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case strings.HasPrefix(rest, "/"):
+		return rest[1:], true
+	case strings.HasPrefix(rest, "@"):
+		if i := strings.Index(rest, "/"); i != -1 {
+			return rest[i+1:], true
+		}
+	}
+	return "", false
+}
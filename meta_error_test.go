@@ -1,11 +1,16 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestMetaErrorBasic tests the basic functionality of MetaError.
@@ -227,12 +232,765 @@ func TestMetaErrorFuncName(t *testing.T) {
 		t.Error("Expected package name to be github.com/mhpenta/app, received: ", err.Package)
 	}
 
-	pkgPath, recvName, recvPtr, typeGeneric, funcGeneric, funcNameSmall, notice := parseFuncName("modeledge-go/internal/routes.RegisterToolRoutes.FakeError.func1")
-	slog.Info("funcname", "pkgPath", pkgPath, "recvName", recvName, "recvPtr", recvPtr, "typeGeneric", typeGeneric, "funcGeneric", funcGeneric, "funcNameSmall", funcNameSmall)
-	slog.Info("funcname", "notice", notice)
+	if err.Receiver != "" {
+		t.Error("Expected empty receiver for a package level function, received: ", err.Receiver)
+	}
+}
+
+type metaErrorTestReceiver struct{}
+
+func (r *metaErrorTestReceiver) fail() *MetaError {
+	return NewMetaError(errors.New("base error"))
+}
+
+type metaErrorTestGeneric[T any] struct{}
+
+func (g metaErrorTestGeneric[T]) fail() *MetaError {
+	return NewMetaError(errors.New("base error"))
+}
+
+// TestMetaErrorMethodCaller tests that methods report the package, receiver and function separately.
+func TestMetaErrorMethodCaller(t *testing.T) {
+	err := (&metaErrorTestReceiver{}).fail()
+
+	if err.Package != "github.com/mhpenta/app" {
+		t.Error("Expected package name to be github.com/mhpenta/app, received: ", err.Package)
+	}
+
+	if err.Func != "fail" {
+		t.Error("Expected function name to be fail, received: ", err.Func)
+	}
+
+	if err.Receiver != "metaErrorTestReceiver" || !err.ReceiverPtr {
+		t.Error("Expected pointer receiver metaErrorTestReceiver, received: ", err.Receiver, err.ReceiverPtr)
+	}
 
-	pkgPath, recvName, recvPtr, typeGeneric, funcGeneric, funcNameSmall, notice = parseFuncName("modeledge-go/internal/routes.RegisterToolRoutes.FakeError.func1")
-	slog.Info("funcname", "pkgPath", pkgPath, "recvName", recvName, "recvPtr", recvPtr, "typeGeneric", typeGeneric, "funcGeneric", funcGeneric, "funcNameSmall", funcNameSmall)
-	slog.Info("funcname", "notice", notice)
+	err = metaErrorTestGeneric[int]{}.fail()
+
+	if err.Package != "github.com/mhpenta/app" {
+		t.Error("Expected package name to be github.com/mhpenta/app, received: ", err.Package)
+	}
+
+	if err.Func != "fail" {
+		t.Error("Expected function name to be fail, received: ", err.Func)
+	}
+
+	if err.Receiver != "metaErrorTestGeneric" || err.ReceiverPtr {
+		t.Error("Expected value receiver metaErrorTestGeneric, received: ", err.Receiver, err.ReceiverPtr)
+	}
+
+	if err.TypeGeneric == "" {
+		t.Error("Expected type generic to be set")
+	}
+}
+
+// TestMetaErrorFormatTemplate tests that installed format templates control fmt output.
+func TestMetaErrorFormatTemplate(t *testing.T) {
+	defer ResetMetaErrorFormat()
+
+	err := NewMetaError(errors.New("base error"))
+
+	if setErr := SetMetaErrorFormat(MetaErrorFormat{
+		Short:   "{{.Func}}|{{.Message}}",
+		Verbose: "verbose {{.Message}} {{.Line}}",
+	}); setErr != nil {
+		t.Fatalf("Expected templates to parse, got %v", setErr)
+	}
+
+	if got := fmt.Sprintf("%v", err); got != "TestMetaErrorFormatTemplate|base error" {
+		t.Errorf("Unexpected %%v output: %s", got)
+	}
 
+	if got := fmt.Sprintf("%s", err); got != "TestMetaErrorFormatTemplate|base error" {
+		t.Errorf("Unexpected %%s output: %s", got)
+	}
+
+	if got := fmt.Sprintf("%+v", err); got != fmt.Sprintf("verbose base error %d", err.Line) {
+		t.Errorf("Unexpected %%+v output: %s", got)
+	}
+
+	if setErr := SetMetaErrorFormat(MetaErrorFormat{Short: "{{.Message"}); setErr == nil {
+		t.Error("Expected parse error for malformed template")
+	}
+
+	ResetMetaErrorFormat()
+	if got := fmt.Sprintf("%v", err); got != err.ToCSV() {
+		t.Errorf("Expected default CSV output after reset, got %s", got)
+	}
+}
+
+// TestFromPanic tests that a recovered panic points at the panicking function.
+func TestFromPanic(t *testing.T) {
+	var err *MetaError
+	func() {
+		defer func() {
+			err = FromPanic(recover())
+		}()
+		panic("boom")
+	}()
+
+	if err == nil {
+		t.Fatal("Expected MetaError from recovered panic")
+	}
+
+	if !errors.Is(err, ErrPanic) {
+		t.Error("Expected errors.Is to match ErrPanic")
+	}
+
+	if err.Error() != "panic: boom" {
+		t.Errorf("Unexpected message: %s", err.Error())
+	}
+
+	if err.Func != "TestFromPanic" {
+		t.Error("Expected function name to be TestFromPanic, received: ", err.Func)
+	}
+
+	if FromPanic(nil) != nil {
+		t.Error("Expected nil for nil recovered value")
+	}
+
+	// A runtime error panics through runtime.sigpanic and runtime.panicmem, which must not become the capture site.
+	var nilMap map[string]int
+	func() {
+		defer func() {
+			err = FromPanic(recover())
+		}()
+		nilMap["boom"] = 1
+	}()
+	if err.Func != "TestFromPanic" || err.Package != "github.com/mhpenta/app" {
+		t.Errorf("Expected the runtime panic to point at TestFromPanic, received %s.%s", err.Package, err.Func)
+	}
+}
+
+// TestMetaErrorFormatter tests fmt output across verbs, flags, width and precision.
+func TestMetaErrorFormatter(t *testing.T) {
+	csvErr := NewMetaErrorOptions(errors.New("connection refused"), 1, true, true)
+	textErr := NewMetaErrorOptions(errors.New("connection refused"), 1, true, false)
+	site := fmt.Sprintf("\n\tat %s:%d (%s) [package: %s]", textErr.File, textErr.Line, textErr.Func, textErr.Package)
+	var nilErr *MetaError
+
+	tests := []struct {
+		name   string
+		format string
+		err    *MetaError
+		want   string
+	}{
+		{"csv %v", "%v", csvErr, csvErr.ToCSV()},
+		{"csv %s", "%s", csvErr, csvErr.ToCSV()},
+		{"csv %+v", "%+v", csvErr, csvErr.ToCSV() + "|" + csvErr.StackTrace()},
+		{"csv precision", "%.10s", csvErr, strings.Replace(csvErr.ToCSV(), "connection refused", "connection", 1)},
+		{"text %v", "%v", textErr, "connection refused" + site},
+		{"text %s", "%s", textErr, "connection refused" + site},
+		{"text %q", "%q", textErr, `"connection refused"` + site},
+		{"text %+v", "%+v", textErr, "connection refused" + site + textErr.StackTrace()},
+		{"text precision", "%.4v", textErr, "conn" + site},
+		{"text precision zero", "%.0s", textErr, site},
+		{"text precision longer than message", "%.100s", textErr, "connection refused" + site},
+		{"nil %s", "%s", nilErr, "<nil>"},
+		{"nil %v", "%v", nilErr, "<nil>"},
+		{"nil %+v", "%+v", nilErr, "<nil>"},
+		{"nil %#v", "%#v", nilErr, "<nil>"},
+		{"nil width", "%7s", nilErr, "  <nil>"},
+		{"nil width left", "%-7s|", nilErr, "<nil>  |"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprintf(tt.format, tt.err); got != tt.want {
+				t.Errorf("Sprintf(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("width pads whole output", func(t *testing.T) {
+		plain := fmt.Sprintf("%s", textErr)
+		got := fmt.Sprintf("%*s", len(plain)+3, textErr)
+		if got != "   "+plain {
+			t.Errorf("Expected output padded by 3 spaces, got %q", got)
+		}
+	})
+
+	t.Run("go syntax", func(t *testing.T) {
+		got := fmt.Sprintf("%#v", textErr)
+		for _, want := range []string{"&app.MetaError{", `File:"` + textErr.File + `"`, fmt.Sprintf("Line:%d", textErr.Line), `Func:"TestMetaErrorFormatter"`} {
+			if !strings.Contains(got, want) {
+				t.Errorf("Expected %%#v output to contain %q, got %q", want, got)
+			}
+		}
+	})
+
+	t.Run("nil error method", func(t *testing.T) {
+		if nilErr.Error() != "<nil>" {
+			t.Errorf("Expected nil MetaError Error() to be <nil>, got %q", nilErr.Error())
+		}
+	})
+}
+
+// TestMarkRetried tests annotating errors that survived a retry policy
+func TestMarkRetried(t *testing.T) {
+	base := errors.New("upstream unavailable")
+
+	if IsRetried(base) {
+		t.Error("Expected a plain error not to be marked as retried")
+	}
+	if MarkRetried(nil, 3) != nil {
+		t.Error("Expected MarkRetried(nil) to return nil")
+	}
+
+	err := fmt.Errorf("sync: %w", MarkRetried(NewMetaError(base), 3))
+	if !IsRetried(err) {
+		t.Error("Expected wrapped retried error to be detected")
+	}
+	if attempts, _ := RetryAttempts(err); attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if !errors.Is(err, base) || !strings.HasPrefix(err.Error(), "sync: upstream unavailable") {
+		t.Errorf("Expected message and chain unchanged, got '%s'", err.Error())
+	}
+}
+
+// TestMetaErrorJSON tests that a MetaError round-trips through JSON, including messages that break CSV
+func TestMetaErrorJSON(t *testing.T) {
+	original := NewMetaError(errors.New("bad | value\nwith \"quotes\""))
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	decoded, err := FromJSON(string(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded.Error() != original.Error() || decoded.Line != original.Line || decoded.Func != "TestMetaErrorJSON" {
+		t.Errorf("Expected round trip to keep fields, got %#v", decoded)
+	}
+	if decoded.StackTrace() == "" || decoded.StackTrace() != original.StackTrace() {
+		t.Error("Expected decoded stack trace to match the original")
+	}
+
+	var slogRecord map[string]interface{}
+	_ = json.Unmarshal([]byte(`{"msg":"failed","err":`+string(data)+`}`), &slogRecord)
+	fromSlog, err := FromSlogMap(slogRecord)
+	if err != nil || fromSlog.File != original.File {
+		t.Errorf("Expected FromSlogMap to decode the JSON object, got %v", err)
+	}
+
+	if _, err := FromJSON("not json"); !errors.Is(err, ErrNotMetaError) {
+		t.Errorf("Expected ErrNotMetaError, got %v", err)
+	}
+}
+
+// TestMetaErrorCode tests error codes and their survival through CSV and JSON
+func TestMetaErrorCode(t *testing.T) {
+	metaErr := NewMetaError(errors.New("query timed out")).WithCode("DB_TIMEOUT")
+	wrapped := fmt.Errorf("load user: %w", metaErr)
+
+	if metaErr.Code() != "DB_TIMEOUT" || !HasCode(wrapped, "DB_TIMEOUT") || HasCode(wrapped, "OTHER") {
+		t.Error("Expected HasCode to find the code through wrapping")
+	}
+	if CodeOf(NewMultiError(errors.New("plain"), wrapped)) != "DB_TIMEOUT" {
+		t.Error("Expected CodeOf to search MultiError entries")
+	}
+
+	fromCSV, err := MetaErrorFromCSV(metaErr.ToCSV())
+	if err != nil || fromCSV.Code() != "DB_TIMEOUT" {
+		t.Errorf("Expected code to survive CSV, got %q (%v)", fromCSV.Code(), err)
+	}
+
+	fromJSON, err := FromJSON(metaErr.ToJSON())
+	if err != nil || fromJSON.Code() != "DB_TIMEOUT" {
+		t.Errorf("Expected code to survive JSON, got %q (%v)", fromJSON.Code(), err)
+	}
+}
+
+// TestMetaErrorFromCSVLayouts tests decoding every CSV layout, including records carrying a stack trace
+func TestMetaErrorFromCSVLayouts(t *testing.T) {
+	plain := NewMetaErrorOptions(errors.New("disk full"), 1, true, true)
+	coded := NewMetaErrorOptions(errors.New("disk full"), 1, true, true).WithCode("FS_FULL")
+
+	for _, tt := range []struct {
+		name  string
+		src   *MetaError
+		csv   string
+		stack bool
+	}{
+		{"five fields", plain, plain.ToCSV(), false},
+		{"code", coded, coded.ToCSV(), false},
+		{"stack", plain, fmt.Sprintf("%+v", plain), true},
+		{"code and stack", coded, fmt.Sprintf("%+v", coded), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			metaErr, err := MetaErrorFromCSV(tt.csv)
+			if err != nil {
+				t.Fatalf("Expected %q to decode, got %v", tt.csv, err)
+			}
+			if metaErr.Error() != "disk full" || metaErr.Line != tt.src.Line || metaErr.Code() != tt.src.Code() {
+				t.Errorf("Expected message, line and code %q, got %#v", tt.src.Code(), metaErr)
+			}
+			if tt.stack && metaErr.StackTrace() != tt.src.StackTrace() {
+				t.Errorf("Expected the stack trace to be restored, got %q, want %q", metaErr.StackTrace(), tt.src.StackTrace())
+			}
+		})
+	}
+}
+
+// TestMetaErrorFromCSVLenient tests partial results for damaged records
+func TestMetaErrorFromCSVLenient(t *testing.T) {
+	metaErr, err := MetaErrorFromCSVLenient("disk full|store.go|forty|Save")
+	if !errors.Is(err, ErrPartialMetaError) || metaErr == nil {
+		t.Fatalf("Expected a partial result, got %v, %v", metaErr, err)
+	}
+	if metaErr.Error() != "disk full" || metaErr.File != "store.go" || metaErr.Func != "Save" || metaErr.Line != 0 {
+		t.Errorf("Expected the readable fields, got %#v", metaErr)
+	}
+	if _, err := MetaErrorFromCSV("disk full|store.go|forty|Save"); !errors.Is(err, ErrNotMetaError) {
+		t.Error("Expected the strict parser to reject the record")
+	}
+
+	if _, err := MetaErrorFromCSVLenient("a|b.go|1|f|pkg|CODE|\nnot a frame"); !errors.Is(err, ErrPartialMetaError) {
+		t.Errorf("Expected unreadable stack lines to be reported, got %v", err)
+	}
+	if _, err := MetaErrorFromCSVLenient(""); !errors.Is(err, ErrNotMetaError) {
+		t.Errorf("Expected ErrNotMetaError for an empty record, got %v", err)
+	}
+}
+
+// TestWrap tests that Wrap builds a cause chain and keeps the original capture site
+func TestWrap(t *testing.T) {
+	if Wrap(nil, "ignored") != nil {
+		t.Error("Expected Wrap(nil) to return nil")
+	}
+
+	base := errors.New("permission denied")
+	origin := NewMetaError(fmt.Errorf("read file: %w", base)).WithCode("FS")
+
+	wrapped := Wrapf(origin, "load %s", "config")
+	if wrapped.Error() != "load config: read file: permission denied" {
+		t.Errorf("Expected cause chain, got '%s'", wrapped.Error())
+	}
+	if wrapped.Line != origin.Line || wrapped.StackTrace() != origin.StackTrace() || wrapped.Code() != "FS" {
+		t.Error("Expected Wrap to keep the original capture site, stack and code")
+	}
+	if !errors.Is(wrapped, base) {
+		t.Error("Expected errors.Is to reach the root cause")
+	}
+
+	plain := Wrap(base, "open")
+	if plain.Func != "TestWrap" {
+		t.Errorf("Expected Wrap of a plain error to capture the caller, got %q", plain.Func)
+	}
+}
+
+// TestMetaErrorFrames tests the structured stack frames
+func TestMetaErrorFrames(t *testing.T) {
+	metaErr := NewMetaError(errors.New("boom"))
+
+	frames := metaErr.Frames()
+	if len(frames) == 0 {
+		t.Fatal("Expected captured frames")
+	}
+	if frames[0].Package != "github.com/mhpenta/app" || !strings.HasSuffix(frames[0].Function, "TestMetaErrorFrames") {
+		t.Errorf("Expected the first frame to be the test, got %+v", frames[0])
+	}
+	if frames[0].Line != metaErr.Line {
+		t.Errorf("Expected line %d, got %d", metaErr.Line, frames[0].Line)
+	}
+
+	decoded, _ := FromJSON(metaErr.ToJSON())
+	if len(decoded.Frames()) != len(frames) || decoded.Frames()[0] != frames[0] {
+		t.Error("Expected decoded frames to match the captured ones")
+	}
+	if (&MetaError{Err: errors.New("no stack")}).Frames() != nil {
+		t.Error("Expected nil frames without a captured stack")
+	}
+}
+
+// TestStackOptions tests filtering and trimming of rendered stack traces
+func TestStackOptions(t *testing.T) {
+	defer SetStackOptions(StackOptions{})
+
+	metaErr := NewMetaError(errors.New("boom"))
+
+	SetStackOptions(StackOptions{SkipPackages: []string{"testing", "runtime"}})
+	if strings.Contains(metaErr.StackTrace(), "testing.tRunner") || strings.Contains(metaErr.StackTrace(), "runtime.goexit") {
+		t.Errorf("Expected testing and runtime frames to be skipped, got %s", metaErr.StackTrace())
+	}
+
+	SetStackOptions(StackOptions{MaxDepth: 1})
+	trace := metaErr.StackTrace()
+	if strings.Count(trace, "\n\t") != 2 || !strings.Contains(trace, "more frames") {
+		t.Errorf("Expected one frame and a summary, got %s", trace)
+	}
+
+	if len(metaErr.Frames()) < 2 {
+		t.Error("Expected Frames to ignore StackOptions")
+	}
+}
+
+// TestNewMetaErrorCtx tests copying request identifiers from the context into fields
+func TestNewMetaErrorCtx(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1\nforged")
+	ctx = WithTraceID(ctx, "trace-9")
+
+	metaErr := NewMetaErrorCtx(ctx, errors.New("boom"))
+	if metaErr.Fields["requestId"] != "req-1forged" || metaErr.Fields["traceId"] != "trace-9" {
+		t.Errorf("Expected sanitized request and trace IDs, got %v", metaErr.Fields)
+	}
+	if _, ok := metaErr.Fields["user"]; ok {
+		t.Error("Expected no user field when the context has none")
+	}
+	if metaErr.Func != "TestNewMetaErrorCtx" {
+		t.Errorf("Expected the caller to be captured, got %q", metaErr.Func)
+	}
+	if NewMetaErrorCtx(ctx, nil) != nil {
+		t.Error("Expected nil for a nil error")
+	}
+}
+
+// TestRegisterContextExtractor tests attaching custom context values and replacing built-in extractors
+func TestRegisterContextExtractor(t *testing.T) {
+	type tenantKey struct{}
+	RegisterContextExtractor("tenant", func(ctx context.Context) (interface{}, bool) {
+		tenant, ok := ctx.Value(tenantKey{}).(string)
+		return tenant, ok
+	})
+	RegisterContextExtractor("user", nil)
+	defer func() {
+		RegisterContextExtractor("tenant", nil)
+		RegisterContextExtractor("user", stringExtractor(userKey))
+	}()
+
+	ctx := WithUser(context.WithValue(context.Background(), tenantKey{}, "acme"), "u-7")
+	metaErr := NewMetaErrorCtx(ctx, errors.New("boom"))
+	if metaErr.Fields["tenant"] != "acme" {
+		t.Errorf("Expected the registered extractor's field, got %v", metaErr.Fields)
+	}
+	if _, ok := metaErr.Fields["user"]; ok {
+		t.Error("Expected the removed user extractor not to run")
+	}
+}
+
+// TestRegisterContextExtractorConcurrent tests replacing extractors while errors are being built, run with -race
+func TestRegisterContextExtractorConcurrent(t *testing.T) {
+	defer RegisterContextExtractor("tenant", nil)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 2000; i++ {
+			tenant := fmt.Sprintf("t-%d", i)
+			RegisterContextExtractor("tenant", func(ctx context.Context) (interface{}, bool) {
+				return tenant, true
+			})
+			RegisterContextExtractor("requestId", stringExtractor(requestIDKey))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 2000; i++ {
+			if metaErr := NewMetaErrorCtx(ctx, errors.New("boom")); metaErr.Fields["requestId"] != "req-1" {
+				t.Errorf("Expected the request ID field, got %v", metaErr.Fields)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+// TestMetaErrorLogValue tests structured slog output and reading it back with FromSlogMap
+func TestMetaErrorLogValue(t *testing.T) {
+	metaErr := NewMetaError(errors.New("sync failed")).WithCode("SYNC").WithField("shard", 3)
+
+	var buf strings.Builder
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.Error("failed", "err", metaErr)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %s", buf.String())
+	}
+	group, ok := record["err"].(map[string]interface{})
+	if !ok || group["func"] != "TestMetaErrorLogValue" || group["code"] != "SYNC" {
+		t.Errorf("Expected structured error attributes, got %s", buf.String())
+	}
+
+	decoded, err := FromSlogMap(record)
+	if err != nil || decoded.Line != metaErr.Line || decoded.Code() != "SYNC" || decoded.Fields["shard"] != float64(3) {
+		t.Errorf("Expected FromSlogMap to read the group back, got %#v (%v)", decoded, err)
+	}
+}
+
+// TestRetryabilityFlags tests MarkRetryable, MarkPermanent and IsRetryable through wrapping
+func TestRetryabilityFlags(t *testing.T) {
+	if _, marked := IsRetryable(errors.New("plain")); marked {
+		t.Error("Expected plain error to be unmarked")
+	}
+
+	permanent := MarkPermanent(errors.New("invalid input"))
+	if permanent.Func != "TestRetryabilityFlags" {
+		t.Errorf("Expected caller location, got %s", permanent.Func)
+	}
+	if retryable, marked := IsRetryable(fmt.Errorf("handler: %w", Wrap(permanent, "validate"))); !marked || retryable {
+		t.Error("Expected wrapped permanent error to stay permanent")
+	}
+
+	if retryable, marked := IsRetryable(MarkRetryable(permanent)); !marked || !retryable {
+		t.Error("Expected MarkRetryable to override the flag in place")
+	}
+
+	if MarkRetryable(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+}
+
+// TestHTTPStatus tests that an HTTP status survives wrapping and JSON round-trips
+func TestHTTPStatus(t *testing.T) {
+	if _, ok := HTTPStatus(errors.New("plain")); ok {
+		t.Error("Expected no status for a plain error")
+	}
+
+	notFound := NewMetaError(errors.New("filing not found")).WithHTTPStatus(404)
+	if status, ok := HTTPStatus(fmt.Errorf("handler: %w", Wrap(notFound, "load filing"))); !ok || status != 404 {
+		t.Errorf("Expected 404 through wrapping, got %d", status)
+	}
+
+	decoded, err := FromJSON(notFound.ToJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, ok := HTTPStatus(decoded); !ok || status != 404 {
+		t.Errorf("Expected 404 after JSON round-trip, got %d", status)
+	}
+}
+
+type callersTestError struct {
+	pcs []uintptr
+}
+
+func (e *callersTestError) Error() string      { return "remote failure" }
+func (e *callersTestError) Callers() []uintptr { return e.pcs }
+
+func newCallersTestError() error {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(1, pcs)
+	return &callersTestError{pcs: pcs[:n]}
+}
+
+// TestCaptureFromError tests that the reported site is where the error originated, not where it was wrapped
+func TestCaptureFromError(t *testing.T) {
+	origin := NewMetaError(errors.New("disk full"))
+	wrapped := fmt.Errorf("handler: %w", origin)
+	captured := CaptureFromError(wrapped)
+	if captured.Line != origin.Line || captured.Error() != wrapped.Error() {
+		t.Errorf("Expected origin line %d and wrapped message, got %d %q", origin.Line, captured.Line, captured.Error())
+	}
+
+	captured = CaptureFromError(newCallersTestError())
+	if captured.Func != "newCallersTestError" {
+		t.Errorf("Expected location from Callers(), got %s", captured.Func)
+	}
+
+	pc, _, line, _ := runtime.Caller(0)
+	at := NewMetaErrorAt(errors.New("late"), pc)
+	if at.Line != line || at.Func != "TestCaptureFromError" {
+		t.Errorf("Expected line %d in TestCaptureFromError, got %d in %s", line, at.Line, at.Func)
+	}
+
+	if CaptureFromError(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+}
+
+// TestNewMetaErrorLite tests that the lite constructor keeps the caller location and caps the stack
+func TestNewMetaErrorLite(t *testing.T) {
+	metaErr := NewMetaErrorLite(errors.New("parse failed"))
+	if metaErr.Func != "TestNewMetaErrorLite" {
+		t.Errorf("Expected caller location, got %s", metaErr.Func)
+	}
+	frames := metaErr.Frames()
+	if len(frames) == 0 || len(frames) > LiteStackDepth || frames[0].Function != "github.com/mhpenta/app.TestNewMetaErrorLite" {
+		t.Errorf("Expected at most %d frames starting at the caller, got %d", LiteStackDepth, len(frames))
+	}
+}
+
+// TestStackBudget tests that stacks are skipped and flagged once the per-second budget is spent, and captured again in
+// the next second
+func TestStackBudget(t *testing.T) {
+	clock := TestMode(t)
+	SetStackBudget(StackBudget{CapturesPerSecond: 2})
+	defer SetStackBudget(StackBudget{})
+
+	skippedBefore := SkippedStacks()
+	var errs []*MetaError
+	for i := 0; i < 3; i++ {
+		errs = append(errs, NewMetaError(errors.New("hot path")))
+	}
+	if errs[1].StackSkipped() || !errs[2].StackSkipped() || len(errs[2].Frames()) != 0 {
+		t.Fatalf("Expected the third error to skip its stack, got skipped=%v frames=%d", errs[2].StackSkipped(), len(errs[2].Frames()))
+	}
+	if errs[2].Func != "TestStackBudget" || SkippedStacks() != skippedBefore+1 {
+		t.Errorf("Expected the location to be kept and one skip counted, got %s, %d", errs[2].Func, SkippedStacks()-skippedBefore)
+	}
+	if !strings.Contains(errs[2].ToJSON(), `"stackSkipped":true`) {
+		t.Errorf("Expected the flag in JSON, got %s", errs[2].ToJSON())
+	}
+
+	clock.Advance(time.Second)
+	if NewMetaError(errors.New("hot path")).StackSkipped() {
+		t.Error("Expected the budget to reset in the next second")
+	}
+}
+
+// TestModuleRoot tests rewriting -trimpath frame files into repository paths and source URLs
+func TestModuleRoot(t *testing.T) {
+	defer func() {
+		moduleRootsMu.Lock()
+		moduleRoots = nil
+		moduleRootsMu.Unlock()
+	}()
+
+	var metaErr MetaError
+	data := `{"message":"sync failed","stack":[` +
+		`{"func":"github.com/mhpenta/app/retry.runLoop","file":"github.com/mhpenta/app@v1.2.0/retry/loop.go","line":42},` +
+		`{"func":"main.main","file":"example.com/svc/cmd/main.go","line":7}]}`
+	if err := json.Unmarshal([]byte(data), &metaErr); err != nil {
+		t.Fatal(err)
+	}
+
+	SetModuleRoot("github.com/mhpenta/app", "./lib/app")
+	SetModuleRoot("example.com/svc", ".")
+	if trace := metaErr.StackTrace(); !strings.Contains(trace, "\tlib/app/retry/loop.go:42") || !strings.Contains(trace, "\tcmd/main.go:7") {
+		t.Errorf("Expected repository-relative files, got %s", trace)
+	}
+
+	SetModuleURL("github.com/mhpenta/app", "https://github.com/mhpenta/app/blob/main/{path}#L{line}")
+	const url = "https://github.com/mhpenta/app/blob/main/lib/app/retry/loop.go#L42"
+	if trace := metaErr.StackTrace(); !strings.Contains(trace, "\t"+url+"\n") {
+		t.Errorf("Expected the source URL in the stack trace, got %s", trace)
+	}
+	if out := metaErr.ToJSON(); !strings.Contains(out, `"file":"lib/app/retry/loop.go","line":42,"url":"`+url+`"`) {
+		t.Errorf("Expected the file and URL in JSON, got %s", out)
+	}
+}
+
+func BenchmarkNewMetaError(b *testing.B) {
+	err := errors.New("benchmark")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewMetaError(err)
+	}
+}
+
+func BenchmarkNewMetaErrorLite(b *testing.B) {
+	err := errors.New("benchmark")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewMetaErrorLite(err)
+	}
+}
+
+func BenchmarkNewMetaErrorNoStack(b *testing.B) {
+	err := errors.New("benchmark")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewMetaErrorOptions(err, 2, false, true)
+	}
+}
+
+// TestAsMetaError tests finding the deepest MetaError through wrapping and MultiError branches
+func TestAsMetaError(t *testing.T) {
+	if _, ok := AsMetaError(errors.New("plain")); ok {
+		t.Error("Expected no MetaError in a plain error")
+	}
+
+	inner := NewMetaError(errors.New("disk full"))
+	outer := NewMetaError(fmt.Errorf("save: %w", inner))
+	if found, ok := AsMetaError(outer); !ok || found != inner {
+		t.Error("Expected the innermost MetaError")
+	}
+
+	var mErr MultiError
+	mErr.Append(errors.New("first"))
+	mErr.Append(fmt.Errorf("batch: %w", inner))
+	if found, ok := AsMetaError(fmt.Errorf("import: %w", &mErr)); !ok || found != inner {
+		t.Error("Expected the MetaError inside a MultiError branch")
+	}
+}
+
+func BenchmarkMetaErrorStackTrace(b *testing.B) {
+	metaErr := NewMetaError(errors.New("benchmark"))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = fmt.Sprintf("%+v", metaErr)
+	}
+}
+
+// TestStackTraceCache tests that the cached stack trace is reused and refreshed when stack options change
+func TestStackTraceCache(t *testing.T) {
+	defer SetStackOptions(StackOptions{})
+
+	metaErr := NewMetaError(errors.New("cached"))
+	full := metaErr.StackTrace()
+	if full == "" || metaErr.StackTrace() != full {
+		t.Fatal("Expected a stable stack trace")
+	}
+
+	SetStackOptions(StackOptions{MaxDepth: 1})
+	if trimmed := metaErr.StackTrace(); trimmed == full || !strings.Contains(trimmed, "more frames") {
+		t.Errorf("Expected new stack options to invalidate the cache, got %q", trimmed)
+	}
+}
+
+// TestEquivalentTo tests that EquivalentTo ignores lines, stacks and volatile message parts
+func TestEquivalentTo(t *testing.T) {
+	a := NewMetaError(errors.New("query failed at store.go:41 on conn 0xc000123abc")).WithCode("DB_TIMEOUT")
+	b := NewMetaError(errors.New("query  failed at store.go:57 on conn 0xc000999000")).WithCode("DB_TIMEOUT")
+	if a.Line == b.Line {
+		t.Fatal("Expected the errors to be captured on different lines")
+	}
+	if !a.EquivalentTo(b) {
+		t.Error("Expected errors differing only in volatile parts to be equivalent")
+	}
+	if a.EquivalentTo(NewMetaError(errors.New("query failed at store.go:41 on replica")).WithCode("DB_TIMEOUT")) {
+		t.Error("Expected different messages not to be equivalent")
+	}
+	if a.EquivalentTo(NewMetaError(errors.New(a.Error()))) {
+		t.Error("Expected different codes not to be equivalent")
+	}
+
+	var nilErr *MetaError
+	if a.EquivalentTo(nil) || !nilErr.EquivalentTo(nil) {
+		t.Error("Expected nil to be equivalent only to nil")
+	}
+}
+
+// TestFingerprint tests that Fingerprint groups occurrences of the same failure
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(msg string) string {
+		return NewMetaError(errors.New(msg)).Fingerprint()
+	}
+
+	a := fingerprint("user 42 not found (request 6f1c2d3e-4a5b-4c6d-8e9f-0a1b2c3d4e5f)")
+	if len(a) != 16 {
+		t.Fatalf("Expected a 16 character fingerprint, got %q", a)
+	}
+	if b := fingerprint("user 97 not found (request 0d9e8f7a-6b5c-4d3e-2f1a-0b9c8d7e6f5a)"); a != b {
+		t.Errorf("Expected IDs to be ignored, got %s and %s", a, b)
+	}
+	if c := fingerprint("user 42 disabled (request 6f1c2d3e-4a5b-4c6d-8e9f-0a1b2c3d4e5f)"); a == c {
+		t.Error("Expected different messages to have different fingerprints")
+	}
+	other := NewMetaError(errors.New("user 42 not found (request 6f1c2d3e-4a5b-4c6d-8e9f-0a1b2c3d4e5f)"))
+	other.Func = "loadUser"
+	if other.Fingerprint() == a {
+		t.Error("Expected a different function to change the fingerprint")
+	}
+
+	var nilErr *MetaError
+	if nilErr.Fingerprint() != "" {
+		t.Error("Expected an empty fingerprint for nil")
+	}
 }
@@ -1,5 +1,10 @@
 package app
 
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
 // DefaultUser is the default user for the application, used when the application needs to set a username but the
 // application is the "user"
 const DefaultUser = "app"
@@ -21,3 +26,25 @@ var (
 func InProductionMode() bool {
 	return Mode == ReleaseMode
 }
+
+var safeMode atomic.Bool
+
+// EnterSafeMode switches the application into a degraded safe mode. Applications check InSafeMode to skip
+// non-essential work, such as background jobs that hammer dependencies.
+func EnterSafeMode(reason string) {
+	if !safeMode.Swap(true) {
+		slog.Warn("Application entering safe mode", "reason", reason)
+	}
+}
+
+// ExitSafeMode leaves safe mode.
+func ExitSafeMode() {
+	if safeMode.Swap(false) {
+		slog.Info("Application leaving safe mode")
+	}
+}
+
+// InSafeMode returns true if the application is running in degraded safe mode
+func InSafeMode() bool {
+	return safeMode.Load()
+}
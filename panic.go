@@ -0,0 +1,50 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPanic is matched by errors.Is for errors created by FromPanic.
+var ErrPanic = errors.New("panic")
+
+// PanicError is the error produced from a recovered panic value.
+type PanicError struct {
+	Value interface{}
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Is reports whether target is ErrPanic.
+func (p *PanicError) Is(target error) bool {
+	return target == ErrPanic
+}
+
+// Unwrap returns the panic value if it was itself an error.
+func (p *PanicError) Unwrap() error {
+	if err, ok := p.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// FromPanic converts a value returned by recover() into a MetaError whose location and stack trace point at the
+// panicking code. It returns nil if recovered is nil. It must be called from the deferred function that called
+// recover.
+//
+// Example usage:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			err = app.FromPanic(r)
+//		}
+//	}()
+func FromPanic(recovered interface{}) *MetaError {
+	if recovered == nil {
+		return nil
+	}
+	// Skip FromPanic, the deferred function and runtime.gopanic to land on the frame that panicked.
+	return NewMetaErrorOptions(&PanicError{Value: recovered}, 4, true, true)
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+type ProfileKind string
+
+const (
+	ProfileCPU       = ProfileKind("cpu")
+	ProfileHeap      = ProfileKind("heap")
+	ProfileGoroutine = ProfileKind("goroutine")
+	ProfileBlock     = ProfileKind("block")
+)
+
+var (
+	ErrUnknownProfile  = errors.New("unknown profile kind")
+	ErrProfileTooLarge = errors.New("profile exceeds size limit")
+)
+
+// MaxProfileSize is the largest profile CaptureProfile writes. Larger profiles are discarded with ErrProfileTooLarge.
+var MaxProfileSize int64 = 64 << 20
+
+// CaptureProfile writes a pprof profile of the given kind to a new file in dir and returns its path. CPU and block
+// profiles are sampled for duration, or until ctx is done; heap and goroutine profiles are snapshots and ignore
+// duration. Files are named "<kind>-<UTC time>-<pid>.pprof" and never overwrite an existing file.
+//
+// Example usage:
+//
+//	path, err := app.CaptureProfile(ctx, app.ProfileCPU, 30*time.Second, os.TempDir())
+func CaptureProfile(ctx context.Context, kind ProfileKind, duration time.Duration, dir string) (string, error) {
+	switch kind {
+	case ProfileCPU, ProfileHeap, ProfileGoroutine, ProfileBlock:
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownProfile, kind)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s-%d.pprof", kind, time.Now().UTC().Format("20060102T150405.000Z"), os.Getpid())
+	path := filepath.Join(dir, name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+
+	w := &limitedWriter{w: file, remaining: MaxProfileSize}
+	err = writeProfile(ctx, kind, duration, w)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && w.exceeded {
+		err = ErrProfileTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		slog.Error("Profile capture failed", "kind", kind, "error", err)
+		return "", err
+	}
+
+	slog.Info("Profile captured", "kind", kind, "path", path, "bytes", MaxProfileSize-w.remaining)
+	return path, nil
+}
+
+func writeProfile(ctx context.Context, kind ProfileKind, duration time.Duration, w io.Writer) error {
+	switch kind {
+	case ProfileCPU:
+		if err := pprof.StartCPUProfile(w); err != nil {
+			return err
+		}
+		waitFor(ctx, duration)
+		pprof.StopCPUProfile()
+		return nil
+	case ProfileBlock:
+		runtime.SetBlockProfileRate(1)
+		waitFor(ctx, duration)
+		runtime.SetBlockProfileRate(0)
+	}
+	return pprof.Lookup(string(kind)).WriteTo(w, 0)
+}
+
+func waitFor(ctx context.Context, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// limitedWriter stops writing once remaining reaches zero. It reports success to the profiler so the capture can
+// finish, and records that the limit was hit.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+	exceeded  bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.exceeded {
+		return len(p), nil
+	}
+	if int64(len(p)) > l.remaining {
+		l.exceeded = true
+		return len(p), nil
+	}
+	n, err := l.w.Write(p)
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCaptureProfile tests writing snapshot and sampled profiles, the size limit and unknown kinds
+func TestCaptureProfile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+
+	path, err := CaptureProfile(context.Background(), ProfileHeap, time.Hour, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 || filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), "heap-") {
+		t.Errorf("Expected a heap profile in %s, got %s, %v", dir, path, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	path, err = CaptureProfile(ctx, ProfileCPU, time.Hour, dir)
+	if err != nil || time.Since(start) > 10*time.Second {
+		t.Fatalf("Expected a cancelled ctx to end CPU sampling early, got %v after %s", err, time.Since(start))
+	}
+	if !strings.HasPrefix(filepath.Base(path), "cpu-") {
+		t.Errorf("Unexpected CPU profile path %s", path)
+	}
+
+	if _, err := CaptureProfile(context.Background(), ProfileKind("mutex"), 0, dir); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Expected ErrUnknownProfile, got %v", err)
+	}
+
+	previous := MaxProfileSize
+	MaxProfileSize = 16
+	t.Cleanup(func() {
+		MaxProfileSize = previous
+	})
+	if _, err := CaptureProfile(context.Background(), ProfileGoroutine, 0, dir); !errors.Is(err, ErrProfileTooLarge) {
+		t.Errorf("Expected ErrProfileTooLarge, got %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Expected the oversized profile removed, got %d files, %v", len(entries), err)
+	}
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorEvent is the tracker-neutral form of an error handed to an ErrorReporter. Exporters for Sentry, Bugsnag,
+// Rollbar and the like only need to map its fields onto their own event type.
+type ErrorEvent struct {
+	Message string
+	// Type is the Go type of the root cause, e.g. "*net.OpError"
+	Type string
+	// Code is the MetaError code, see WithCode
+	Code string
+	// Severity is SeverityOf the error
+	Severity Severity
+	// Category is CategoryOf the error
+	Category    Category
+	Fingerprint string
+	File        string
+	Line        int
+	Func        string
+	Package     string
+	// Frames is the captured stack, innermost first, filtered by StackOptions
+	Frames    []Frame
+	Fields    map[string]interface{}
+	RequestID string
+	TraceID   string
+	User      string
+	Timestamp time.Time
+}
+
+// ErrorReporter sends error events to an external error tracker.
+type ErrorReporter interface {
+	Report(ctx context.Context, event *ErrorEvent) error
+}
+
+type noopReporter struct{}
+
+func (noopReporter) Report(context.Context, *ErrorEvent) error { return nil }
+
+type reporterHolder struct {
+	reporter ErrorReporter
+}
+
+var currentReporter atomic.Pointer[reporterHolder]
+
+// SetErrorReporter installs r as the process-wide reporter used by Report. A nil r restores the default, which
+// discards events.
+//
+// Example usage:
+//
+//	app.SetErrorReporter(sentryReporter{hub: sentry.CurrentHub()})
+func SetErrorReporter(r ErrorReporter) {
+	if r == nil {
+		currentReporter.Store(nil)
+		return
+	}
+	currentReporter.Store(&reporterHolder{reporter: r})
+}
+
+func activeReporter() ErrorReporter {
+	if holder := currentReporter.Load(); holder != nil {
+		return holder.reporter
+	}
+	return noopReporter{}
+}
+
+// Report converts err to an ErrorEvent and sends it to the installed ErrorReporter. Errors that are not, and do not
+// wrap, a MetaError are attributed to the caller. Reporter failures are logged, never returned, so Report is safe to
+// call from error paths. It does nothing for a nil err.
+//
+// Example usage:
+//
+//	if err := job.Run(ctx); err != nil {
+//		app.Report(ctx, err)
+//	}
+func Report(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	event := newErrorEvent(ctx, err, 3)
+	if reportErr := activeReporter().Report(ctx, event); reportErr != nil {
+		slog.Warn("Error reporter failed", "error", reportErr, "reported", event.Message)
+	}
+}
+
+// NewErrorEvent converts err to the ErrorEvent Report would send, for exporters and tests. It returns nil for a nil
+// err.
+func NewErrorEvent(ctx context.Context, err error) *ErrorEvent {
+	if err == nil {
+		return nil
+	}
+	return newErrorEvent(ctx, err, 3)
+}
+
+func newErrorEvent(ctx context.Context, err error, skip int) *ErrorEvent {
+	var metaErr *MetaError
+	if !errors.As(err, &metaErr) {
+		metaErr = NewMetaErrorOptions(err, skip, true, true)
+	}
+	frames, _ := applyStackOptions(metaErr.Frames())
+
+	event := &ErrorEvent{
+		Message:     err.Error(),
+		Type:        fmt.Sprintf("%T", RootCause(err)),
+		Code:        CodeOf(err),
+		Severity:    SeverityOf(err),
+		Category:    CategoryOf(err),
+		Fingerprint: metaErr.Fingerprint(),
+		File:        metaErr.File,
+		Line:        metaErr.Line,
+		Func:        metaErr.Func,
+		Package:     metaErr.Package,
+		Frames:      frames,
+		Timestamp:   Now(),
+	}
+
+	if len(metaErr.Fields) > 0 {
+		event.Fields = make(map[string]interface{}, len(metaErr.Fields))
+		for k, v := range metaErr.Fields {
+			event.Fields[k] = v
+		}
+	}
+
+	if ctx != nil {
+		event.RequestID, _ = RequestIDFromContext(ctx)
+		event.TraceID, _ = TraceIDFromContext(ctx)
+		event.User, _ = UserFromContext(ctx)
+	}
+	return event
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingReporter struct {
+	events []*ErrorEvent
+}
+
+func (r *recordingReporter) Report(ctx context.Context, event *ErrorEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// TestReport tests that Report converts errors to events for the installed reporter
+func TestReport(t *testing.T) {
+	reporter := &recordingReporter{}
+	SetErrorReporter(reporter)
+	defer SetErrorReporter(nil)
+
+	ctx := WithTraceID(WithRequestID(context.Background(), "req-1"), "trace-1")
+	Report(ctx, NewMetaError(errors.New("not found")).WithCode("NOT_FOUND").WithHTTPStatus(404).WithField("id", 7))
+	Report(ctx, FromPanic("boom"))
+	Report(ctx, errors.New("plain"))
+	Report(ctx, nil)
+
+	if len(reporter.events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(reporter.events))
+	}
+
+	event := reporter.events[0]
+	if event.Message != "not found" || event.Code != "NOT_FOUND" || event.Severity != SeverityWarning {
+		t.Errorf("Expected message, code and warning severity, got %+v", event)
+	}
+	if event.RequestID != "req-1" || event.TraceID != "trace-1" || event.Fields["id"] != 7 {
+		t.Errorf("Expected context IDs and fields, got %+v", event)
+	}
+	if event.Func != "TestReport" || len(event.Frames) == 0 || event.Fingerprint == "" {
+		t.Errorf("Expected location, frames and fingerprint, got %+v", event)
+	}
+
+	if reporter.events[1].Severity != SeverityFatal {
+		t.Errorf("Expected panics to be fatal, got %s", reporter.events[1].Severity)
+	}
+	if plain := reporter.events[2]; plain.Severity != SeverityError || plain.Func != "TestReport" || plain.Type != "*errors.errorString" {
+		t.Errorf("Expected a plain error attributed to the caller, got %+v", plain)
+	}
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type requestContextKey int
+
+const (
+	requestIDKey requestContextKey = iota
+	traceIDKey
+	userKey
+)
+
+// maxContextIDLength bounds identifiers copied from requests into logs and errors.
+const maxContextIDLength = 128
+
+// WithRequestID returns a copy of ctx carrying the request ID, used by NewMetaErrorCtx.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, sanitizeContextID(id))
+}
+
+// RequestIDFromContext returns the request ID stored with WithRequestID.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	return contextString(ctx, requestIDKey)
+}
+
+// WithTraceID returns a copy of ctx carrying the trace ID, used by NewMetaErrorCtx.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey, sanitizeContextID(id))
+}
+
+// TraceIDFromContext returns the trace ID stored with WithTraceID.
+func TraceIDFromContext(ctx context.Context) (string, bool) {
+	return contextString(ctx, traceIDKey)
+}
+
+// WithUser returns a copy of ctx carrying the user identifier, used by NewMetaErrorCtx. Store an opaque ID rather
+// than a name or email, since it ends up in logs.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey, sanitizeContextID(user))
+}
+
+// UserFromContext returns the user identifier stored with WithUser.
+func UserFromContext(ctx context.Context) (string, bool) {
+	return contextString(ctx, userKey)
+}
+
+func contextString(ctx context.Context, key requestContextKey) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	v, ok := ctx.Value(key).(string)
+	return v, ok && v != ""
+}
+
+// sanitizeContextID drops control characters and caps the length of identifiers that usually come from request
+// headers, so they cannot forge log lines or bloat every error.
+func sanitizeContextID(id string) string {
+	id = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, id)
+	return truncateRunes(id, maxContextIDLength)
+}
+
+// ContextExtractor returns a value to attach to errors created by NewMetaErrorCtx, and whether ctx holds one.
+type ContextExtractor func(ctx context.Context) (interface{}, bool)
+
+type contextExtractor struct {
+	field string
+	fn    ContextExtractor
+}
+
+var (
+	contextExtractorsMu sync.RWMutex
+	contextExtractors   = []contextExtractor{
+		{"requestId", stringExtractor(requestIDKey)},
+		{"traceId", stringExtractor(traceIDKey)},
+		{"user", stringExtractor(userKey)},
+	}
+)
+
+func stringExtractor(key requestContextKey) ContextExtractor {
+	return func(ctx context.Context) (interface{}, bool) {
+		return contextString(ctx, key)
+	}
+}
+
+// RegisterContextExtractor makes NewMetaErrorCtx attach the value fn finds in the context as field. Registering a
+// field again replaces its extractor, including the built-in "requestId", "traceId" and "user" ones; a nil fn removes
+// it. Extractors run in registration order on every NewMetaErrorCtx call, so they should be cheap. Register them
+// during initialization.
+//
+// Example usage:
+//
+//	app.RegisterContextExtractor("tenant", func(ctx context.Context) (interface{}, bool) {
+//		tenant, ok := ctx.Value(tenantKey{}).(string)
+//		return tenant, ok
+//	})
+func RegisterContextExtractor(field string, fn ContextExtractor) {
+	contextExtractorsMu.Lock()
+	defer contextExtractorsMu.Unlock()
+
+	for i, extractor := range contextExtractors {
+		if extractor.field != field {
+			continue
+		}
+		if fn == nil {
+			contextExtractors = append(contextExtractors[:i:i], contextExtractors[i+1:]...)
+		} else {
+			// Copy rather than assign in place, NewMetaErrorCtx may be reading the current slice
+			replaced := append([]contextExtractor(nil), contextExtractors...)
+			replaced[i].fn = fn
+			contextExtractors = replaced
+		}
+		return
+	}
+	if fn != nil {
+		contextExtractors = append(contextExtractors, contextExtractor{field: field, fn: fn})
+	}
+}
+
+// NewMetaErrorCtx creates a MetaError like NewMetaError and attaches the values found in ctx by the registered
+// extractors as fields, so error logs correlate with request logs. By default these are the request ID, trace ID and
+// user, as the "requestId", "traceId" and "user" fields; see RegisterContextExtractor for adding others. If err is
+// already a *MetaError, the fields are added to it without overwriting existing ones.
+//
+// Example usage:
+//
+//	ctx = app.WithRequestID(r.Context(), r.Header.Get("X-Request-ID"))
+//	...
+//	return app.NewMetaErrorCtx(ctx, err)
+func NewMetaErrorCtx(ctx context.Context, err error) *MetaError {
+	if err == nil {
+		return nil
+	}
+
+	metaErr, ok := err.(*MetaError)
+	if !ok {
+		metaErr = NewMetaErrorOptions(err, 2, true, true)
+	}
+	if ctx == nil {
+		return metaErr
+	}
+
+	contextExtractorsMu.RLock()
+	extractors := contextExtractors
+	contextExtractorsMu.RUnlock()
+
+	for _, extractor := range extractors {
+		if _, exists := metaErr.Fields[extractor.field]; exists {
+			continue
+		}
+		if v, ok := extractor.fn(ctx); ok {
+			metaErr.WithField(extractor.field, v)
+		}
+	}
+	return metaErr
+}
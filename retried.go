@@ -0,0 +1,63 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+)
+
+// retryAttempter is implemented by errors that survived a retry policy, such as those returned by MarkRetried and
+// retry.RetryError.
+type retryAttempter interface {
+	RetryAttempts() int
+}
+
+// MarkRetried annotates err as having survived attempts tries of a retry policy, so upper layers can tell it from a
+// first-occurrence failure, e.g. to raise alert severity. The message and formatting of err are unchanged and it
+// remains reachable through errors.Is and errors.As. MarkRetried returns nil for a nil err.
+func MarkRetried(err error, attempts int) error {
+	if err == nil {
+		return nil
+	}
+	return &retriedError{err: err, attempts: attempts}
+}
+
+// IsRetried reports whether err, or any error it wraps, survived a retry policy.
+func IsRetried(err error) bool {
+	_, ok := RetryAttempts(err)
+	return ok
+}
+
+// RetryAttempts returns the number of attempts recorded on the outermost retried error in err's chain.
+func RetryAttempts(err error) (int, bool) {
+	var retried retryAttempter
+	if errors.As(err, &retried) {
+		return retried.RetryAttempts(), true
+	}
+	return 0, false
+}
+
+type retriedError struct {
+	err      error
+	attempts int
+}
+
+func (r *retriedError) Error() string {
+	return r.err.Error()
+}
+
+func (r *retriedError) Unwrap() error {
+	return r.err
+}
+
+func (r *retriedError) RetryAttempts() int {
+	return r.attempts
+}
+
+// Format delegates to the wrapped error so %+v still prints MetaError stack traces.
+func (r *retriedError) Format(s fmt.State, verb rune) {
+	if formatter, ok := r.err.(fmt.Formatter); ok {
+		formatter.Format(s, verb)
+		return
+	}
+	fmt.Fprintf(s, fmt.FormatString(s, verb), r.err)
+}
@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mhpenta/app/httpext"
+	"net/http"
+)
+
+// RegisterAdminRoutes adds retry inspection and tuning endpoints to mux, typically one created by
+// httpext.NewAdminMux. All routes are guarded by httpext.AdminOnly, so they are only served in debug and dev mode.
+// The kill switch, which operators need in production, is registered separately with RegisterKillSwitchRoute.
+//
+//	GET  /retry/policies             registered policies
+//	POST /retry/policies?name=<name> tune a policy, body is a JSON PolicySettings such as {"sleepTime":"30s"}; zero
+//	                                 fields are left unchanged
+//	GET  /retry/loops                retry loops currently running
+func RegisterAdminRoutes(mux *http.ServeMux) {
+	mux.Handle("/retry/policies", httpext.AdminOnly(http.HandlerFunc(policiesHandler)))
+	mux.Handle("/retry/loops", httpext.AdminOnly(http.HandlerFunc(loopsHandler)))
+}
+
+// RegisterKillSwitchRoute adds the kill switch endpoint to mux in every application mode, including release mode.
+// guard authenticates the caller, typically by checking a token or client certificate, and rejects the request
+// otherwise. A nil guard serves the route unauthenticated, which is only safe on a listener reachable by operators
+// alone, such as a separate admin listener bound to localhost.
+//
+//	GET  /retry/kill-switch          current kill switch mode
+//	POST /retry/kill-switch?mode=<m> set the kill switch to off, single-attempt or fail-fast
+//
+// Example usage:
+//
+//	mux := httpext.NewAdminMux()
+//	retry.RegisterAdminRoutes(mux)
+//	retry.RegisterKillSwitchRoute(mux, func(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+opsToken)) != 1 {
+//				http.Error(w, "unauthorized", http.StatusUnauthorized)
+//				return
+//			}
+//			next.ServeHTTP(w, r)
+//		})
+//	})
+func RegisterKillSwitchRoute(mux *http.ServeMux, guard func(http.Handler) http.Handler) {
+	var handler http.Handler = http.HandlerFunc(killSwitchHandler)
+	if guard != nil {
+		handler = guard(handler)
+	}
+	mux.Handle("/retry/kill-switch", handler)
+}
+
+func policiesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		httpext.WriteJSON(w, http.StatusOK, Policies())
+	case http.MethodPost:
+		var settings PolicySettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, httpext.BadRequestError, http.StatusBadRequest)
+			return
+		}
+
+		updated, err := TunePolicy(r.URL.Query().Get("name"), settings)
+		switch {
+		case errors.Is(err, ErrUnknownPolicy):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			httpext.WriteJSON(w, http.StatusOK, updated)
+		}
+	default:
+		http.Error(w, httpext.BadRequestError, http.StatusMethodNotAllowed)
+	}
+}
+
+func loopsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, httpext.BadRequestError, http.StatusMethodNotAllowed)
+		return
+	}
+	httpext.WriteJSON(w, http.StatusOK, ActiveLoops())
+}
+
+func killSwitchHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		mode := KillSwitchMode(r.URL.Query().Get("mode"))
+		switch mode {
+		case KillSwitchOff, KillSwitchSingleAttempt, KillSwitchFailFast:
+			SetKillSwitch(mode)
+		default:
+			http.Error(w, httpext.BadRequestError, http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, httpext.BadRequestError, http.StatusMethodNotAllowed)
+		return
+	}
+	httpext.WriteJSON(w, http.StatusOK, map[string]KillSwitchMode{"mode": KillSwitch()})
+}
@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"encoding/json"
+	"github.com/mhpenta/app"
+	"github.com/mhpenta/app/httpext"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	previous := app.Mode
+	app.Mode = app.DebugMode
+	defer func() { app.Mode = previous }()
+
+	mux := httpext.NewAdminMux()
+	RegisterAdminRoutes(mux)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	RegisterPolicy(t.Name(), PolicySettings{MaxAttempts: 3, SleepTime: time.Second, MaxWaitTime: time.Minute})
+	rec := serve(http.MethodPost, "/retry/policies?name="+t.Name(), `{"maxAttempts":10,"sleepTime":"2s"}`)
+	var updated PolicySettings
+	if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected the tuned policy, got %d %q", rec.Code, rec.Body.String())
+	}
+	if updated != (PolicySettings{MaxAttempts: 10, SleepTime: 2 * time.Second, MaxWaitTime: time.Minute}) {
+		t.Errorf("Expected MaxAttempts and SleepTime changed, got %+v", updated)
+	}
+	if !strings.Contains(rec.Body.String(), `"sleepTime":"2s","maxWaitTime":"1m0s"`) {
+		t.Errorf("Expected durations written as strings, got %q", rec.Body.String())
+	}
+	if settings, _ := LookupPolicy(t.Name()); settings != updated {
+		t.Errorf("Expected the registry updated, got %+v", settings)
+	}
+
+	if rec := serve(http.MethodPost, "/retry/policies?name=missing", `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown policy, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/retry/policies?name="+t.Name(), `{`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed body, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/retry/policies?name="+t.Name(), `{"sleepTime":"soon"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid duration, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/retry/policies?name="+t.Name(), `{"sleepTime":"-1s"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative duration, got %d", rec.Code)
+	}
+
+	var policies map[string]PolicySettings
+	rec = serve(http.MethodGet, "/retry/policies", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &policies); err != nil || policies[t.Name()] != updated {
+		t.Errorf("Expected the policy listed, got %q", rec.Body.String())
+	}
+
+	if rec := serve(http.MethodGet, "/retry/loops", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for loops, got %d", rec.Code)
+	}
+
+	app.Mode = app.ReleaseMode
+	if rec := serve(http.MethodGet, "/retry/loops", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 in release mode, got %d", rec.Code)
+	}
+}
+
+func TestKillSwitchRoute(t *testing.T) {
+	previous := app.Mode
+	app.Mode = app.ReleaseMode
+	defer func() {
+		app.Mode = previous
+		SetKillSwitch(KillSwitchOff)
+	}()
+
+	mux := http.NewServeMux()
+	RegisterKillSwitchRoute(mux, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer ops" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	serve := func(target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("/retry/kill-switch?mode=fail-fast", ""); rec.Code != http.StatusUnauthorized || KillSwitch() != KillSwitchOff {
+		t.Errorf("Expected the guard to reject an unauthenticated caller, got %d", rec.Code)
+	}
+	rec := serve("/retry/kill-switch?mode=fail-fast", "ops")
+	if rec.Code != http.StatusOK || KillSwitch() != KillSwitchFailFast || !strings.Contains(rec.Body.String(), `"fail-fast"`) {
+		t.Errorf("Expected the kill switch set to fail-fast in release mode, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := serve("/retry/kill-switch?mode=sometimes", "ops"); rec.Code != http.StatusBadRequest || KillSwitch() != KillSwitchFailFast {
+		t.Errorf("Expected an unknown mode rejected, got %d", rec.Code)
+	}
+}
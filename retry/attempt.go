@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAttemptTimeout is wrapped by the error recorded for an attempt that did not finish within Config.AttemptTimeout.
+var ErrAttemptTimeout = errors.New("retry attempt timed out")
+
+// pair carries the two results of an ExecuteWithTwoReturns task through runAttempt.
+type pair[T1, T2 any] struct {
+	first  T1
+	second T2
+}
+
+// runAttempt calls task once. Without an attempt timeout it simply calls task; otherwise task runs on its own
+// goroutine and is abandoned when the timeout expires or ctx is done. An abandoned task keeps running until it
+// returns, after which onAbandon receives its outcome so it can release what the caller will never see.
+func runAttempt[T any](ctx context.Context, timeout time.Duration, task func(ctx context.Context) (T, error), onAbandon func(ctx context.Context, result T, err error)) (T, error) {
+	if timeout <= 0 {
+		return task(ctx)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		result T
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := task(attemptCtx)
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-attemptCtx.Done():
+	}
+
+	go func() {
+		o := <-done
+		onAbandon(context.WithoutCancel(ctx), o.result, o.err)
+	}()
+
+	var zero T
+	if ctx.Err() != nil {
+		return zero, ctx.Err()
+	}
+	return zero, fmt.Errorf("%w after %s", ErrAttemptTimeout, timeout)
+}
@@ -0,0 +1,43 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecuteAbandonsSlowAttempts(t *testing.T) {
+	release := make(chan struct{})
+	abandoned := make(chan interface{}, 1)
+	config := Config{
+		Times:              2,
+		ExponentialBackoff: func(int) time.Duration { return 0 },
+		AttemptTimeout:     20 * time.Millisecond,
+		OnAbandon: func(ctx context.Context, result interface{}, err error) {
+			abandoned <- result
+		},
+	}
+
+	var calls atomic.Int32
+	result, err := Execute(context.Background(), config, func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			<-release
+			return "late", nil
+		}
+		return "fresh", nil
+	})
+	if err != nil || result != "fresh" {
+		t.Fatalf("Expected second attempt to succeed, got %q (%v)", result, err)
+	}
+
+	close(release)
+	select {
+	case late := <-abandoned:
+		if late != "late" {
+			t.Errorf("Expected OnAbandon to receive the late result, got %v", late)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected OnAbandon to be called for the abandoned attempt")
+	}
+}
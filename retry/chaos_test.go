@@ -0,0 +1,85 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app/httpext"
+	"github.com/mhpenta/app/retry/retrytest"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestChaosServerFaultsAreClassified(t *testing.T) {
+	srv := retrytest.NewChaosServer(nil,
+		retrytest.Reset, retrytest.GoAway, retrytest.Timeout, retrytest.TooManyRequests, retrytest.TruncatedBody)
+	defer srv.Close()
+
+	client := srv.Client()
+	client.Timeout = 200 * time.Millisecond
+
+	_, err := client.Get(srv.URL)
+	if !httpext.IsConnectionResetByPeerError(err) || !isConnectionError(err) {
+		t.Errorf("Expected a reset classified as a connection error, got %v", err)
+	}
+
+	_, err = client.Get(srv.URL)
+	if !httpext.IsHTTP2GoAwayError(err) || !httpext.IsTransientNetworkOrDNSIssueErr(err) {
+		t.Errorf("Expected a transient GOAWAY error, got %v", err)
+	}
+
+	_, err = client.Get(srv.URL)
+	if !httpext.IsIOTimeoutError(err) || !httpext.IsTransientNetworkOrDNSIssueErr(err) {
+		t.Errorf("Expected a transient timeout, got %v", err)
+	}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "0" {
+		t.Fatalf("Expected 429 with Retry-After, got %v, %v", resp, err)
+	}
+	resp.Body.Close()
+
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = httpext.ReadVerifiedBody(resp)
+	if !errors.Is(err, httpext.ErrTruncatedBody) || !httpext.IsTransientNetworkOrDNSIssueErr(err) {
+		t.Errorf("Expected a transient truncated body, got %v", err)
+	}
+
+	if injected := srv.Injected(); len(injected) != 5 || injected[4] != retrytest.TruncatedBody {
+		t.Errorf("Expected every fault injected once, got %v", injected)
+	}
+}
+
+func TestOnConnectionErrorRecoversFromChaosServer(t *testing.T) {
+	sleeps := recordSleeps(t)
+
+	srv := retrytest.NewChaosServer(nil, retrytest.Reset, retrytest.Reset)
+	defer srv.Close()
+	client := srv.Client()
+
+	config := ConnectionRetryConfig{MaxAttempts: 5, SleepTime: time.Second, MaxWaitTime: time.Hour}
+	body, err := OnConnectionErrorWithConfig(context.Background(), func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		return string(b), err
+	}, config)
+
+	if err != nil || body != "ok" {
+		t.Fatalf("Expected success after two resets, got %q, %v", body, err)
+	}
+	if len(*sleeps) != 2 || len(srv.Injected()) != 3 {
+		t.Errorf("Expected two retries, got sleeps %v and faults %v", *sleeps, srv.Injected())
+	}
+}
@@ -0,0 +1,183 @@
+package retry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mhpenta/app"
+	"github.com/mhpenta/app/jsonext"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DeadLetterRecord is one line of a dead-letter file.
+type DeadLetterRecord struct {
+	// Name identifies the operation, see Op.Name
+	Name string `json:"name"`
+	// Payload is the JSON encoding of Op.Payload, null when it could not be encoded
+	Payload json.RawMessage `json:"payload"`
+	// Attempts is the number of attempts made before the operation was dead-lettered
+	Attempts int `json:"attempts"`
+	// Error is the last error, with its capture site when it carried one
+	Error *app.MetaError `json:"error"`
+	// FailedAt is when the operation was dead-lettered
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// DeadLetterFile appends failed operations to a JSON lines file so they are not lost when retries are exhausted.
+// It is safe for concurrent use.
+type DeadLetterFile struct {
+	w *jsonext.RotatingWriter
+}
+
+// OpenDeadLetterFile opens path for appending, creating it if needed. The file is never rotated, so every record
+// remains available to ReplayDeadLetters.
+func OpenDeadLetterFile(path string) (*DeadLetterFile, error) {
+	w, err := jsonext.NewRotatingWriter(path, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &DeadLetterFile{w: w}, nil
+}
+
+// Write records a failed operation. It can be called directly by batch jobs that give up on an item outside a Queue.
+func (f *DeadLetterFile) Write(name string, payload interface{}, attempts int, err error) error {
+	record := DeadLetterRecord{
+		Name:     name,
+		Payload:  json.RawMessage("null"),
+		Attempts: attempts,
+		Error:    app.CaptureFromError(err),
+		FailedAt: app.Now(),
+	}
+	if data, marshalErr := json.Marshal(payload); marshalErr == nil {
+		record.Payload = data
+	} else {
+		slog.Warn("Dead-letter payload could not be encoded", "op", name, "error", marshalErr)
+	}
+	return f.w.WriteRecord(record)
+}
+
+// Handler returns a function for QueueConfig.DeadLetter that writes failed operations to the file, logging them
+// instead when the write fails.
+//
+// Example usage:
+//
+//	dlq, err := retry.OpenDeadLetterFile("/var/lib/app/webhooks.dlq")
+//	if err != nil {
+//		return err
+//	}
+//	defer app.CloseWithLog(dlq, "webhook dead-letter file")
+//	queue := retry.NewQueue(retry.QueueConfig{DeadLetter: dlq.Handler()})
+func (f *DeadLetterFile) Handler() func(op Op, attempts int, err error) {
+	return func(op Op, attempts int, err error) {
+		if writeErr := f.Write(op.Name, op.Payload, attempts, err); writeErr != nil {
+			slog.Error("Retry queue operation dropped, dead-letter write failed",
+				"op", op.Name, "attempts", attempts, "error", err, "writeError", writeErr)
+		}
+	}
+}
+
+// Close closes the file.
+func (f *DeadLetterFile) Close() error {
+	return f.w.Close()
+}
+
+// ReplayDeadLetters calls run for every record in the dead-letter file at path, in the order they were written.
+// Records that run accepts are removed; the file is rewritten with the records that failed again, or removed when
+// none did. Failures are returned in an *app.MultiError labelled with the operation name, alongside the number of
+// records replayed successfully. Close any DeadLetterFile writing to path first.
+//
+// Example usage:
+//
+//	replayed, err := retry.ReplayDeadLetters(ctx, "/var/lib/app/webhooks.dlq", func(ctx context.Context, record retry.DeadLetterRecord) error {
+//		var event WebhookEvent
+//		if err := json.Unmarshal(record.Payload, &event); err != nil {
+//			return err
+//		}
+//		return sendWebhook(ctx, event)
+//	})
+func ReplayDeadLetters(ctx context.Context, path string, run func(ctx context.Context, record DeadLetterRecord) error) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var mErr app.MultiError
+	var remaining [][]byte
+	replayed := 0
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		data := append([]byte(nil), scanner.Bytes()...)
+		if len(data) == 0 {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			remaining = append(remaining, data)
+			continue
+		}
+
+		var record DeadLetterRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			mErr.AppendWrapped(fmt.Sprintf("line %d", line), err)
+			remaining = append(remaining, data)
+			continue
+		}
+
+		if err := run(ctx, record); err != nil {
+			mErr.AppendWrapped(record.Name, err)
+			remaining = append(remaining, data)
+			continue
+		}
+		replayed++
+	}
+	scanErr := scanner.Err()
+	file.Close()
+	if scanErr != nil {
+		return replayed, scanErr
+	}
+	if ctx.Err() != nil {
+		mErr.Append(ctx.Err())
+	}
+
+	if err := rewriteDeadLetters(path, remaining); err != nil {
+		mErr.Append(err)
+	}
+	return replayed, mErr.ErrorOrNil()
+}
+
+// rewriteDeadLetters atomically replaces path with the given lines, removing it when there are none.
+func rewriteDeadLetters(path string, lines [][]byte) error {
+	if len(lines) == 0 {
+		return os.Remove(path)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, line := range lines {
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
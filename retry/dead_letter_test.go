@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeadLetterFileReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ops.dlq")
+	dlq, err := OpenDeadLetterFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := dlq.Handler()
+	handler(Op{Name: "first", Payload: map[string]int{"id": 1}}, 3, errors.New("upstream unavailable"))
+	handler(Op{Name: "second", Payload: map[string]int{"id": 2}}, 5, app.NewMetaError(errors.New("rejected")))
+	if err := dlq.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []DeadLetterRecord
+	replayed, err := ReplayDeadLetters(context.Background(), path, func(ctx context.Context, record DeadLetterRecord) error {
+		seen = append(seen, record)
+		if record.Name == "second" {
+			return errors.New("still rejected")
+		}
+		return nil
+	})
+	if replayed != 1 || err == nil || !strings.Contains(err.Error(), "still rejected") {
+		t.Errorf("Expected one replayed record and the second failure, got %d, %v", replayed, err)
+	}
+	if len(seen) != 2 || string(seen[0].Payload) != `{"id":1}` || seen[1].Attempts != 5 {
+		t.Fatalf("Expected both records in order, got %+v", seen)
+	}
+	if seen[0].Error == nil || seen[0].Error.Error() != "upstream unavailable" || seen[1].Error.Line == 0 {
+		t.Errorf("Expected the errors with their capture sites, got %+v, %+v", seen[0].Error, seen[1].Error)
+	}
+
+	replayed, err = ReplayDeadLetters(context.Background(), path, func(ctx context.Context, record DeadLetterRecord) error {
+		if record.Name != "second" {
+			t.Errorf("Expected only the failed record to remain, got %s", record.Name)
+		}
+		return nil
+	})
+	if replayed != 1 || err != nil {
+		t.Errorf("Expected the remaining record to replay, got %d, %v", replayed, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the drained file to be removed")
+	}
+}
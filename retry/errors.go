@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reasons a retry loop gives up, reported in RetryError.Reason.
+const (
+	ReasonMaxAttempts = "max attempts"
+	ReasonMaxWaitTime = "max wait time"
+)
+
+// RetryError is returned when a retry loop gives up. It carries the same information as its message in fields, so
+// callers and log pipelines can inspect the label, attempts and elapsed time without parsing text:
+//
+//	var retryErr *retry.RetryError
+//	if errors.As(err, &retryErr) {
+//		slog.Error("Fetch abandoned", "label", retryErr.Label, "attempts", retryErr.Attempts, "elapsed", retryErr.Elapsed)
+//	}
+type RetryError struct {
+	// Label names the operation, taken from the loop config or its kind when unset
+	Label string
+	// Attempts is the number of failed attempts
+	Attempts int
+	// Elapsed is the time from the first attempt until the loop gave up
+	Elapsed time.Duration
+	// Reason is ReasonMaxAttempts or ReasonMaxWaitTime
+	Reason string
+	// Err is the error returned by the last attempt
+	Err error
+}
+
+// Error renders the error as "retry[label]: max attempts (20) over 6m12s: last err: ...".
+func (e *RetryError) Error() string {
+	elapsed := e.Elapsed
+	if elapsed >= time.Second {
+		elapsed = elapsed.Round(time.Second)
+	} else {
+		elapsed = elapsed.Round(time.Millisecond)
+	}
+	return fmt.Sprintf("retry[%s]: %s (%d) over %s: last err: %v", e.Label, e.Reason, e.Attempts, elapsed, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAttempts returns Attempts, which lets app.IsRetried recognise errors returned by retry loops.
+func (e *RetryError) RetryAttempts() int {
+	return e.Attempts
+}
@@ -0,0 +1,25 @@
+package retry
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SleepFloor is the minimum delay between attempts of every retry loop in this package. Delays below it, including
+// zero from a misconfigured backoff, are raised to it so that a failing task cannot spin in a hot loop and starve the
+// scheduler. Set it to zero to disable the floor.
+var SleepFloor = 10 * time.Millisecond
+
+var floorWarnOnce sync.Once
+
+// floorDelay returns d raised to SleepFloor, warning once per process when a configured delay was too short.
+func floorDelay(d time.Duration) time.Duration {
+	if d >= SleepFloor {
+		return d
+	}
+	floorWarnOnce.Do(func() {
+		slog.Warn("Retry delay below floor, using floor instead", "delay", d, "floor", SleepFloor)
+	})
+	return SleepFloor
+}
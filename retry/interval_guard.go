@@ -0,0 +1,82 @@
+package retry
+
+import (
+	"context"
+	"github.com/mhpenta/app"
+	"sync"
+	"time"
+)
+
+// IntervalGuard enforces a minimum interval between attempts that share a key, such as the name of a dependency.
+//
+// The guard is shared by every retry loop in the process, so ten independent loops polling the same dependency
+// together still never call it more often than the configured floor.
+type IntervalGuard struct {
+	mu     sync.Mutex
+	floors map[string]time.Duration
+	next   map[string]time.Time
+}
+
+// DefaultIntervalGuard is the process-wide guard consulted by the retry loops when a config sets MinIntervalKey.
+var DefaultIntervalGuard = NewIntervalGuard()
+
+// NewIntervalGuard creates an IntervalGuard with no floors configured.
+func NewIntervalGuard() *IntervalGuard {
+	return &IntervalGuard{
+		floors: make(map[string]time.Duration),
+		next:   make(map[string]time.Time),
+	}
+}
+
+// SetMinInterval sets the minimum interval between attempts for key. A floor of zero or less removes the limit.
+func (g *IntervalGuard) SetMinInterval(key string, floor time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if floor <= 0 {
+		delete(g.floors, key)
+		delete(g.next, key)
+		return
+	}
+	g.floors[key] = floor
+}
+
+// Wait blocks until an attempt for key is allowed and reserves that slot. It returns immediately when key is empty
+// or has no floor, and returns the context error if ctx is done before the slot is reached.
+func (g *IntervalGuard) Wait(ctx context.Context, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	g.mu.Lock()
+	floor, ok := g.floors[key]
+	if !ok {
+		g.mu.Unlock()
+		return nil
+	}
+
+	now := app.Now()
+	slot := g.next[key]
+	if slot.Before(now) {
+		slot = now
+	}
+	g.next[key] = slot.Add(floor)
+	g.mu.Unlock()
+
+	wait := slot.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-app.After(wait):
+		return nil
+	}
+}
+
+// SetMinInterval sets the minimum interval between attempts for key on DefaultIntervalGuard.
+func SetMinInterval(key string, floor time.Duration) {
+	DefaultIntervalGuard.SetMinInterval(key, floor)
+}
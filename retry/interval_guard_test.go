@@ -0,0 +1,81 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app"
+	"testing"
+	"time"
+)
+
+func TestIntervalGuardUnderTestMode(t *testing.T) {
+	clock := app.TestMode(t)
+
+	guard := NewIntervalGuard()
+	guard.SetMinInterval("edgar", 10*time.Second)
+	for i := 0; i < 3; i++ {
+		if err := guard.Wait(context.Background(), "edgar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if waited := clock.Now().Sub(app.TestModeStart); waited != 20*time.Second {
+		t.Errorf("Expected 20s on the fake clock for three attempts, got %s", waited)
+	}
+}
+
+func TestIntervalGuardWithoutFloor(t *testing.T) {
+	guard := NewIntervalGuard()
+	guard.SetMinInterval("edgar", time.Hour)
+	guard.SetMinInterval("edgar", 0)
+
+	for _, key := range []string{"", "edgar", "unknown"} {
+		done := make(chan error, 1)
+		go func() {
+			done <- guard.Wait(context.Background(), key)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Expected no error for %q, got %v", key, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %q not to wait", key)
+		}
+	}
+}
+
+func TestIntervalGuardCancelled(t *testing.T) {
+	guard := NewIntervalGuard()
+	guard.SetMinInterval("edgar", time.Hour)
+	if err := guard.Wait(context.Background(), "edgar"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := guard.Wait(ctx, "edgar"); err != context.DeadlineExceeded {
+		t.Errorf("Expected the context error while waiting for the slot, got %v", err)
+	}
+}
+
+func TestExecuteHonoursMinInterval(t *testing.T) {
+	clock := app.TestMode(t)
+	SetMinInterval("filings-api", 5*time.Second)
+	defer SetMinInterval("filings-api", 0)
+
+	calls := 0
+	config := Config{Times: 3, MinIntervalKey: "filings-api", ExponentialBackoff: func(int) time.Duration { return 0 }}
+	_, err := Execute(context.Background(), config, func(ctx context.Context) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errors.New("flaky")
+		}
+		return 1, nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("Expected success on the third attempt, got %d calls, %v", calls, err)
+	}
+	if waited := clock.Now().Sub(app.TestModeStart); waited != 10*time.Second {
+		t.Errorf("Expected attempts spaced 5s apart, got %s", waited)
+	}
+}
@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"errors"
+	"github.com/mhpenta/app/httpext"
+	"log/slog"
+	"sync"
+)
+
+// ErrRetriesDisabled is returned without calling the operation while the kill switch is set to KillSwitchFailFast.
+var ErrRetriesDisabled = errors.New("retries disabled by kill switch")
+
+// KillSwitchMode controls how retry loops behave process-wide during an incident.
+type KillSwitchMode string
+
+const (
+	// KillSwitchOff lets retry loops run as configured
+	KillSwitchOff KillSwitchMode = "off"
+	// KillSwitchSingleAttempt runs every operation once and returns its error without retrying
+	KillSwitchSingleAttempt KillSwitchMode = "single-attempt"
+	// KillSwitchFailFast returns ErrRetriesDisabled without calling the operation at all
+	KillSwitchFailFast KillSwitchMode = "fail-fast"
+)
+
+func init() {
+	httpext.RetryGate = killSwitchGate
+}
+
+var (
+	killSwitchMu     sync.Mutex
+	killSwitchMode   = KillSwitchOff
+	killSwitchSource func() KillSwitchMode
+	killSwitchSeen   = KillSwitchOff
+)
+
+// SetKillSwitch sets the process-wide kill switch. It applies to loops and Execute calls started afterwards, to
+// retries scheduled by queues, and to the retries of httpext.RetryTransport and httpext.PaginateWithConfig. Operators
+// can also set it through RegisterKillSwitchRoute.
+//
+// Example usage:
+//
+//	retry.SetKillSwitch(retry.KillSwitchSingleAttempt) // shed retry load while the upstream recovers
+func SetKillSwitch(mode KillSwitchMode) {
+	killSwitchMu.Lock()
+	killSwitchMode = mode
+	killSwitchMu.Unlock()
+	KillSwitch()
+}
+
+// SetKillSwitchSource makes the kill switch follow source, typically a lookup in the application's feature flag
+// provider, instead of the value set with SetKillSwitch. source is called each time a retry loop starts, so it
+// should be cheap. A nil source restores SetKillSwitch control.
+//
+// Example usage:
+//
+//	retry.SetKillSwitchSource(func() retry.KillSwitchMode {
+//		return retry.KillSwitchMode(flags.String("retry-kill-switch", "off"))
+//	})
+func SetKillSwitchSource(source func() KillSwitchMode) {
+	killSwitchMu.Lock()
+	killSwitchSource = source
+	killSwitchMu.Unlock()
+	KillSwitch()
+}
+
+// KillSwitch returns the current kill switch mode. Unknown modes are treated as KillSwitchOff. Every change of mode
+// is logged, at Warn level while the switch is active.
+func KillSwitch() KillSwitchMode {
+	killSwitchMu.Lock()
+	defer killSwitchMu.Unlock()
+
+	mode := killSwitchMode
+	if killSwitchSource != nil {
+		mode = killSwitchSource()
+	}
+	switch mode {
+	case KillSwitchSingleAttempt, KillSwitchFailFast:
+	default:
+		mode = KillSwitchOff
+	}
+
+	if mode != killSwitchSeen {
+		if mode == KillSwitchOff {
+			slog.Info("Retry kill switch released, retries enabled", "previous", killSwitchSeen)
+		} else {
+			slog.Warn("RETRY KILL SWITCH ACTIVE: retries are disabled process-wide", "mode", mode)
+		}
+		killSwitchSeen = mode
+	}
+	return mode
+}
+
+// killSwitchGate applies the kill switch to the retries of httpext.RetryTransport and httpext.PaginateWithConfig.
+func killSwitchGate() (int, error) {
+	switch KillSwitch() {
+	case KillSwitchFailFast:
+		return 0, ErrRetriesDisabled
+	case KillSwitchSingleAttempt:
+		return 1, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,65 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app/httpext"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestKillSwitchCoversHTTPRetries(t *testing.T) {
+	defer SetKillSwitch(KillSwitchOff)
+
+	calls := 0
+	transport := &httpext.RetryTransport{
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return nil, dialError()
+		}),
+		Config: httpext.RetryTransportConfig{MaxAttempts: 3, Backoff: func(int) time.Duration { return time.Millisecond }},
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/filings", nil)
+
+	SetKillSwitch(KillSwitchSingleAttempt)
+	if _, err := transport.RoundTrip(req); err == nil || calls != 1 {
+		t.Errorf("Expected a single attempt, got %d (%v)", calls, err)
+	}
+
+	SetKillSwitch(KillSwitchFailFast)
+	calls = 0
+	if _, err := transport.RoundTrip(req); !errors.Is(err, ErrRetriesDisabled) || calls != 0 {
+		t.Errorf("Expected fail fast without sending, got %d calls (%v)", calls, err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	config := httpext.DefaultPaginateConfig
+	config.Retry.Backoff = func(int) time.Duration { return time.Millisecond }
+	handlePage := func(ctx context.Context, page httpext.Page) error {
+		return nil
+	}
+
+	err := httpext.PaginateWithConfig(context.Background(), srv.Client(), srv.URL, handlePage, config)
+	if !errors.Is(err, ErrRetriesDisabled) || calls != 0 {
+		t.Errorf("Expected pagination to fail fast, got %d calls (%v)", calls, err)
+	}
+
+	SetKillSwitch(KillSwitchSingleAttempt)
+	err = httpext.PaginateWithConfig(context.Background(), srv.Client(), srv.URL, handlePage, config)
+	if !errors.Is(err, httpext.ErrUnexpectedStatus) || calls != 1 {
+		t.Errorf("Expected a single page attempt, got %d calls (%v)", calls, err)
+	}
+}
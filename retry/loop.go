@@ -0,0 +1,228 @@
+package retry
+
+import (
+	"context"
+	"github.com/mhpenta/app"
+	"github.com/mhpenta/app/httpext"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// loopSpec describes a classified retry loop. The OnNetworkError, OnConnectionError and OnUnmarshallingError
+// families all run through runLoop with their own spec.
+type loopSpec struct {
+	kind           string
+	label          string
+	policy         string
+	maxAttempts    int
+	sleepTime      time.Duration
+	maxWaitTime    time.Duration
+	growthFactor   float64
+	maxSleep       time.Duration
+	minIntervalKey string
+	retryable      func(error) bool
+	retryMsg       string
+}
+
+// LoopState is a point-in-time view of a running retry loop.
+type LoopState struct {
+	ID        uint64    `json:"id"`
+	Kind      string    `json:"kind"`
+	Label     string    `json:"label,omitempty"`
+	Policy    string    `json:"policy,omitempty"`
+	Attempt   int       `json:"attempt"`
+	StartedAt time.Time `json:"startedAt"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// sleep is replaced in tests to observe the delay sequence without waiting.
+var sleep = app.Sleep
+
+var (
+	loopSeq     atomic.Uint64
+	activeMu    sync.Mutex
+	activeLoops = make(map[uint64]*LoopState)
+)
+
+// ActiveLoops returns the state of all retry loops currently running in the process, oldest first.
+func ActiveLoops() []LoopState {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+
+	states := make([]LoopState, 0, len(activeLoops))
+	for _, s := range activeLoops {
+		states = append(states, *s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+	return states
+}
+
+func trackLoop(spec loopSpec) *LoopState {
+	state := &LoopState{
+		ID:        loopSeq.Add(1),
+		Kind:      spec.kind,
+		Label:     spec.label,
+		Policy:    spec.policy,
+		StartedAt: app.Now(),
+	}
+
+	activeMu.Lock()
+	activeLoops[state.ID] = state
+	activeMu.Unlock()
+	return state
+}
+
+func untrackLoop(state *LoopState) {
+	activeMu.Lock()
+	delete(activeLoops, state.ID)
+	activeMu.Unlock()
+}
+
+func updateLoop(state *LoopState, attempt int, err error) {
+	activeMu.Lock()
+	state.Attempt = attempt
+	state.LastError = err.Error()
+	activeMu.Unlock()
+}
+
+// resolve applies the registered policy, caps the initial sleep by maxSleep and fills defaults, giving the spec a
+// loop actually runs with.
+func (spec loopSpec) resolve() loopSpec {
+	if settings, ok := LookupPolicy(spec.policy); ok {
+		spec.maxAttempts = settings.MaxAttempts
+		spec.sleepTime = settings.SleepTime
+		spec.maxWaitTime = settings.MaxWaitTime
+	}
+	if spec.maxSleep > 0 && spec.sleepTime > spec.maxSleep {
+		spec.sleepTime = spec.maxSleep
+	}
+	if spec.label == "" {
+		spec.label = spec.kind
+	}
+	return spec
+}
+
+// shouldRetry reports whether err is worth another attempt. A flag set with app.MarkRetryable or app.MarkPermanent
+// takes precedence over the spec's classification.
+func (spec loopSpec) shouldRetry(err error) bool {
+	retryable, _ := spec.classify(err)
+	return retryable
+}
+
+// classify is shouldRetry, also naming what decided: "marked" for an explicit flag, otherwise the loop kind.
+func (spec loopSpec) classify(err error) (bool, string) {
+	if retryable, marked := app.IsRetryable(err); marked {
+		return retryable, "marked"
+	}
+	return spec.retryable(err), spec.kind
+}
+
+// exhausted reports whether the budget is spent after the attempt-th retryable failure, elapsed into the loop, and
+// returns ReasonMaxAttempts or ReasonMaxWaitTime when it is.
+func (spec loopSpec) exhausted(attempt int, elapsed time.Duration) (string, bool) {
+	switch {
+	case attempt >= spec.maxAttempts:
+		return ReasonMaxAttempts, true
+	case elapsed > spec.maxWaitTime:
+		return ReasonMaxWaitTime, true
+	}
+	return "", false
+}
+
+// runLoop calls f until it succeeds, returns an error spec.shouldRetry rejects, or the attempt or wait budget is spent,
+// in which case the last error is returned inside a *RetryError. The kill switch can reduce the budget to one attempt
+// or skip f entirely. A failure caused by ctx being cancelled or expiring is returned at once.
+func runLoop(ctx context.Context, spec loopSpec, f func(context.Context) error) error {
+	spec = spec.resolve()
+	switch KillSwitch() {
+	case KillSwitchFailFast:
+		return ErrRetriesDisabled
+	case KillSwitchSingleAttempt:
+		spec.maxAttempts = 1
+	}
+
+	state := trackLoop(spec)
+	defer untrackLoop(state)
+
+	var err error
+
+	startTime := app.Now()
+	attempt := 0
+	waitDuration := spec.sleepTime
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Context cancelled, aborting retry", "error", ctx.Err())
+			return ctx.Err()
+		default:
+			if err = DefaultIntervalGuard.Wait(ctx, spec.minIntervalKey); err != nil {
+				return err
+			}
+
+			err = f(ctx)
+			if err == nil {
+				traceSuccess(spec.label, attempt+1, app.Since(startTime))
+				return nil
+			}
+
+			trace := attemptTrace{label: spec.label, attempt: attempt + 1, err: err, remainingWait: -1}
+			retryable, classifier := spec.classify(err)
+			if ctx.Err() != nil && httpext.IsCausedByContext(err) {
+				// The caller gave up; the error reports the cancellation, not the network.
+				retryable, classifier = false, "context"
+			}
+			trace.classifier = classifier
+			if !retryable {
+				trace.decision = decisionPermanent
+				trace.log()
+				return err
+			}
+
+			attempt++
+			updateLoop(state, attempt, err)
+
+			elapsed := app.Since(startTime)
+			trace.remainingAttempts = max(spec.maxAttempts-attempt, 0)
+			trace.remainingWait = max(spec.maxWaitTime-elapsed, 0)
+			if reason, done := spec.exhausted(attempt, elapsed); done {
+				trace.decision, trace.classifier = decisionExhausted, reason
+				trace.log()
+				return &RetryError{Label: spec.label, Attempts: attempt, Elapsed: app.Since(startTime), Reason: reason, Err: err}
+			}
+			trace.decision, trace.delay = decisionRetry, floorDelay(waitDuration)
+			trace.log()
+
+			slog.Info(spec.retryMsg,
+				"label", spec.label,
+				"error", err,
+				"attempt", attempt,
+				"nextRetryIn", waitDuration,
+			)
+			sleep(floorDelay(waitDuration))
+			waitDuration = nextSleep(waitDuration, spec.growthFactor, spec.maxSleep)
+		}
+	}
+}
+
+// nextSleep grows current by growthFactor, capped at maxSleep when it is positive. Growth factors of 1 or less
+// leave the sleep unchanged.
+func nextSleep(current time.Duration, growthFactor float64, maxSleep time.Duration) time.Duration {
+	next := current
+	if growthFactor > 1 {
+		next = time.Duration(float64(current) * growthFactor)
+		if next < current {
+			// overflow
+			next = current
+		}
+	}
+	if maxSleep > 0 && next > maxSleep {
+		next = maxSleep
+	}
+	return next
+}
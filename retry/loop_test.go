@@ -0,0 +1,314 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func recordSleeps(t *testing.T) *[]time.Duration {
+	var sleeps []time.Duration
+	original := sleep
+	sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	}
+	t.Cleanup(func() {
+		sleep = original
+	})
+	return &sleeps
+}
+
+func dialError() error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}
+}
+
+func TestOnNetworkErrorGrowthSequence(t *testing.T) {
+	tests := []struct {
+		name   string
+		config NetworkRetryConfig
+		want   []time.Duration
+	}{
+		{
+			name: "constant sleep by default",
+			config: NetworkRetryConfig{
+				MaxAttempts:  4,
+				SleepTime:    time.Second,
+				MaxWaitTime:  time.Hour,
+				GrowthFactor: 1,
+			},
+			want: []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name: "zero growth factor keeps constant sleep",
+			config: NetworkRetryConfig{
+				MaxAttempts: 3,
+				SleepTime:   time.Second,
+				MaxWaitTime: time.Hour,
+			},
+			want: []time.Duration{time.Second, time.Second},
+		},
+		{
+			name: "exponential growth capped by max sleep",
+			config: NetworkRetryConfig{
+				MaxAttempts:  6,
+				SleepTime:    time.Second,
+				MaxWaitTime:  time.Hour,
+				GrowthFactor: 2,
+				MaxSleep:     5 * time.Second,
+			},
+			want: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name: "initial sleep capped by max sleep",
+			config: NetworkRetryConfig{
+				MaxAttempts:  3,
+				SleepTime:    10 * time.Second,
+				MaxWaitTime:  time.Hour,
+				GrowthFactor: 2,
+				MaxSleep:     3 * time.Second,
+			},
+			want: []time.Duration{3 * time.Second, 3 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sleeps := recordSleeps(t)
+
+			calls := 0
+			_, err := OnNetworkErrorWithConfig(context.Background(), func(ctx context.Context) (int, error) {
+				calls++
+				return 0, dialError()
+			}, tt.config)
+
+			if err == nil {
+				t.Fatal("Expected error after exhausting attempts")
+			}
+
+			if calls != tt.config.MaxAttempts {
+				t.Errorf("Expected %d calls, got %d", tt.config.MaxAttempts, calls)
+			}
+
+			if len(*sleeps) != len(tt.want) {
+				t.Fatalf("Expected sleeps %v, got %v", tt.want, *sleeps)
+			}
+			for i := range tt.want {
+				if (*sleeps)[i] != tt.want[i] {
+					t.Errorf("Expected sleeps %v, got %v", tt.want, *sleeps)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestOnConnectionErrorGrowthSequence(t *testing.T) {
+	sleeps := recordSleeps(t)
+
+	config := ConnectionRetryConfig{
+		MaxAttempts:  4,
+		SleepTime:    100 * time.Millisecond,
+		MaxWaitTime:  time.Hour,
+		GrowthFactor: 1.5,
+		MaxSleep:     200 * time.Millisecond,
+	}
+
+	err := OnConnectionErrorSimpleWithConfig(context.Background(), func() error {
+		return dialError()
+	}, config)
+
+	if err == nil {
+		t.Fatal("Expected error after exhausting attempts")
+	}
+
+	want := []time.Duration{100 * time.Millisecond, 150 * time.Millisecond, 200 * time.Millisecond}
+	if len(*sleeps) != len(want) {
+		t.Fatalf("Expected sleeps %v, got %v", want, *sleeps)
+	}
+	for i := range want {
+		if (*sleeps)[i] != want[i] {
+			t.Errorf("Expected sleeps %v, got %v", want, *sleeps)
+			break
+		}
+	}
+}
+
+func TestRetryErrorLabel(t *testing.T) {
+	recordSleeps(t)
+
+	config := ConnectionRetryConfig{
+		MaxAttempts: 3,
+		SleepTime:   time.Millisecond,
+		MaxWaitTime: time.Hour,
+		Label:       "sec-fetch",
+	}
+
+	err := OnConnectionErrorSimpleWithConfig(context.Background(), func() error {
+		return dialError()
+	}, config)
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("Expected *RetryError, got %T", err)
+	}
+	if retryErr.Label != "sec-fetch" || retryErr.Attempts != 3 || retryErr.Reason != ReasonMaxAttempts {
+		t.Errorf("Expected sec-fetch, 3 attempts, %q, got %+v", ReasonMaxAttempts, retryErr)
+	}
+	if !strings.HasPrefix(err.Error(), "retry[sec-fetch]: max attempts (3) over ") {
+		t.Errorf("Expected labelled message, got %q", err.Error())
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Error("Expected the last attempt's error to be unwrappable")
+	}
+}
+
+func TestRetryabilityFlags(t *testing.T) {
+	recordSleeps(t)
+
+	config := ConnectionRetryConfig{MaxAttempts: 3, SleepTime: time.Millisecond, MaxWaitTime: time.Hour}
+
+	calls := 0
+	err := OnConnectionErrorSimpleWithConfig(context.Background(), func() error {
+		calls++
+		return app.MarkPermanent(dialError())
+	}, config)
+	if calls != 1 || errors.As(err, new(*RetryError)) {
+		t.Errorf("Expected permanent error to stop after 1 call, got %d calls (%v)", calls, err)
+	}
+
+	calls = 0
+	err = OnConnectionErrorSimpleWithConfig(context.Background(), func() error {
+		calls++
+		return app.MarkRetryable(errors.New("version conflict"))
+	}, config)
+	if calls != 3 || !errors.As(err, new(*RetryError)) {
+		t.Errorf("Expected retryable error to use all 3 attempts, got %d calls (%v)", calls, err)
+	}
+}
+
+func TestKillSwitch(t *testing.T) {
+	recordSleeps(t)
+	defer SetKillSwitch(KillSwitchOff)
+
+	config := ConnectionRetryConfig{MaxAttempts: 3, SleepTime: time.Millisecond, MaxWaitTime: time.Hour}
+	calls := 0
+	fetch := func() error {
+		calls++
+		return dialError()
+	}
+
+	SetKillSwitch(KillSwitchSingleAttempt)
+	_ = OnConnectionErrorSimpleWithConfig(context.Background(), fetch, config)
+	if calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", calls)
+	}
+
+	SetKillSwitchSource(func() KillSwitchMode { return KillSwitchFailFast })
+	defer SetKillSwitchSource(nil)
+	_, err := Execute(context.Background(), NewConfig(3), func(ctx context.Context) (int, error) {
+		calls++
+		return 0, nil
+	})
+	if !errors.Is(err, ErrRetriesDisabled) || calls != 1 {
+		t.Errorf("Expected fail fast without calling the task, got %v after %d calls", err, calls)
+	}
+}
+
+func TestLoopUnderTestMode(t *testing.T) {
+	clock := app.TestMode(t)
+
+	config := ConnectionRetryConfig{MaxAttempts: 4, SleepTime: time.Second, MaxWaitTime: time.Hour}
+	err := OnConnectionErrorSimpleWithConfig(context.Background(), func() error {
+		return dialError()
+	}, config)
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 4 {
+		t.Fatalf("Expected 4 attempts, got %v", err)
+	}
+	if waited := clock.Now().Sub(app.TestModeStart); waited != 3*time.Second || retryErr.Elapsed != waited {
+		t.Errorf("Expected 3s on the fake clock, got %s (elapsed %s)", waited, retryErr.Elapsed)
+	}
+}
+
+func TestPlanMatchesLoop(t *testing.T) {
+	config := NetworkRetryConfig{
+		MaxAttempts:  3,
+		SleepTime:    time.Second,
+		MaxWaitTime:  time.Hour,
+		GrowthFactor: 2,
+	}
+
+	plan := config.Plan([]error{dialError(), dialError(), dialError(), dialError()})
+	if plan.Outcome != ReasonMaxAttempts {
+		t.Errorf("Expected outcome %q, got %q", ReasonMaxAttempts, plan.Outcome)
+	}
+	if len(plan.Steps) != 3 || plan.TotalDelay != 3*time.Second {
+		t.Errorf("Expected 3 steps over 3s, got %d steps over %s", len(plan.Steps), plan.TotalDelay)
+	}
+
+	sleeps := recordSleeps(t)
+	_ = OnNetworkErrorWithConfigOnlyError(context.Background(), func(context.Context) error {
+		return dialError()
+	}, config)
+	for i, d := range *sleeps {
+		if plan.Steps[i].Delay != d {
+			t.Errorf("Expected plan delay %s at step %d to match loop sleep %s", plan.Steps[i].Delay, i, d)
+		}
+	}
+
+	plan = config.Plan([]error{dialError(), nil})
+	if plan.Outcome != OutcomeSucceeded || !plan.WouldRetry() {
+		t.Errorf("Expected a retry then success, got %+v", plan)
+	}
+
+	plan = config.Plan([]error{errors.New("bad request")})
+	if plan.Outcome != OutcomeNotRetryable || plan.WouldRetry() {
+		t.Errorf("Expected no retry for a non-network error, got %+v", plan)
+	}
+}
+
+func TestMaxSleepCapsTunedPolicy(t *testing.T) {
+	RegisterPolicy(t.Name(), PolicySettings{MaxAttempts: 3, SleepTime: time.Second, MaxWaitTime: time.Hour})
+	if _, err := TunePolicy(t.Name(), PolicySettings{SleepTime: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	config := NetworkRetryConfig{Policy: t.Name(), MaxSleep: 5 * time.Second}
+
+	plan := config.Plan([]error{dialError(), dialError(), dialError()})
+	if len(plan.Steps) != 3 || plan.Steps[0].Delay != 5*time.Second || plan.TotalDelay != 10*time.Second {
+		t.Errorf("Expected the tuned sleep capped at 5s in the plan, got %+v", plan)
+	}
+
+	sleeps := recordSleeps(t)
+	_ = OnNetworkErrorWithConfigOnlyError(context.Background(), func(context.Context) error {
+		return dialError()
+	}, config)
+	if len(*sleeps) != 2 || (*sleeps)[0] != 5*time.Second || (*sleeps)[1] != 5*time.Second {
+		t.Errorf("Expected the tuned sleep capped at 5s in the loop, got %v", *sleeps)
+	}
+}
+
+func TestLoopStopsWhenCallerGivesUp(t *testing.T) {
+	sleeps := recordSleeps(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	config := NetworkRetryConfig{MaxAttempts: 5, SleepTime: time.Second, MaxWaitTime: time.Hour}
+	err := OnNetworkErrorWithConfigOnlyError(ctx, func(ctx context.Context) error {
+		calls++
+		cancel()
+		_, err := (&net.Dialer{}).DialContext(ctx, "tcp", "127.0.0.1:1")
+		return err
+	}, config)
+
+	if !errors.Is(err, context.Canceled) || calls != 1 || len(*sleeps) != 0 {
+		t.Errorf("Expected the cancellation returned without retrying, got %v after %d calls and sleeps %v", err, calls, *sleeps)
+	}
+}
@@ -0,0 +1,95 @@
+package retry
+
+import "time"
+
+// Plan outcomes, reported in Plan.Outcome alongside ReasonMaxAttempts and ReasonMaxWaitTime.
+const (
+	OutcomeSucceeded        = "succeeded"
+	OutcomeNotRetryable     = "not retryable"
+	OutcomeSamplesExhausted = "samples exhausted"
+)
+
+// PlanStep is one simulated attempt of a dry-run Plan.
+type PlanStep struct {
+	// Attempt is the 1-based index of the call
+	Attempt int
+	// Err is the sample returned by the call, nil for success
+	Err error
+	// Retry reports whether the loop would call again
+	Retry bool
+	// Delay is the sleep before the next call when Retry is true
+	Delay time.Duration
+}
+
+// Plan is the result of a dry run: what a retry loop would do if successive calls returned the given samples.
+type Plan struct {
+	Label string
+	Steps []PlanStep
+	// TotalDelay is the sum of all delays, which is also the simulated elapsed time
+	TotalDelay time.Duration
+	// Outcome is OutcomeSucceeded, OutcomeNotRetryable, ReasonMaxAttempts, ReasonMaxWaitTime or
+	// OutcomeSamplesExhausted when the loop would still be retrying after the last sample
+	Outcome string
+}
+
+// WouldRetry reports whether the loop would retry at least once.
+func (p Plan) WouldRetry() bool {
+	return len(p.Steps) > 0 && p.Steps[0].Retry
+}
+
+// Plan runs the OnNetworkError decision logic against samples without sleeping or calling anything, each sample
+// standing for the error returned by one call (nil for success). Registered policies are applied, and elapsed time
+// is simulated as the sum of delays.
+//
+// Example usage:
+//
+//	plan := config.Plan([]error{recordedErr, recordedErr, nil})
+//	fmt.Println(plan.Outcome, plan.TotalDelay)
+func (config NetworkRetryConfig) Plan(samples []error) Plan {
+	return planLoop(config.loopSpec(), samples)
+}
+
+// Plan runs the OnConnectionError decision logic against samples, see NetworkRetryConfig.Plan.
+func (config ConnectionRetryConfig) Plan(samples []error) Plan {
+	return planLoop(config.loopSpec(), samples)
+}
+
+// Plan runs the OnUnmarshallingError decision logic against samples, see NetworkRetryConfig.Plan.
+func (config UnmarshallingRetryConfig) Plan(samples []error) Plan {
+	return planLoop(config.loopSpec(), samples)
+}
+
+func planLoop(spec loopSpec, samples []error) Plan {
+	spec = spec.resolve()
+
+	plan := Plan{Label: spec.label, Outcome: OutcomeSamplesExhausted}
+	attempt := 0
+	waitDuration := spec.sleepTime
+
+	for i, err := range samples {
+		step := PlanStep{Attempt: i + 1, Err: err}
+
+		switch {
+		case err == nil:
+			plan.Outcome = OutcomeSucceeded
+		case !spec.shouldRetry(err):
+			plan.Outcome = OutcomeNotRetryable
+		default:
+			attempt++
+			if reason, done := spec.exhausted(attempt, plan.TotalDelay); done {
+				plan.Outcome = reason
+				break
+			}
+			step.Retry = true
+			step.Delay = floorDelay(waitDuration)
+			plan.TotalDelay += step.Delay
+			waitDuration = nextSleep(waitDuration, spec.growthFactor, spec.maxSleep)
+		}
+
+		plan.Steps = append(plan.Steps, step)
+		if !step.Retry {
+			break
+		}
+	}
+	return plan
+}
@@ -0,0 +1,142 @@
+package retry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrUnknownPolicy is returned when tuning a policy that has not been registered.
+var ErrUnknownPolicy = errors.New("unknown retry policy")
+
+// ErrInvalidPolicy is wrapped by the error TunePolicy returns for settings it cannot apply.
+var ErrInvalidPolicy = errors.New("invalid retry policy settings")
+
+// PolicySettings are the tunable settings of a named retry policy. In JSON the durations are strings accepted by
+// time.ParseDuration, such as "30s" or "6m0s".
+type PolicySettings struct {
+	MaxAttempts int
+	SleepTime   time.Duration
+	MaxWaitTime time.Duration
+}
+
+type policySettingsJSON struct {
+	MaxAttempts int    `json:"maxAttempts"`
+	SleepTime   string `json:"sleepTime"`
+	MaxWaitTime string `json:"maxWaitTime"`
+}
+
+// MarshalJSON encodes the durations as strings such as "30s".
+func (s PolicySettings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(policySettingsJSON{
+		MaxAttempts: s.MaxAttempts,
+		SleepTime:   s.SleepTime.String(),
+		MaxWaitTime: s.MaxWaitTime.String(),
+	})
+}
+
+// UnmarshalJSON decodes the object produced by MarshalJSON. Missing or empty durations decode as zero.
+func (s *PolicySettings) UnmarshalJSON(data []byte) error {
+	var in policySettingsJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	out := PolicySettings{MaxAttempts: in.MaxAttempts}
+	var err error
+	if out.SleepTime, err = parsePolicyDuration("sleepTime", in.SleepTime); err != nil {
+		return err
+	}
+	if out.MaxWaitTime, err = parsePolicyDuration("maxWaitTime", in.MaxWaitTime); err != nil {
+		return err
+	}
+	*s = out
+	return nil
+}
+
+func parsePolicyDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", field, err)
+	}
+	return d, nil
+}
+
+var (
+	policiesMu sync.RWMutex
+	policies   = make(map[string]PolicySettings)
+)
+
+// RegisterPolicy registers or replaces the named policy. Loops whose config sets Policy to name use these settings
+// instead of the MaxAttempts, SleepTime and MaxWaitTime in the config, so a policy can be inspected and tuned at
+// runtime, see RegisterAdminRoutes.
+//
+// Example usage:
+//
+//	retry.RegisterPolicy("sec-fetch", retry.PolicySettings{MaxAttempts: 20, SleepTime: 30 * time.Second, MaxWaitTime: 6 * time.Minute})
+//	cfg := retry.DefaultConnectionRetryConfig
+//	cfg.Policy = "sec-fetch"
+//	body, err := retry.OnConnectionErrorWithConfig(ctx, fetch, cfg)
+func RegisterPolicy(name string, settings PolicySettings) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	policies[name] = settings
+}
+
+// LookupPolicy returns the settings of the named policy.
+func LookupPolicy(name string) (PolicySettings, bool) {
+	if name == "" {
+		return PolicySettings{}, false
+	}
+
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	settings, ok := policies[name]
+	return settings, ok
+}
+
+// TunePolicy updates a registered policy. Zero fields in settings leave the current value unchanged; negative ones
+// are rejected with an error wrapping ErrInvalidPolicy. Changes apply to loops started after the call.
+func TunePolicy(name string, settings PolicySettings) (PolicySettings, error) {
+	if settings.MaxAttempts < 0 || settings.SleepTime < 0 || settings.MaxWaitTime < 0 {
+		return PolicySettings{}, fmt.Errorf("%w: negative values in %+v", ErrInvalidPolicy, settings)
+	}
+
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+
+	current, ok := policies[name]
+	if !ok {
+		return PolicySettings{}, ErrUnknownPolicy
+	}
+
+	if settings.MaxAttempts > 0 {
+		current.MaxAttempts = settings.MaxAttempts
+	}
+	if settings.SleepTime > 0 {
+		current.SleepTime = settings.SleepTime
+	}
+	if settings.MaxWaitTime > 0 {
+		current.MaxWaitTime = settings.MaxWaitTime
+	}
+
+	policies[name] = current
+	return current, nil
+}
+
+// Policies returns a copy of all registered policies.
+func Policies() map[string]PolicySettings {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+
+	out := make(map[string]PolicySettings, len(policies))
+	for name, settings := range policies {
+		out[name] = settings
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"github.com/mhpenta/app/httpext"
+	"log/slog"
+)
+
+// Operation is a unit of work guarded by a Policy.
+type Operation[T any] func(ctx context.Context) (T, error)
+
+// Policy wraps an Operation with resilience behaviour. Policies are combined with Compose.
+type Policy[T any] func(next Operation[T]) Operation[T]
+
+// Compose combines policies into one. The first policy is applied innermost, so
+// Compose(Retry(cfg), Breaker(name), FallbackValue(v)) retries the operation, counts each retried call once in the
+// breaker, and falls back to v when the breaker is open or the retries fail.
+//
+// Example usage:
+//
+//	policy := retry.Compose(
+//		retry.Retry[*Quote](retry.NewConfig(3)),
+//		retry.Breaker[*Quote]("quotes-api"),
+//		retry.FallbackValue(cachedQuote),
+//	)
+//	quote, err := policy.Do(ctx, fetchQuote)
+func Compose[T any](policies ...Policy[T]) Policy[T] {
+	return func(next Operation[T]) Operation[T] {
+		for _, policy := range policies {
+			next = policy(next)
+		}
+		return next
+	}
+}
+
+// Do runs op through the policy.
+func (p Policy[T]) Do(ctx context.Context, op Operation[T]) (T, error) {
+	return p(op)(ctx)
+}
+
+// Retry returns a Policy that retries the operation with Execute.
+func Retry[T any](config Config) Policy[T] {
+	return func(next Operation[T]) Operation[T] {
+		return func(ctx context.Context) (T, error) {
+			return Execute(ctx, config, next)
+		}
+	}
+}
+
+// Breaker returns a Policy that guards the operation with the shared httpext circuit breaker registered under name.
+// While the breaker is open the operation is not called and the error wraps httpext.ErrBreakerOpen.
+func Breaker[T any](name string) Policy[T] {
+	return func(next Operation[T]) Operation[T] {
+		return func(ctx context.Context) (T, error) {
+			breaker := httpext.BreakerFor(name)
+			ticket, err := breaker.Allow()
+			if err != nil {
+				var zero T
+				return zero, fmt.Errorf("%w: %s", err, name)
+			}
+
+			result, err := next(ctx)
+			breaker.Record(ticket, err)
+			return result, err
+		}
+	}
+}
+
+// Fallback returns a Policy that calls fallback with the error when the operation fails.
+func Fallback[T any](fallback func(ctx context.Context, err error) (T, error)) Policy[T] {
+	return func(next Operation[T]) Operation[T] {
+		return func(ctx context.Context) (T, error) {
+			result, err := next(ctx)
+			if err == nil {
+				return result, nil
+			}
+			return fallback(ctx, err)
+		}
+	}
+}
+
+// FallbackValue returns a Policy that returns value instead of the error when the operation fails. The error is
+// logged so the degradation stays visible.
+func FallbackValue[T any](value T) Policy[T] {
+	return Fallback(func(ctx context.Context, err error) (T, error) {
+		slog.Warn("Operation failed, using fallback value", "error", err)
+		return value, nil
+	})
+}
@@ -0,0 +1,36 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestComposePolicies(t *testing.T) {
+	calls := 0
+	policy := Compose(
+		Retry[string](Config{Times: 2, ExponentialBackoff: func(int) time.Duration { return 0 }}),
+		Breaker[string]("test-compose"),
+		FallbackValue("cached"),
+	)
+
+	result, err := policy.Do(context.Background(), func(ctx context.Context) (string, error) {
+		calls++
+		return "", errors.New("down")
+	})
+
+	if err != nil || result != "cached" {
+		t.Errorf("Expected fallback value, got %q (%v)", result, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls from the retry policy, got %d", calls)
+	}
+
+	result, err = policy.Do(context.Background(), func(ctx context.Context) (string, error) {
+		return "fresh", nil
+	})
+	if err != nil || result != "fresh" {
+		t.Errorf("Expected fresh value, got %q (%v)", result, err)
+	}
+}
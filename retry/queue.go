@@ -0,0 +1,276 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by Enqueue when the queue is at capacity.
+	ErrQueueFull = errors.New("retry queue is full")
+	// ErrQueueClosed is returned by Enqueue after Shutdown has been called.
+	ErrQueueClosed = errors.New("retry queue is closed")
+)
+
+// Op is an operation run by a Queue. Name and Payload identify the operation in logs and dead-letter callbacks.
+type Op struct {
+	Name    string
+	Payload interface{}
+	Run     func(ctx context.Context) error
+}
+
+// QueueConfig holds configuration for a Queue
+type QueueConfig struct {
+	// Workers is the number of goroutines running operations
+	Workers int
+	// Capacity is the maximum number of operations waiting to run
+	Capacity int
+	// MaxAttempts is the number of attempts before an operation is dead-lettered
+	MaxAttempts int
+	// Backoff returns the delay before the given retry, starting at 1
+	Backoff func(retryCount int) time.Duration
+	// DeadLetter is called with operations that exhausted their attempts or could not be requeued. Defaults to
+	// logging the failure; use DeadLetterFile.Handler to persist them for ReplayDeadLetters.
+	DeadLetter func(op Op, attempts int, err error)
+}
+
+// DefaultQueueConfig provides sensible default values for QueueConfig
+var DefaultQueueConfig = QueueConfig{
+	Workers:     4,
+	Capacity:    1024,
+	MaxAttempts: 5,
+	Backoff:     ExponentialBackoff1sPower2WithJitter,
+}
+
+// Queue is a bounded in-process queue that runs fire-and-forget operations on worker goroutines and retries failed
+// ones with backoff, without blocking the caller. It is meant for webhook sends, cache warms and similar work that
+// should not hold up a request path.
+type Queue struct {
+	config QueueConfig
+	items  chan *queueItem
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	closed  bool
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+}
+
+type queueItem struct {
+	op       Op
+	attempts int
+}
+
+// NewQueue creates a Queue and starts its workers.
+func NewQueue(config QueueConfig) *Queue {
+	if config.Workers <= 0 {
+		config.Workers = DefaultQueueConfig.Workers
+	}
+	if config.Capacity <= 0 {
+		config.Capacity = DefaultQueueConfig.Capacity
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultQueueConfig.MaxAttempts
+	}
+	if config.Backoff == nil {
+		config.Backoff = DefaultQueueConfig.Backoff
+	}
+	if config.DeadLetter == nil {
+		config.DeadLetter = func(op Op, attempts int, err error) {
+			slog.Error("Retry queue operation dropped", "op", op.Name, "attempts", attempts, "error", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	q := &Queue{
+		config: config,
+		items:  make(chan *queueItem, config.Capacity),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue adds op to the queue. It never blocks: it returns ErrQueueFull when the queue is at capacity and
+// ErrQueueClosed after Shutdown.
+func (q *Queue) Enqueue(op Op) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	q.pending.Add(1)
+	select {
+	case q.items <- &queueItem{op: op}:
+		return nil
+	default:
+		q.pending.Done()
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting new operations and waits until every queued operation, including those waiting for a
+// retry, has succeeded or been dead-lettered. If ctx is done first, workers are cancelled and ctx.Err() is returned;
+// operations still queued or waiting for a retry are dead-lettered with that error, and running ones with their own
+// error once they return.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		q.pending.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		q.cancel(nil)
+		q.workers.Wait()
+		return nil
+	case <-ctx.Done():
+		// Cancel under the lock so no retry is requeued after the drain below
+		q.mu.Lock()
+		q.cancel(ctx.Err())
+		q.mu.Unlock()
+		q.drain()
+		return ctx.Err()
+	}
+}
+
+// drain dead-letters the operations left in the queue after it was cancelled.
+func (q *Queue) drain() {
+	for {
+		select {
+		case item := <-q.items:
+			q.deadLetter(item, context.Cause(q.ctx))
+		default:
+			return
+		}
+	}
+}
+
+func (q *Queue) work() {
+	defer q.workers.Done()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case item := <-q.items:
+			if q.ctx.Err() != nil {
+				q.deadLetter(item, context.Cause(q.ctx))
+				continue
+			}
+			q.run(item)
+		}
+	}
+}
+
+func (q *Queue) run(item *queueItem) {
+	mode := KillSwitch()
+	if mode == KillSwitchFailFast {
+		q.deadLetter(item, ErrRetriesDisabled)
+		return
+	}
+
+	item.attempts++
+	err := item.op.Run(q.ctx)
+	if err == nil {
+		q.pending.Done()
+		return
+	}
+
+	if item.attempts >= q.config.MaxAttempts || q.ctx.Err() != nil || mode == KillSwitchSingleAttempt {
+		q.deadLetter(item, err)
+		return
+	}
+
+	delay := q.config.Backoff(item.attempts)
+	slog.Info("Retry queue operation failed, retrying",
+		"op", item.op.Name,
+		"error", err,
+		"attempt", item.attempts,
+		"nextRetryIn", delay,
+	)
+
+	go func() {
+		select {
+		case <-q.ctx.Done():
+		case <-app.After(delay):
+		}
+		q.requeue(item, err)
+	}()
+}
+
+// requeue puts item back in the queue for its next attempt, or dead-letters it when the queue was cancelled or is
+// full.
+func (q *Queue) requeue(item *queueItem, err error) {
+	q.mu.Lock()
+	var dropErr error
+	if q.ctx.Err() != nil {
+		dropErr = errors.Join(err, context.Cause(q.ctx))
+	} else {
+		select {
+		case q.items <- item:
+		default:
+			dropErr = errors.Join(err, ErrQueueFull)
+		}
+	}
+	q.mu.Unlock()
+
+	if dropErr != nil {
+		q.deadLetter(item, dropErr)
+	}
+}
+
+func (q *Queue) deadLetter(item *queueItem, err error) {
+	defer q.pending.Done()
+	q.config.DeadLetter(item.op, item.attempts, err)
+}
+
+var (
+	defaultQueueOnce sync.Once
+	defaultQueue     *Queue
+)
+
+// DefaultQueue returns the process-wide queue used by Enqueue, created with DefaultQueueConfig on first use.
+func DefaultQueue() *Queue {
+	defaultQueueOnce.Do(func() {
+		defaultQueue = NewQueue(DefaultQueueConfig)
+	})
+	return defaultQueue
+}
+
+// Enqueue adds op to the process-wide retry queue. Call DrainQueue during shutdown so queued work is not lost.
+//
+// Example usage:
+//
+//	err := retry.Enqueue(retry.Op{
+//		Name:    "webhook",
+//		Payload: event,
+//		Run: func(ctx context.Context) error {
+//			return sendWebhook(ctx, event)
+//		},
+//	})
+func Enqueue(op Op) error {
+	return DefaultQueue().Enqueue(op)
+}
+
+// DrainQueue shuts down the process-wide retry queue, see Queue.Shutdown.
+func DrainQueue(ctx context.Context) error {
+	return DefaultQueue().Shutdown(ctx)
+}
@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type deadLetters struct {
+	mu      sync.Mutex
+	entries map[string]error
+}
+
+func (d *deadLetters) handler(op Op, attempts int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.entries == nil {
+		d.entries = make(map[string]error)
+	}
+	d.entries[op.Name] = err
+}
+
+func (d *deadLetters) get(name string) (error, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err, ok := d.entries[name]
+	return err, ok
+}
+
+func TestQueueRetriesUntilSuccess(t *testing.T) {
+	var dead deadLetters
+	q := NewQueue(QueueConfig{Workers: 2, MaxAttempts: 3, Backoff: func(int) time.Duration { return time.Millisecond }, DeadLetter: dead.handler})
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	op := func(name string, failures int) Op {
+		return Op{Name: name, Run: func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[name]++
+			if calls[name] <= failures {
+				return errors.New("webhook refused")
+			}
+			return nil
+		}}
+	}
+
+	if err := q.Enqueue(op("flaky", 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue(op("broken", 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls["flaky"] != 3 {
+		t.Errorf("Expected flaky to succeed on its third attempt, got %d attempts", calls["flaky"])
+	}
+	if _, ok := dead.get("flaky"); ok {
+		t.Error("Expected flaky not to be dead-lettered")
+	}
+	if err, ok := dead.get("broken"); !ok || err == nil || calls["broken"] != 3 {
+		t.Errorf("Expected broken dead-lettered after 3 attempts, got %d attempts (%v)", calls["broken"], err)
+	}
+	if err := q.Enqueue(op("late", 0)); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Expected ErrQueueClosed after Shutdown, got %v", err)
+	}
+}
+
+func TestQueueFullAndShutdownTimeout(t *testing.T) {
+	var dead deadLetters
+	q := NewQueue(QueueConfig{Workers: 1, Capacity: 1, MaxAttempts: 3, DeadLetter: dead.handler})
+
+	started := make(chan struct{})
+	blocking := Op{Name: "blocking", Run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	idle := func(name string) Op {
+		return Op{Name: name, Run: func(ctx context.Context) error {
+			return nil
+		}}
+	}
+
+	if err := q.Enqueue(blocking); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := q.Enqueue(idle("buffered")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue(idle("overflow")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the shutdown to time out, got %v", err)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		q.pending.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("Expected every operation accounted for after the timeout")
+	}
+
+	if err, ok := dead.get("buffered"); !ok || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the buffered operation dead-lettered with the shutdown error, got %v", err)
+	}
+	if err, ok := dead.get("blocking"); !ok || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the running operation dead-lettered with its own error, got %v", err)
+	}
+	if _, ok := dead.get("overflow"); ok {
+		t.Error("Expected the rejected operation not to be dead-lettered")
+	}
+}
@@ -3,7 +3,7 @@ package retry
 import (
 	"context"
 	"github.com/mhpenta/app"
-	"math/rand/v2"
+	"runtime"
 	"time"
 )
 
@@ -15,6 +15,18 @@ type Config struct {
 	InitialDelayMilliseconds int
 	// ExponentialBackoff function that calculates the retry delay
 	ExponentialBackoff func(retryCount int) time.Duration
+	// MinIntervalKey, when set, spaces attempts through DefaultIntervalGuard. See SetMinInterval.
+	MinIntervalKey string
+	// Yield calls runtime.Gosched before every retry, letting other goroutines run between rapid early retries of
+	// CPU-bound tasks. Delays are never shorter than SleepFloor either way.
+	Yield bool
+	// AttemptTimeout bounds each attempt. An attempt still running when it expires is abandoned and counted as a
+	// failure wrapping ErrAttemptTimeout; its context is cancelled, but the loop does not wait for it to return.
+	AttemptTimeout time.Duration
+	// OnAbandon is called once an abandoned attempt returns, with a context that is never cancelled, so resources
+	// it produced (open bodies, temp files) can be released. result holds the attempt's value, or a []interface{}
+	// of both values for ExecuteWithTwoReturns.
+	OnAbandon func(ctx context.Context, result interface{}, err error)
 }
 
 func NewConfig(retryCount int) Config {
@@ -24,13 +36,28 @@ func NewConfig(retryCount int) Config {
 	}
 }
 
-// Execute the task and retries when the task returns an error
+// Execute the task and retries when the task returns an error. Errors marked with app.MarkPermanent stop the retries,
+// and the kill switch can limit the task to one attempt or skip it, see SetKillSwitch.
 func Execute[T any](ctx context.Context, config Config, task func(ctx context.Context) (T, error)) (T, error) {
 	var mRetryErr app.MultiError
 	var defaultResult T
 
+	switch KillSwitch() {
+	case KillSwitchFailFast:
+		return defaultResult, ErrRetriesDisabled
+	case KillSwitchSingleAttempt:
+		config.Times = 1
+	}
+
 	for i := 0; i < config.Times; i++ {
-		result, err := task(ctx)
+		if err := DefaultIntervalGuard.Wait(ctx, config.MinIntervalKey); err != nil {
+			mRetryErr.Append(err)
+			return defaultResult, mRetryErr.ErrorOrNil()
+		}
+
+		result, err := runAttempt(ctx, config.AttemptTimeout, task, func(ctx context.Context, result T, err error) {
+			config.abandoned(ctx, result, err)
+		})
 
 		if err == nil {
 			return result, nil
@@ -38,65 +65,103 @@ func Execute[T any](ctx context.Context, config Config, task func(ctx context.Co
 			mRetryErr.Errors = append(mRetryErr.Errors, err)
 		}
 
-		if i == config.Times-1 {
+		if i == config.Times-1 || isPermanent(err) {
+			traceExecuteAttempt(i, config.Times, err, 0)
 			break
 		}
 
-		var delay time.Duration
+		delay := config.backoff(i + 1)
+		traceExecuteAttempt(i, config.Times, err, floorDelay(delay))
 
-		if config.ExponentialBackoff != nil {
-			delay = config.ExponentialBackoff(i + 1)
-		} else {
-			delay = ExponentialBackoff1sPower2(i + 1)
+		if config.Yield {
+			runtime.Gosched()
 		}
 
 		select {
 		case <-ctx.Done():
 			return defaultResult, mRetryErr.ErrorOrNil()
-		case <-time.After(delay * time.Millisecond):
+		case <-app.After(floorDelay(delay)):
 		}
 	}
 
 	return defaultResult, mRetryErr.ErrorOrNil()
 }
 
-// ExecuteWithTwoReturns the task and retries when the task returns an error
+// ExecuteWithTwoReturns the task and retries when the task returns an error, see Execute
 func ExecuteWithTwoReturns[T1, T2 any](ctx context.Context, config Config, task func(ctx context.Context) (T1, T2, error)) (T1, T2, error) {
 	var mRetryErr app.MultiError
 	var defaultResult1 T1
 	var defaultResult2 T2
 
+	switch KillSwitch() {
+	case KillSwitchFailFast:
+		return defaultResult1, defaultResult2, ErrRetriesDisabled
+	case KillSwitchSingleAttempt:
+		config.Times = 1
+	}
+
 	for i := 0; i < config.Times; i++ {
-		result1, result2, err := task(ctx)
+		if err := DefaultIntervalGuard.Wait(ctx, config.MinIntervalKey); err != nil {
+			mRetryErr.Append(err)
+			return defaultResult1, defaultResult2, mRetryErr.ErrorOrNil()
+		}
+
+		results, err := runAttempt(ctx, config.AttemptTimeout, func(ctx context.Context) (pair[T1, T2], error) {
+			result1, result2, err := task(ctx)
+			return pair[T1, T2]{first: result1, second: result2}, err
+		}, func(ctx context.Context, results pair[T1, T2], err error) {
+			config.abandoned(ctx, []interface{}{results.first, results.second}, err)
+		})
 
 		if err == nil {
-			return result1, result2, nil
+			return results.first, results.second, nil
 		} else {
 			mRetryErr.Errors = append(mRetryErr.Errors, err)
 		}
 
-		if i == config.Times-1 {
+		if i == config.Times-1 || isPermanent(err) {
+			traceExecuteAttempt(i, config.Times, err, 0)
 			break
 		}
 
-		var delay time.Duration
+		delay := config.backoff(i + 1)
+		traceExecuteAttempt(i, config.Times, err, floorDelay(delay))
 
-		if config.ExponentialBackoff != nil {
-			delay = config.ExponentialBackoff(i + 1)
-		} else {
-			delay = ExponentialBackoff1sPower2(i + 1)
+		if config.Yield {
+			runtime.Gosched()
 		}
 
 		select {
 		case <-ctx.Done():
 			return defaultResult1, defaultResult2, mRetryErr.ErrorOrNil()
-		case <-time.After(delay * time.Millisecond):
+		case <-app.After(floorDelay(delay)):
 		}
 	}
 
 	return defaultResult1, defaultResult2, mRetryErr.ErrorOrNil()
 }
 
+// backoff returns the delay before the given retry, starting at 1.
+func (config Config) backoff(retryCount int) time.Duration {
+	if config.ExponentialBackoff != nil {
+		return config.ExponentialBackoff(retryCount)
+	}
+	return ExponentialBackoff1sPower2(retryCount)
+}
+
+// abandoned forwards the outcome of an abandoned attempt to OnAbandon when it is set.
+func (config Config) abandoned(ctx context.Context, result interface{}, err error) {
+	if config.OnAbandon != nil {
+		config.OnAbandon(ctx, result, err)
+	}
+}
+
+// isPermanent reports whether err was marked with app.MarkPermanent.
+func isPermanent(err error) bool {
+	retryable, marked := app.IsRetryable(err)
+	return marked && !retryable
+}
+
 // ExponentialBackoff1sPower2 calculates the delay as an exponential backoff of 1 second, power of 2
 func ExponentialBackoff1sPower2(retryCount int) time.Duration {
 	// Start with a 100ms delay and double it with each retry
@@ -114,6 +179,6 @@ func ExponentialBackoff1sPower2WithJitter(retryCount int) time.Duration {
 	baseDelay := time.Duration(1000*(1<<retryCount)) * time.Millisecond
 	jitter := 0.5
 	maxDelay := baseDelay + time.Duration(float64(baseDelay)*jitter)
-	delay := baseDelay + time.Duration(rand.N(int64(maxDelay-baseDelay)))
+	delay := baseDelay + time.Duration(app.RandN(int64(maxDelay-baseDelay)))
 	return delay
 }
@@ -2,9 +2,7 @@ package retry
 
 import (
 	"context"
-	"fmt"
 	"github.com/mhpenta/app/httpext"
-	"log/slog"
 	"time"
 )
 
@@ -13,13 +11,47 @@ type ConnectionRetryConfig struct {
 	MaxAttempts int
 	SleepTime   time.Duration
 	MaxWaitTime time.Duration
+	// GrowthFactor multiplies the sleep time after each failed attempt. Values below 1 keep the sleep constant.
+	GrowthFactor float64
+	// MaxSleep caps the sleep between attempts, including SleepTime and a SleepTime tuned through a registered
+	// policy. Zero means no cap.
+	MaxSleep time.Duration
+	// Label names the operation in logs and in the RetryError returned when the loop gives up. Defaults to the
+	// kind of loop.
+	Label string
+	// MinIntervalKey, when set, spaces attempts through DefaultIntervalGuard so that all loops sharing the key
+	// respect the floor configured with SetMinInterval.
+	MinIntervalKey string
+	// Policy, when set to a name registered with RegisterPolicy, overrides MaxAttempts, SleepTime and MaxWaitTime.
+	Policy string
 }
 
 // DefaultConnectionRetryConfig provides sensible default values for RetryConfig
 var DefaultConnectionRetryConfig = ConnectionRetryConfig{
-	MaxAttempts: 20,
-	SleepTime:   30 * time.Second,
-	MaxWaitTime: 6 * time.Minute,
+	MaxAttempts:  20,
+	SleepTime:    30 * time.Second,
+	MaxWaitTime:  6 * time.Minute,
+	GrowthFactor: 1,
+}
+
+func (config ConnectionRetryConfig) loopSpec() loopSpec {
+	return loopSpec{
+		kind:           "connection",
+		label:          config.Label,
+		policy:         config.Policy,
+		maxAttempts:    config.MaxAttempts,
+		sleepTime:      config.SleepTime,
+		maxWaitTime:    config.MaxWaitTime,
+		growthFactor:   config.GrowthFactor,
+		maxSleep:       config.MaxSleep,
+		minIntervalKey: config.MinIntervalKey,
+		retryable:      isConnectionError,
+		retryMsg:       "Connection unreachable, retrying",
+	}
+}
+
+func isConnectionError(err error) bool {
+	return httpext.IsTransientNetworkOrDNSIssueErr(err) && httpext.IsDialError(err)
 }
 
 // OnConnectionError retries the given function with a standard wait time on Connection errors with default configuration
@@ -36,44 +68,14 @@ func OnConnectionError[T any](ctx context.Context, f func(context.Context) (T, e
 // OnConnectionErrorWithConfig retries the given function with a standard wait time on Connection errors
 func OnConnectionErrorWithConfig[T any](ctx context.Context, f func(context.Context) (T, error), config ConnectionRetryConfig) (T, error) {
 	var result T
-	var err error
-
-	startTime := time.Now()
-	attempt := 0
-	waitDuration := config.SleepTime
-
-	for {
-		select {
-		case <-ctx.Done():
-			slog.Info("Context cancelled, aborting retry", "error", ctx.Err())
-			return result, ctx.Err()
-		default:
-			result, err = f(ctx)
-			if err == nil {
-				return result, nil
-			}
-
-			if !httpext.IsTransientNetworkOrDNSIssueErr(err) || !httpext.IsDialError(err) {
-				return result, err
-			}
-
-			attempt++
-			if attempt >= config.MaxAttempts {
-				return result, fmt.Errorf("max retry attempts reached: %w", err)
-			}
 
-			if time.Since(startTime) > config.MaxWaitTime {
-				return result, fmt.Errorf("max wait time exceeded: %w", err)
-			}
+	err := runLoop(ctx, config.loopSpec(), func(ctx context.Context) error {
+		var err error
+		result, err = f(ctx)
+		return err
+	})
 
-			slog.Info("Connection unreachable, retrying",
-				"error", err,
-				"attempt", attempt,
-				"nextRetryIn", waitDuration,
-			)
-			time.Sleep(waitDuration)
-		}
-	}
+	return result, err
 }
 
 func OnConnectionErrorSimple(ctx context.Context, f func() error) error {
@@ -82,42 +84,7 @@ func OnConnectionErrorSimple(ctx context.Context, f func() error) error {
 
 // OnConnectionErrorSimpleWithConfig retries the given function with a standard wait time on Connection errors
 func OnConnectionErrorSimpleWithConfig(ctx context.Context, f func() error, config ConnectionRetryConfig) error {
-	var err error
-
-	startTime := time.Now()
-	attempt := 0
-	waitDuration := config.SleepTime
-
-	for {
-		select {
-		case <-ctx.Done():
-			slog.Info("Context cancelled, aborting retry", "error", ctx.Err())
-			return ctx.Err()
-		default:
-			err = f()
-			if err == nil {
-				return nil
-			}
-
-			if !httpext.IsTransientNetworkOrDNSIssueErr(err) || !httpext.IsDialError(err) {
-				return err
-			}
-
-			attempt++
-			if attempt >= config.MaxAttempts {
-				return fmt.Errorf("max retry attempts reached: %w", err)
-			}
-
-			if time.Since(startTime) > config.MaxWaitTime {
-				return fmt.Errorf("max wait time exceeded: %w", err)
-			}
-
-			slog.Info("Connection unreachable, retrying",
-				"error", err,
-				"attempt", attempt,
-				"nextRetryIn", waitDuration,
-			)
-			time.Sleep(waitDuration)
-		}
-	}
+	return runLoop(ctx, config.loopSpec(), func(context.Context) error {
+		return f()
+	})
 }
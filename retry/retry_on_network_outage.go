@@ -2,9 +2,7 @@ package retry
 
 import (
 	"context"
-	"fmt"
 	"github.com/mhpenta/app/httpext"
-	"log/slog"
 	"time"
 )
 
@@ -13,13 +11,43 @@ type NetworkRetryConfig struct {
 	MaxAttempts int
 	SleepTime   time.Duration
 	MaxWaitTime time.Duration
+	// GrowthFactor multiplies the sleep time after each failed attempt. Values below 1 keep the sleep constant.
+	GrowthFactor float64
+	// MaxSleep caps the sleep between attempts, including SleepTime and a SleepTime tuned through a registered
+	// policy. Zero means no cap.
+	MaxSleep time.Duration
+	// Label names the operation in logs and in the RetryError returned when the loop gives up. Defaults to the
+	// kind of loop.
+	Label string
+	// MinIntervalKey, when set, spaces attempts through DefaultIntervalGuard so that all loops sharing the key
+	// respect the floor configured with SetMinInterval.
+	MinIntervalKey string
+	// Policy, when set to a name registered with RegisterPolicy, overrides MaxAttempts, SleepTime and MaxWaitTime.
+	Policy string
 }
 
 // DefaultNetworkRetryConfig provides sensible default values for RetryConfig
 var DefaultNetworkRetryConfig = NetworkRetryConfig{
-	MaxAttempts: 480,
-	SleepTime:   1 * time.Minute,
-	MaxWaitTime: 8 * time.Hour,
+	MaxAttempts:  480,
+	SleepTime:    1 * time.Minute,
+	MaxWaitTime:  8 * time.Hour,
+	GrowthFactor: 1,
+}
+
+func (config NetworkRetryConfig) loopSpec() loopSpec {
+	return loopSpec{
+		kind:           "network",
+		label:          config.Label,
+		policy:         config.Policy,
+		maxAttempts:    config.MaxAttempts,
+		sleepTime:      config.SleepTime,
+		maxWaitTime:    config.MaxWaitTime,
+		growthFactor:   config.GrowthFactor,
+		maxSleep:       config.MaxSleep,
+		minIntervalKey: config.MinIntervalKey,
+		retryable:      httpext.IsDialError,
+		retryMsg:       "Network unreachable, retrying",
+	}
 }
 
 // OnNetworkError retries the given function with a standard wait time on network errors with default configuration
@@ -35,44 +63,14 @@ func OnNetworkError[T any](ctx context.Context, f func(context.Context) (T, erro
 // OnNetworkErrorWithConfig retries the given function with a standard wait time on network errors
 func OnNetworkErrorWithConfig[T any](ctx context.Context, f func(context.Context) (T, error), config NetworkRetryConfig) (T, error) {
 	var result T
-	var err error
-
-	startTime := time.Now()
-	attempt := 0
-	waitDuration := config.SleepTime
 
-	for {
-		select {
-		case <-ctx.Done():
-			slog.Info("Context cancelled, aborting retry", "error", ctx.Err())
-			return result, ctx.Err()
-		default:
-			result, err = f(ctx)
-			if err == nil {
-				return result, nil
-			}
+	err := runLoop(ctx, config.loopSpec(), func(ctx context.Context) error {
+		var err error
+		result, err = f(ctx)
+		return err
+	})
 
-			if !httpext.IsDialError(err) {
-				return result, err
-			}
-
-			attempt++
-			if attempt >= config.MaxAttempts {
-				return result, fmt.Errorf("max retry attempts reached: %w", err)
-			}
-
-			if time.Since(startTime) > config.MaxWaitTime {
-				return result, fmt.Errorf("max wait time exceeded: %w", err)
-			}
-
-			slog.Info("Network unreachable, retrying",
-				"error", err,
-				"attempt", attempt,
-				"nextRetryIn", waitDuration,
-			)
-			time.Sleep(waitDuration)
-		}
-	}
+	return result, err
 }
 
 // OnNetworkErrorOnlyError retries the given function with a standard wait time on network errors with default configuration
@@ -87,43 +85,5 @@ func OnNetworkErrorOnlyError(ctx context.Context, f func(context.Context) error)
 
 // OnNetworkErrorWithConfigOnlyError retries the given function with a standard wait time on network errors
 func OnNetworkErrorWithConfigOnlyError(ctx context.Context, f func(context.Context) error, config NetworkRetryConfig) error {
-
-	var err error
-
-	startTime := time.Now()
-	attempt := 0
-	waitDuration := config.SleepTime
-
-	for {
-		select {
-		case <-ctx.Done():
-			slog.Info("Context cancelled, aborting retry", "error", ctx.Err())
-			return ctx.Err()
-		default:
-			err = f(ctx)
-			if err == nil {
-				return nil
-			}
-
-			if !httpext.IsDialError(err) {
-				return err
-			}
-
-			attempt++
-			if attempt >= config.MaxAttempts {
-				return fmt.Errorf("max retry attempts reached: %w", err)
-			}
-
-			if time.Since(startTime) > config.MaxWaitTime {
-				return fmt.Errorf("max wait time exceeded: %w", err)
-			}
-
-			slog.Info("Network unreachable, retrying",
-				"error", err,
-				"attempt", attempt,
-				"nextRetryIn", waitDuration,
-			)
-			time.Sleep(waitDuration)
-		}
-	}
+	return runLoop(ctx, config.loopSpec(), f)
 }
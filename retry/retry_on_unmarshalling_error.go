@@ -2,9 +2,7 @@ package retry
 
 import (
 	"context"
-	"fmt"
 	"github.com/mhpenta/app/jsonext"
-	"log/slog"
 
 	"time"
 )
@@ -14,6 +12,14 @@ type UnmarshallingRetryConfig struct {
 	MaxAttempts int
 	SleepTime   time.Duration
 	MaxWaitTime time.Duration
+	// Label names the operation in logs and in the RetryError returned when the loop gives up. Defaults to the
+	// kind of loop.
+	Label string
+	// MinIntervalKey, when set, spaces attempts through DefaultIntervalGuard so that all loops sharing the key
+	// respect the floor configured with SetMinInterval.
+	MinIntervalKey string
+	// Policy, when set to a name registered with RegisterPolicy, overrides MaxAttempts, SleepTime and MaxWaitTime.
+	Policy string
 }
 
 // DefaultUnmarshallingErrorRetryConfig provides sensible default values for RetryConfig
@@ -23,6 +29,20 @@ var DefaultUnmarshallingErrorRetryConfig = UnmarshallingRetryConfig{
 	MaxWaitTime: 30 * time.Minute,
 }
 
+func (config UnmarshallingRetryConfig) loopSpec() loopSpec {
+	return loopSpec{
+		kind:           "unmarshalling",
+		label:          config.Label,
+		policy:         config.Policy,
+		maxAttempts:    config.MaxAttempts,
+		sleepTime:      config.SleepTime,
+		maxWaitTime:    config.MaxWaitTime,
+		minIntervalKey: config.MinIntervalKey,
+		retryable:      jsonext.IsUnmarshallingError,
+		retryMsg:       "Connection unreachable, retrying",
+	}
+}
+
 // OnUnmarshallingError retries the given function with a standard wait time on Connection errors with default configuration
 //
 // Function is designed to re-attempt a function if the error it encounters is a Connection error.
@@ -37,42 +57,12 @@ func OnUnmarshallingError[T any](ctx context.Context, f func(context.Context) (T
 // OnUnmarshallingErrorWithConfig retries the given function with a standard wait time on Connection errors
 func OnUnmarshallingErrorWithConfig[T any](ctx context.Context, f func(context.Context) (T, error), config UnmarshallingRetryConfig) (T, error) {
 	var result T
-	var err error
-
-	startTime := time.Now()
-	attempt := 0
-	waitDuration := config.SleepTime
 
-	for {
-		select {
-		case <-ctx.Done():
-			slog.Info("Context cancelled, aborting retry", "error", ctx.Err())
-			return result, ctx.Err()
-		default:
-			result, err = f(ctx)
-			if err == nil {
-				return result, nil
-			}
+	err := runLoop(ctx, config.loopSpec(), func(ctx context.Context) error {
+		var err error
+		result, err = f(ctx)
+		return err
+	})
 
-			if !jsonext.IsUnmarshallingError(err) {
-				return result, err
-			}
-
-			attempt++
-			if attempt >= config.MaxAttempts {
-				return result, fmt.Errorf("max retry attempts reached: %w", err)
-			}
-
-			if time.Since(startTime) > config.MaxWaitTime {
-				return result, fmt.Errorf("max wait time exceeded: %w", err)
-			}
-
-			slog.Info("Connection unreachable, retrying",
-				"error", err,
-				"attempt", attempt,
-				"nextRetryIn", waitDuration,
-			)
-			time.Sleep(waitDuration)
-		}
-	}
+	return result, err
 }
@@ -0,0 +1,74 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExecuteWaitsBackoffDuration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	config := Config{
+		Times:              3,
+		ExponentialBackoff: func(retryCount int) time.Duration { return 20 * time.Millisecond },
+	}
+
+	calls := 0
+	start := time.Now()
+	result, err := Execute(ctx, config, func(ctx context.Context) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errors.New("flaky")
+		}
+		return 42, nil
+	})
+	if err != nil || result != 42 {
+		t.Fatalf("Expected 42 after two retries, got %d, %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected two 20ms backoffs, waited %v", elapsed)
+	}
+
+	calls = 0
+	start = time.Now()
+	first, second, err := ExecuteWithTwoReturns(ctx, config, func(ctx context.Context) (int, string, error) {
+		calls++
+		if calls < 2 {
+			return 0, "", errors.New("flaky")
+		}
+		return 7, "filing", nil
+	})
+	if err != nil || first != 7 || second != "filing" {
+		t.Fatalf("Expected 7 and filing after a retry, got %d, %q, %v", first, second, err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected one 20ms backoff, waited %v", elapsed)
+	}
+}
+
+func TestExecuteEnforcesSleepFloor(t *testing.T) {
+	config := Config{
+		Times: 3,
+		ExponentialBackoff: func(int) time.Duration {
+			return 0
+		},
+		Yield: true,
+	}
+
+	start := time.Now()
+	calls := 0
+	_, err := Execute(context.Background(), config, func(ctx context.Context) (int, error) {
+		calls++
+		return 0, errors.New("busy")
+	})
+
+	if err == nil || calls != 3 {
+		t.Fatalf("Expected 3 failed calls, got %d (%v)", calls, err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*SleepFloor {
+		t.Errorf("Expected at least %s between attempts, took %s", 2*SleepFloor, elapsed)
+	}
+}
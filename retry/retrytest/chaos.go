@@ -0,0 +1,196 @@
+// Package retrytest provides a test HTTP server that injects network faults on a scripted schedule, so the error
+// classifiers in httpext and the retry loops in retry, and code built on them, can be verified end-to-end.
+package retrytest
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Fault is a failure injected by a ChaosServer into one request.
+type Fault int
+
+const (
+	// Pass serves the request with the server's handler
+	Pass Fault = iota
+	// Reset aborts the connection with a TCP RST, so the client sees "connection reset by peer"
+	Reset
+	// GoAway sends an HTTP/2 GOAWAY frame covering the request and closes the connection without answering it
+	GoAway
+	// Timeout never answers, holding the request until the client gives up or the server is closed
+	Timeout
+	// TooManyRequests answers 429 with a Retry-After header
+	TooManyRequests
+	// TruncatedBody announces a Content-Length and ends the response after half of the body
+	TruncatedBody
+)
+
+// String returns the name of the fault.
+func (f Fault) String() string {
+	switch f {
+	case Pass:
+		return "pass"
+	case Reset:
+		return "reset"
+	case GoAway:
+		return "goaway"
+	case Timeout:
+		return "timeout"
+	case TooManyRequests:
+		return "429"
+	case TruncatedBody:
+		return "truncated body"
+	}
+	return "Fault(" + strconv.Itoa(int(f)) + ")"
+}
+
+// ChaosServer is an HTTP/2 test server that applies one scripted Fault to each request in arrival order and serves
+// requests with its handler once the schedule is exhausted. Resets and GOAWAYs drop the whole connection, failing
+// any other request in flight on it, so schedules are easiest to reason about with sequential clients.
+//
+// Example usage:
+//
+//	srv := retrytest.NewChaosServer(handler, retrytest.Reset, retrytest.TooManyRequests, retrytest.GoAway)
+//	defer srv.Close()
+//
+//	client := srv.Client()
+//	client.Timeout = time.Second
+type ChaosServer struct {
+	*httptest.Server
+
+	// RetryAfter is the Retry-After value sent with TooManyRequests faults. Defaults to "0".
+	RetryAfter string
+
+	handler http.Handler
+	closed  chan struct{}
+
+	mu       sync.Mutex
+	schedule []Fault
+	injected []Fault
+}
+
+type connKey struct{}
+
+const resetSettle = 10 * time.Millisecond
+
+// NewChaosServer starts a TLS server speaking HTTP/2 that injects schedule into the first requests before passing
+// them to handler. A nil handler answers 200 with an "ok" body. Use Client to get a client trusting the server.
+func NewChaosServer(handler http.Handler, schedule ...Fault) *ChaosServer {
+	if handler == nil {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		})
+	}
+
+	s := &ChaosServer{
+		RetryAfter: "0",
+		handler:    handler,
+		closed:     make(chan struct{}),
+		schedule:   append([]Fault(nil), schedule...),
+	}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	s.Server.EnableHTTP2 = true
+	s.Server.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		return context.WithValue(ctx, connKey{}, c)
+	}
+	s.Server.StartTLS()
+	return s
+}
+
+// Script appends faults to the schedule.
+func (s *ChaosServer) Script(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedule = append(s.schedule, faults...)
+}
+
+// Injected returns the fault applied to each request received so far, in arrival order.
+func (s *ChaosServer) Injected() []Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Fault(nil), s.injected...)
+}
+
+// Close releases requests held by Timeout faults and shuts the server down.
+func (s *ChaosServer) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	s.mu.Unlock()
+	s.Server.Close()
+}
+
+func (s *ChaosServer) next() Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fault := Pass
+	if len(s.schedule) > 0 {
+		fault = s.schedule[0]
+		s.schedule = s.schedule[1:]
+	}
+	s.injected = append(s.injected, fault)
+	return fault
+}
+
+func (s *ChaosServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch s.next() {
+	case Reset:
+		conn := r.Context().Value(connKey{}).(net.Conn)
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			conn = tlsConn.NetConn()
+		}
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.SetLinger(0)
+		}
+		// Let the client finish writing the HTTP/2 settings exchange. A client whose write fails on the reset
+		// connection reports EOF rather than the reset.
+		time.Sleep(resetSettle)
+		conn.Close()
+		panic(http.ErrAbortHandler)
+
+	case GoAway:
+		conn := r.Context().Value(connKey{}).(net.Conn)
+		if r.ProtoMajor == 2 {
+			// The frame claims every stream as received, so the client surfaces the GOAWAY instead of silently
+			// retrying the request on a new connection.
+			frame := make([]byte, 9+8)
+			frame[2] = 8
+			frame[3] = 0x7
+			binary.BigEndian.PutUint32(frame[9:], 1<<31-1)
+			_, _ = conn.Write(frame)
+		}
+		conn.Close()
+		panic(http.ErrAbortHandler)
+
+	case Timeout:
+		select {
+		case <-r.Context().Done():
+		case <-s.closed:
+		}
+
+	case TooManyRequests:
+		w.Header().Set("Retry-After", s.RetryAfter)
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+
+	case TruncatedBody:
+		body := []byte(fmt.Sprintf("%064d", 0))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body[:len(body)/2])
+
+	default:
+		s.handler.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"github.com/mhpenta/app"
+	"log/slog"
+	"time"
+)
+
+// Decisions reported by the per-attempt trace logs.
+const (
+	decisionRetry     = "retry"
+	decisionPermanent = "permanent"
+	decisionExhausted = "exhausted"
+)
+
+// traceEnabled reports whether retry loops log every attempt, which they do when app.Mode is app.DebugMode. In other
+// modes only the terse per-retry Info lines are written.
+func traceEnabled() bool {
+	return app.Mode == app.DebugMode
+}
+
+// attemptTrace describes one failed attempt for the debug trace.
+type attemptTrace struct {
+	label    string
+	attempt  int
+	err      error
+	decision string
+	// classifier names what made the decision: "marked" for app.MarkRetryable and app.MarkPermanent, otherwise the
+	// loop's classifier or budget
+	classifier        string
+	delay             time.Duration
+	remainingAttempts int
+	// remainingWait is the wait budget left, or negative when the loop has none
+	remainingWait time.Duration
+}
+
+func (t attemptTrace) log() {
+	if !traceEnabled() {
+		return
+	}
+
+	attrs := []interface{}{
+		"label", t.label,
+		"attempt", t.attempt,
+		"error", t.err,
+		"decision", t.decision,
+		"classifier", t.classifier,
+		"remainingAttempts", t.remainingAttempts,
+	}
+	if t.decision == decisionRetry {
+		attrs = append(attrs, "delay", t.delay)
+	}
+	if t.remainingWait >= 0 {
+		attrs = append(attrs, "remainingWait", t.remainingWait)
+	}
+	slog.Debug("Retry attempt failed", attrs...)
+}
+
+// traceSuccess logs a loop that succeeded after at least one failed attempt.
+func traceSuccess(label string, attempts int, elapsed time.Duration) {
+	if !traceEnabled() || attempts < 2 {
+		return
+	}
+	slog.Debug("Retry loop succeeded", "label", label, "attempts", attempts, "elapsed", elapsed)
+}
+
+// traceExecuteAttempt logs the i-th failed attempt of an Execute loop limited to times attempts.
+func traceExecuteAttempt(i int, times int, err error, delay time.Duration) {
+	trace := attemptTrace{
+		label:             "execute",
+		attempt:           i + 1,
+		err:               err,
+		decision:          decisionRetry,
+		classifier:        "any error",
+		delay:             delay,
+		remainingAttempts: times - i - 1,
+		remainingWait:     -1,
+	}
+	switch {
+	case isPermanent(err):
+		trace.decision, trace.classifier = decisionPermanent, "marked"
+	case i == times-1:
+		trace.decision, trace.classifier = decisionExhausted, ReasonMaxAttempts
+	}
+	trace.log()
+}
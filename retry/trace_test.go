@@ -0,0 +1,44 @@
+package retry
+
+import (
+	"context"
+	"github.com/mhpenta/app"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugModeTracesAttempts(t *testing.T) {
+	app.TestMode(t)
+	var buf strings.Builder
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	previousMode := app.Mode
+	app.Mode = app.DebugMode
+	defer func() { app.Mode = previousMode }()
+
+	config := ConnectionRetryConfig{MaxAttempts: 2, SleepTime: time.Second, MaxWaitTime: time.Hour}
+	OnConnectionErrorSimpleWithConfig(context.Background(), func() error {
+		return dialError()
+	}, config)
+
+	out := buf.String()
+	if strings.Count(out, "Retry attempt failed") != 2 {
+		t.Fatalf("Expected a trace line per attempt, got %s", out)
+	}
+	for _, want := range []string{"decision=retry", "delay=1s", "remainingAttempts=1", "decision=exhausted", `classifier="max attempts"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %s in the trace, got %s", want, out)
+		}
+	}
+
+	buf.Reset()
+	app.Mode = app.ReleaseMode
+	OnConnectionErrorSimpleWithConfig(context.Background(), func() error {
+		return dialError()
+	}, config)
+	if strings.Contains(buf.String(), "Retry attempt failed") {
+		t.Error("Expected no trace outside debug mode")
+	}
+}
@@ -0,0 +1,180 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"log/slog"
+	"time"
+)
+
+// ErrConditionTimeout is matched by errors.Is for errors returned by Until when the condition is still not met once
+// the timeout or poll budget is spent.
+var ErrConditionTimeout = errors.New("condition not met")
+
+// ErrNotYet may be wrapped by the error a condition returns to report what it observed while not done, e.g.
+// fmt.Errorf("%w: job is %s", retry.ErrNotYet, status). Until keeps polling and reports the last such state in the
+// ConditionTimeoutError.
+var ErrNotYet = errors.New("not yet")
+
+// UntilConfig holds configuration for Until
+type UntilConfig struct {
+	// Interval is the wait between the first polls
+	Interval time.Duration
+	// GrowthFactor multiplies the interval after each poll. Values below 1 keep the interval constant.
+	GrowthFactor float64
+	// MaxInterval caps the interval reached through GrowthFactor. Zero means no cap.
+	MaxInterval time.Duration
+	// Timeout bounds the time from the first poll until Until gives up. The last wait is shortened so a final poll
+	// happens at the timeout.
+	Timeout time.Duration
+	// MaxPolls, when positive, also bounds the number of polls
+	MaxPolls int
+	// Label names the condition in logs and in the ConditionTimeoutError. Defaults to "until".
+	Label string
+}
+
+// DefaultUntilConfig provides sensible default values for UntilConfig
+var DefaultUntilConfig = UntilConfig{
+	Interval:     time.Second,
+	GrowthFactor: 1.5,
+	MaxInterval:  30 * time.Second,
+	Timeout:      5 * time.Minute,
+}
+
+// ConditionTimeoutError is returned by Until when the condition is still not met after the configured timeout or
+// number of polls.
+type ConditionTimeoutError struct {
+	// Label names the condition
+	Label string
+	// Polls is the number of times the condition was checked
+	Polls int
+	// Elapsed is the time from the first poll until Until gave up
+	Elapsed time.Duration
+	// LastState is the last not-yet state the condition reported, nil when it only returned false
+	LastState error
+}
+
+// Error renders the error as "until[label]: condition not met after 12 polls over 5m0s: last state: ...".
+func (e *ConditionTimeoutError) Error() string {
+	msg := fmt.Sprintf("until[%s]: %s after %d polls over %s", e.Label, ErrConditionTimeout, e.Polls, e.Elapsed.Round(time.Millisecond))
+	if e.LastState != nil {
+		msg += fmt.Sprintf(": last state: %v", e.LastState)
+	}
+	return msg
+}
+
+// Is reports whether target is ErrConditionTimeout.
+func (e *ConditionTimeoutError) Is(target error) bool {
+	return target == ErrConditionTimeout
+}
+
+// Unwrap returns the last state reported by the condition.
+func (e *ConditionTimeoutError) Unwrap() error {
+	return e.LastState
+}
+
+// Until polls cond until it reports done, backing off between polls as configured, for waits such as a job reaching
+// a final status, a file appearing or a port opening. Zero fields of config are filled from DefaultUntilConfig.
+//
+// cond separates "not yet" from "failed":
+//   - (true, nil) stops polling and Until returns nil
+//   - (false, nil) polls again
+//   - (false, err) with err wrapping ErrNotYet or marked with app.MarkRetryable polls again, recording err as the last
+//     observed state
+//   - any other error stops polling and is returned as is
+//
+// When the timeout or MaxPolls is reached first, Until returns a *ConditionTimeoutError matching ErrConditionTimeout.
+// A cancelled ctx returns ctx.Err().
+//
+// Example usage:
+//
+//	err := retry.Until(ctx, retry.DefaultUntilConfig, func(ctx context.Context) (bool, error) {
+//		status, err := jobs.Status(ctx, id)
+//		if err != nil {
+//			return false, err
+//		}
+//		if status == "failed" {
+//			return false, app.MarkPermanent(fmt.Errorf("job %s failed", id))
+//		}
+//		if status != "done" {
+//			return false, fmt.Errorf("%w: job is %s", retry.ErrNotYet, status)
+//		}
+//		return true, nil
+//	})
+func Until(ctx context.Context, config UntilConfig, cond func(ctx context.Context) (done bool, err error)) error {
+	config = config.withDefaults()
+	if KillSwitch() == KillSwitchFailFast {
+		return ErrRetriesDisabled
+	}
+
+	state := trackLoop(loopSpec{kind: "until", label: config.Label})
+	defer untrackLoop(state)
+
+	var lastState error
+	startTime := app.Now()
+	interval := config.Interval
+
+	for polls := 1; ; polls++ {
+		if err := ctx.Err(); err != nil {
+			slog.Info("Context cancelled, aborting poll", "label", config.Label, "error", err)
+			return err
+		}
+
+		done, err := cond(ctx)
+		if err != nil && !isNotYet(err) {
+			return err
+		}
+		if done && err == nil {
+			traceSuccess(config.Label, polls, app.Since(startTime))
+			return nil
+		}
+		if err != nil {
+			lastState = err
+			updateLoop(state, polls, err)
+		}
+
+		remaining := config.Timeout - app.Since(startTime)
+		if remaining <= 0 || (config.MaxPolls > 0 && polls >= config.MaxPolls) || KillSwitch() == KillSwitchSingleAttempt {
+			return &ConditionTimeoutError{Label: config.Label, Polls: polls, Elapsed: app.Since(startTime), LastState: lastState}
+		}
+
+		slog.Debug("Condition not met, polling again", "label", config.Label, "poll", polls, "state", lastState, "nextPollIn", interval)
+		select {
+		case <-ctx.Done():
+			slog.Info("Context cancelled, aborting poll", "label", config.Label, "error", ctx.Err())
+			return ctx.Err()
+		case <-app.After(min(floorDelay(interval), remaining)):
+		}
+		interval = nextSleep(interval, config.GrowthFactor, config.MaxInterval)
+	}
+}
+
+func (config UntilConfig) withDefaults() UntilConfig {
+	if config.Interval <= 0 {
+		config.Interval = DefaultUntilConfig.Interval
+	}
+	if config.GrowthFactor == 0 {
+		config.GrowthFactor = DefaultUntilConfig.GrowthFactor
+	}
+	if config.MaxInterval == 0 {
+		config.MaxInterval = DefaultUntilConfig.MaxInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultUntilConfig.Timeout
+	}
+	if config.Label == "" {
+		config.Label = "until"
+	}
+	return config
+}
+
+// isNotYet reports whether a condition error describes a state worth polling again.
+func isNotYet(err error) bool {
+	if errors.Is(err, ErrNotYet) {
+		return true
+	}
+	retryable, marked := app.IsRetryable(err)
+	return marked && retryable
+}
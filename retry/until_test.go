@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mhpenta/app"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUntil(t *testing.T) {
+	clock := app.TestMode(t)
+	config := UntilConfig{Interval: time.Second, GrowthFactor: 2, MaxInterval: 4 * time.Second, Timeout: 10 * time.Second}
+
+	polls := 0
+	err := Until(context.Background(), config, func(ctx context.Context) (bool, error) {
+		polls++
+		if polls < 3 {
+			return false, fmt.Errorf("%w: job is running", ErrNotYet)
+		}
+		return true, nil
+	})
+	if err != nil || polls != 3 {
+		t.Fatalf("Expected success on the third poll, got %d polls, %v", polls, err)
+	}
+	if waited := clock.Now().Sub(app.TestModeStart); waited != 3*time.Second {
+		t.Errorf("Expected waits of 1s and 2s, got %s", waited)
+	}
+
+	start := clock.Now()
+	err = Until(context.Background(), config, func(ctx context.Context) (bool, error) {
+		return false, fmt.Errorf("%w: job is queued", ErrNotYet)
+	})
+	var timeoutErr *ConditionTimeoutError
+	if !errors.Is(err, ErrConditionTimeout) || !errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected ErrConditionTimeout, got %v", err)
+	}
+	if timeoutErr.Polls != 5 || timeoutErr.Elapsed != 10*time.Second || !strings.Contains(timeoutErr.LastState.Error(), "queued") {
+		t.Errorf("Expected 5 polls over 10s ending queued, got %+v", timeoutErr)
+	}
+	if waited := clock.Now().Sub(start); waited != 10*time.Second {
+		t.Errorf("Expected the last wait shortened to the timeout, got %s", waited)
+	}
+
+	failure := errors.New("job failed")
+	polls = 0
+	err = Until(context.Background(), config, func(ctx context.Context) (bool, error) {
+		polls++
+		return false, failure
+	})
+	if err != failure || polls != 1 {
+		t.Errorf("Expected the failure returned after one poll, got %d polls, %v", polls, err)
+	}
+
+	err = Until(context.Background(), UntilConfig{MaxPolls: 2}, func(ctx context.Context) (bool, error) {
+		return false, app.MarkRetryable(errors.New("probe refused"))
+	})
+	if !errors.As(err, &timeoutErr) || timeoutErr.Polls != 2 {
+		t.Errorf("Expected a timeout after two polls, got %v", err)
+	}
+}
+
+func TestUntilReturnsWhenCancelledWhileWaiting(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := Until(ctx, UntilConfig{Interval: time.Minute}, func(ctx context.Context) (bool, error) {
+		return false, ErrNotYet
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the wait cut short by the context, waited %v", elapsed)
+	}
+}
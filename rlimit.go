@@ -0,0 +1,7 @@
+package app
+
+import "errors"
+
+// ErrFileDescriptorLimit is returned by EnsureFileDescriptorLimit when the open file limit cannot be raised to the
+// requested minimum.
+var ErrFileDescriptorLimit = errors.New("file descriptor limit too low")
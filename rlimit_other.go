@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package app
+
+// EnsureFileDescriptorLimit is a no-op on platforms without RLIMIT_NOFILE.
+func EnsureFileDescriptorLimit(required uint64) error {
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build linux || darwin
+
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"syscall"
+)
+
+// EnsureFileDescriptorLimit makes sure the process may open at least required files. It raises the RLIMIT_NOFILE soft
+// limit toward the hard limit when the soft limit is lower than required, and returns an error wrapping
+// ErrFileDescriptorLimit when the hard limit is too low or the limit cannot be changed. Call it early in main, since
+// a low limit shows up as "too many open files" cascades once retries pile up connections.
+func EnsureFileDescriptorLimit(required uint64) error {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return fmt.Errorf("%w: reading RLIMIT_NOFILE: %v", ErrFileDescriptorLimit, err)
+	}
+
+	if limit.Cur >= required {
+		return nil
+	}
+
+	if limit.Max < required {
+		slog.Error("File descriptor hard limit below requirement", "soft", limit.Cur, "hard", limit.Max, "required", required)
+		return fmt.Errorf("%w: hard limit %d is below required %d", ErrFileDescriptorLimit, limit.Max, required)
+	}
+
+	previous := limit.Cur
+	limit.Cur = limit.Max
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		// Some systems (macOS) refuse an unlimited soft limit, so fall back to exactly what was asked for.
+		limit.Cur = required
+		if err = syscall.Setrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+			slog.Error("Unable to raise file descriptor limit", "soft", previous, "hard", limit.Max, "required", required, "error", err)
+			return fmt.Errorf("%w: raising soft limit from %d to %d: %v", ErrFileDescriptorLimit, previous, required, err)
+		}
+	}
+
+	slog.Info("Raised file descriptor limit", "from", previous, "to", limit.Cur, "hard", limit.Max)
+	return nil
+}
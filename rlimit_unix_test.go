@@ -0,0 +1,46 @@
+//go:build linux || darwin
+
+package app
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+// TestEnsureFileDescriptorLimit tests raising the soft limit and rejecting requirements above the hard limit
+func TestEnsureFileDescriptorLimit(t *testing.T) {
+	var original syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &original); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = syscall.Setrlimit(syscall.RLIMIT_NOFILE, &original)
+	})
+	if original.Max < 256 {
+		t.Skipf("Hard limit %d too low to test", original.Max)
+	}
+
+	lowered := syscall.Rlimit{Cur: 128, Max: original.Max}
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &lowered); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EnsureFileDescriptorLimit(64); err != nil {
+		t.Errorf("Expected a satisfied requirement to pass, got %v", err)
+	}
+	if err := EnsureFileDescriptorLimit(256); err != nil {
+		t.Fatalf("Expected the soft limit raised, got %v", err)
+	}
+	var current syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &current); err != nil || current.Cur < 256 {
+		t.Errorf("Expected a soft limit of at least 256, got %d, %v", current.Cur, err)
+	}
+
+	if original.Max == ^uint64(0) {
+		return
+	}
+	if err := EnsureFileDescriptorLimit(original.Max + 1); !errors.Is(err, ErrFileDescriptorLimit) {
+		t.Errorf("Expected ErrFileDescriptorLimit above the hard limit, got %v", err)
+	}
+}
@@ -1,13 +1,12 @@
 package app
 
 import (
-	"math/rand/v2"
 	"time"
 )
 
 // SleepMinPlusRandom sleeps for a duration that is randomly adjusted to be between the original duration and up to double that duration.
 func SleepMinPlusRandom(minDuration time.Duration) {
-	time.Sleep(time.Duration(float64(minDuration) * (1 + float64(rand.N(100))/100)))
+	Sleep(time.Duration(float64(minDuration) * (1 + float64(RandN(100))/100)))
 }
 
 // ReturnTrueXPercentOfTime returns true with a probability equal to the given percentage.
@@ -20,7 +19,8 @@ func SleepMinPlusRandom(minDuration time.Duration) {
 //	    fmt.Println("This will print approximately 25% of the time")
 //	}
 //
-// This function uses math/rand/v2, which does not require manual seeding.
+// This function uses math/rand/v2, which does not require manual seeding, unless SeedRandom or TestMode made it
+// deterministic.
 func ReturnTrueXPercentOfTime(percentage float64) bool {
-	return rand.Float64() < percentage
+	return RandFloat64() < percentage
 }
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// osExit is replaced in tests.
+var osExit = os.Exit
+
+// Run is the harness for a main function. It runs the initializers added with RegisterInit, calls main with
+// MainContext, converts a panic into an error with FromPanic, logs the failure and exits the process with
+// ExitCode(err).
+//
+// Example usage:
+//
+//	func main() {
+//		app.Run(func(ctx context.Context) error {
+//			cfg, err := loadConfig()
+//			if err != nil {
+//				return fmt.Errorf("%w: %v", app.ErrConfig, err)
+//			}
+//			return serve(ctx, cfg)
+//		})
+//	}
+func Run(main func(ctx context.Context) error) {
+	ctx, cancel := MainContext()
+	err := runInits(ctx)
+	if err == nil {
+		err = runMain(ctx, main)
+	}
+	cancel()
+
+	code := ExitCode(err)
+	if err != nil {
+		slog.Error("Application exited with error", "error", err, "exitCode", code)
+	}
+	osExit(code)
+}
+
+func runMain(ctx context.Context, main func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = FromPanic(r)
+		}
+	}()
+	return main(ctx)
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestRun tests that Run exits with the code of the returned error and recovers panics
+func TestRun(t *testing.T) {
+	var code int
+	osExit = func(c int) { code = c }
+	defer func() { osExit = os.Exit }()
+
+	Run(func(ctx context.Context) error {
+		return fmt.Errorf("%w: bad flag", ErrConfig)
+	})
+	if code != ExitConfig {
+		t.Errorf("Expected exit code %d, got %d", ExitConfig, code)
+	}
+
+	Run(func(ctx context.Context) error {
+		panic("boom")
+	})
+	if code != ExitInternal {
+		t.Errorf("Expected exit code %d after panic, got %d", ExitInternal, code)
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import "sync"
+
+// SafeMultiError is a MultiError that can be shared across goroutines. The zero value is ready to use.
+//
+// Example usage:
+//
+//	var errs app.SafeMultiError
+//	var wg sync.WaitGroup
+//	for _, job := range jobs {
+//		wg.Add(1)
+//		go func(job Job) {
+//			defer wg.Done()
+//			errs.Append(job.Run())
+//		}(job)
+//	}
+//	wg.Wait()
+//	return errs.ErrorOrNil()
+type SafeMultiError struct {
+	mu   sync.Mutex
+	errs MultiError
+}
+
+// Append adds err, ignoring nil errors.
+func (s *SafeMultiError) Append(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs.Append(err)
+}
+
+// AppendWrapped appends err wrapped as "op: err", see MultiError.AppendWrapped.
+func (s *SafeMultiError) AppendWrapped(op string, err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs.AppendWrapped(op, err)
+}
+
+// Error returns the messages of all errors appended so far.
+func (s *SafeMultiError) Error() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errs.Error()
+}
+
+// ErrorOrNil returns nil if no errors were appended, otherwise a snapshot *MultiError that later appends do not
+// modify.
+func (s *SafeMultiError) ErrorOrNil() error {
+	return s.Snapshot().ErrorOrNil()
+}
+
+// HasErrors reports whether any error was appended.
+func (s *SafeMultiError) HasErrors() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errs.HasErrors()
+}
+
+// Len returns the number of errors appended.
+func (s *SafeMultiError) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.errs.Errors)
+}
+
+// Unwrap returns a copy of the errors appended so far.
+func (s *SafeMultiError) Unwrap() []error {
+	return s.Snapshot().Unwrap()
+}
+
+// Snapshot returns a *MultiError holding a copy of the errors appended so far.
+func (s *SafeMultiError) Snapshot() *MultiError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := NewMultiError(s.errs.Errors...)
+	snapshot.MaxErrors = s.errs.MaxErrors
+	snapshot.Unique = s.errs.Unique
+	snapshot.dropped = s.errs.dropped
+	return snapshot
+}
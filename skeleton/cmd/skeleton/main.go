@@ -0,0 +1,23 @@
+// Command skeleton runs the service scaffold of package skeleton.
+//
+//	go run ./skeleton/cmd/skeleton -mode dev -upstream https://example.com/api/status
+package main
+
+import (
+	"context"
+	"github.com/mhpenta/app"
+	"github.com/mhpenta/app/skeleton"
+	"os"
+)
+
+func init() {
+	app.RegisterInit("file descriptors", func(ctx context.Context) error {
+		return app.EnsureFileDescriptorLimit(4096)
+	})
+}
+
+func main() {
+	app.Run(func(ctx context.Context) error {
+		return skeleton.Main(ctx, os.Args[1:])
+	})
+}
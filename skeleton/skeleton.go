@@ -0,0 +1,273 @@
+// Package skeleton is a runnable service scaffold wiring the subsystems of this module together: flags and logger
+// setup, app.Run and app.MainContext, health endpoints, the admin mux, a retrying HTTP client and graceful shutdown.
+// It doubles as living documentation; copy it as the starting point of a new service, or run cmd/skeleton as is.
+package skeleton
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mhpenta/app"
+	"github.com/mhpenta/app/httpext"
+	"github.com/mhpenta/app/retry"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config holds configuration for the Service
+type Config struct {
+	// Addr is the address the service listens on
+	Addr string
+	// AdminAddr is the address of the admin endpoints, see httpext.NewAdminMux. Empty disables them. The retry kill
+	// switch is served there unauthenticated in every mode, so keep it on an interface only operators can reach.
+	AdminAddr string
+	// Mode is the application mode, see app.Mode
+	Mode app.ApplicationMode
+	// LogLevel is the minimum level logged
+	LogLevel slog.Level
+	// LogJSON logs JSON lines instead of text
+	LogJSON bool
+	// UpstreamURL is fetched by the /upstream endpoint through the retrying client. Empty disables the endpoint.
+	UpstreamURL string
+	// ShutdownTimeout bounds the time in-flight requests get to finish once the service is asked to stop
+	ShutdownTimeout time.Duration
+}
+
+// DefaultConfig provides sensible default values for Config
+var DefaultConfig = Config{
+	Addr:            ":8080",
+	AdminAddr:       "localhost:6061",
+	Mode:            app.ReleaseMode,
+	LogLevel:        slog.LevelInfo,
+	ShutdownTimeout: 15 * time.Second,
+}
+
+// ParseFlags parses the command line arguments, without the program name, into a Config starting from
+// DefaultConfig. Invalid arguments return an error wrapping app.ErrConfig, so app.Run exits with app.ExitConfig.
+func ParseFlags(args []string) (Config, error) {
+	config := DefaultConfig
+	mode := string(config.Mode)
+
+	fs := flag.NewFlagSet("skeleton", flag.ContinueOnError)
+	fs.StringVar(&config.Addr, "addr", config.Addr, "address to listen on")
+	fs.StringVar(&config.AdminAddr, "admin-addr", config.AdminAddr, "address of the admin endpoints, empty to disable")
+	fs.StringVar(&mode, "mode", mode, "application mode: release, dev or debug")
+	fs.TextVar(&config.LogLevel, "log-level", config.LogLevel, "minimum log level: debug, info, warn or error")
+	fs.BoolVar(&config.LogJSON, "log-json", config.LogJSON, "log JSON lines instead of text")
+	fs.StringVar(&config.UpstreamURL, "upstream", config.UpstreamURL, "URL fetched by /upstream")
+	fs.DurationVar(&config.ShutdownTimeout, "shutdown-timeout", config.ShutdownTimeout, "time given to in-flight requests on shutdown")
+	if err := fs.Parse(args); err != nil {
+		return config, fmt.Errorf("%w: %v", app.ErrConfig, err)
+	}
+
+	switch config.Mode = app.ApplicationMode(mode); config.Mode {
+	case app.ReleaseMode, app.DevMode, app.DebugMode:
+	default:
+		return config, fmt.Errorf("%w: unknown mode %q", app.ErrConfig, mode)
+	}
+	if config.ShutdownTimeout <= 0 {
+		return config, fmt.Errorf("%w: shutdown timeout must be positive, got %s", app.ErrConfig, config.ShutdownTimeout)
+	}
+	return config, nil
+}
+
+// NewLogger returns a logger writing to w at config.LogLevel, as JSON lines when config.LogJSON is set.
+func NewLogger(w io.Writer, config Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: config.LogLevel}
+	if config.LogJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Main is the main function of the scaffold, meant to be passed to app.Run:
+//
+//	func main() {
+//		app.Run(func(ctx context.Context) error {
+//			return skeleton.Main(ctx, os.Args[1:])
+//		})
+//	}
+//
+// It parses the flags, installs the logger and application mode, and runs a Service until ctx is cancelled.
+func Main(ctx context.Context, args []string) error {
+	config, err := ParseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	app.Mode = config.Mode
+	slog.SetDefault(NewLogger(os.Stderr, config))
+	app.StartClockSkewMonitor(ctx, time.Second)
+
+	return New(config).Run(ctx)
+}
+
+// Service is the HTTP service run by Main. It answers /healthz while the process is up, /readyz while it accepts
+// traffic, and /upstream with the payload fetched from Config.UpstreamURL.
+type Service struct {
+	config Config
+	client *http.Client
+	slo    *httpext.SLOTracker
+
+	ready atomic.Bool
+	mu    sync.Mutex
+	addr  net.Addr
+}
+
+// New creates a Service from config. Its HTTP client retries transient network errors, shares the per-host circuit
+// breakers and records the latency of upstream calls, which /readyz reports on.
+func New(config Config) *Service {
+	slo := httpext.NewSLOTracker(httpext.DefaultSLOConfig)
+	retryConfig := httpext.DefaultRetryTransportConfig
+
+	clientConfig := httpext.DefaultClientConfig
+	clientConfig.TracePhases = true
+	clientConfig.Retry = &retryConfig
+	clientConfig.SLO = slo
+
+	return &Service{
+		config: config,
+		client: httpext.NewClient(clientConfig),
+		slo:    slo,
+	}
+}
+
+// Ready reports whether the service is listening and not shutting down.
+func (s *Service) Ready() bool {
+	return s.ready.Load()
+}
+
+// Addr returns the address the service listens on, nil before Run has started listening.
+func (s *Service) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// Handler returns the routes of the service, with panics recovered by httpext.RecoverMiddleware.
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+	mux.HandleFunc("/readyz", s.readyz)
+	mux.Handle("/upstream", httpext.HandlerFunc(s.upstream))
+	return httpext.RecoverMiddleware(mux)
+}
+
+func (s *Service) readyz(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case !s.Ready():
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+	case app.InSafeMode():
+		http.Error(w, "safe mode", http.StatusServiceUnavailable)
+	default:
+		if err := s.slo.Healthy(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ready")
+	}
+}
+
+func (s *Service) upstream(w http.ResponseWriter, r *http.Request) error {
+	if s.config.UpstreamURL == "" {
+		return app.NewMetaError(errors.New("no upstream configured")).WithHTTPStatus(http.StatusNotFound)
+	}
+
+	// The client already retries transient network errors; this loop retries the responses worth another try.
+	config := retry.Config{Times: 3, ExponentialBackoff: func(retryCount int) time.Duration {
+		return time.Duration(retryCount) * 250 * time.Millisecond
+	}}
+	payload, err := retry.Execute(r.Context(), config, func(ctx context.Context) (interface{}, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.UpstreamURL, nil)
+		if err != nil {
+			return nil, app.MarkPermanent(err)
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, app.MarkPermanent(err)
+		}
+
+		var payload interface{}
+		err = httpext.DecodeByContentType(resp, &payload)
+		if errors.Is(err, httpext.ErrUnexpectedStatus) && !httpext.IsRetryableStatus(err) {
+			return nil, app.MarkPermanent(err)
+		}
+		return payload, err
+	})
+	if err != nil {
+		return app.NewMetaError(err).WithHTTPStatus(http.StatusBadGateway)
+	}
+
+	httpext.WriteJSON(w, http.StatusOK, payload)
+	return nil
+}
+
+// Run serves the service, and the admin endpoints when configured, until ctx is cancelled or a listener fails. It
+// then reports not ready and gives in-flight requests Config.ShutdownTimeout to finish.
+func (s *Service) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.config.Addr, err)
+	}
+
+	servers := []*http.Server{{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}}
+	listeners := []net.Listener{listener}
+
+	if s.config.AdminAddr != "" {
+		adminListener, err := net.Listen("tcp", s.config.AdminAddr)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("listen on %s: %w", s.config.AdminAddr, err)
+		}
+		mux := httpext.NewAdminMux()
+		retry.RegisterAdminRoutes(mux)
+		retry.RegisterKillSwitchRoute(mux, nil)
+		servers = append(servers, &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second})
+		listeners = append(listeners, adminListener)
+	}
+
+	serveErrs := make(chan error, len(servers))
+	for i, server := range servers {
+		go func(server *http.Server, listener net.Listener) {
+			if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				serveErrs <- err
+			}
+		}(server, listeners[i])
+	}
+
+	s.mu.Lock()
+	s.addr = listener.Addr()
+	s.mu.Unlock()
+	s.ready.Store(true)
+	slog.Info("Service listening", "addr", listener.Addr(), "admin", s.config.AdminAddr, "mode", app.Mode)
+
+	var errs app.MultiError
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down", "timeout", s.config.ShutdownTimeout)
+	case err := <-serveErrs:
+		errs.AppendWrapped("serve", err)
+	}
+	s.ready.Store(false)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		errs.AppendWrapped("shutdown", server.Shutdown(shutdownCtx))
+	}
+	s.client.CloseIdleConnections()
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,104 @@
+package skeleton
+
+import (
+	"context"
+	"errors"
+	"github.com/mhpenta/app"
+	"github.com/mhpenta/app/retry"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestParseFlags tests flag parsing and the app.ErrConfig classification of invalid arguments
+func TestParseFlags(t *testing.T) {
+	config, err := ParseFlags([]string{"-addr", "127.0.0.1:9000", "-mode", "dev", "-log-level", "debug", "-admin-addr", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Addr != "127.0.0.1:9000" || config.Mode != app.DevMode || config.LogLevel != slog.LevelDebug || config.AdminAddr != "" {
+		t.Errorf("Unexpected config %+v", config)
+	}
+
+	for _, args := range [][]string{{"-mode", "staging"}, {"-shutdown-timeout", "0s"}, {"-unknown"}} {
+		if _, err := ParseFlags(args); !errors.Is(err, app.ErrConfig) || app.ExitCode(err) != app.ExitConfig {
+			t.Errorf("Expected a config error for %v, got %v", args, err)
+		}
+	}
+}
+
+// TestService tests the health endpoints, the upstream endpoint and graceful shutdown of a running service
+func TestService(t *testing.T) {
+	var missing atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if missing.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"status":"green"}`)
+	}))
+	defer upstream.Close()
+
+	config := DefaultConfig
+	config.Addr = "127.0.0.1:0"
+	config.AdminAddr = ""
+	config.UpstreamURL = upstream.URL
+	service := New(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- service.Run(ctx)
+	}()
+
+	err := retry.Until(ctx, retry.UntilConfig{Interval: 10 * time.Millisecond, Timeout: 5 * time.Second}, func(ctx context.Context) (bool, error) {
+		return service.Ready(), nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the service to become ready, got %v", err)
+	}
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get("http://" + service.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	if status, body := get("/healthz"); status != http.StatusOK || body != "ok" {
+		t.Errorf("Expected /healthz to answer ok, got %d %q", status, body)
+	}
+	if status, body := get("/readyz"); status != http.StatusOK || body != "ready" {
+		t.Errorf("Expected /readyz to answer ready, got %d %q", status, body)
+	}
+	if status, body := get("/upstream"); status != http.StatusOK || body != `{"status":"green"}` {
+		t.Errorf("Expected the upstream payload, got %d %q", status, body)
+	}
+
+	missing.Store(true)
+	if status, _ := get("/upstream"); status != http.StatusBadGateway {
+		t.Errorf("Expected 502 for a missing upstream resource, got %d", status)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to return after cancellation")
+	}
+	if service.Ready() {
+		t.Error("Expected the service to report not ready after shutdown")
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithSoftDeadline returns a context that is cancelled after hard, like context.WithTimeout, and logs a warning once
+// soft has passed while the operation is still running. The warning names the caller of WithSoftDeadline and, when ctx
+// derives from a DebugContext, its values, giving an early signal about slow operations before they actually fail.
+// A soft duration of zero or at least hard disables the warning.
+//
+// Example usage:
+//
+//	ctx, cancel := app.WithSoftDeadline(ctx, 5*time.Second, 30*time.Second)
+//	defer cancel()
+//	filings, err := fetchFilings(ctx, cik)
+func WithSoftDeadline(ctx context.Context, soft, hard time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, hard)
+	if soft <= 0 || soft >= hard {
+		return ctx, cancel
+	}
+
+	start := Now()
+	caller := Caller(1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-After(soft):
+		}
+
+		attrs := []any{
+			"soft", soft,
+			"hard", hard,
+			"elapsed", Since(start),
+			"file", caller.File,
+			"line", caller.Line,
+			"func", caller.Name,
+			"package", caller.PkgPath,
+		}
+		if d, ok := debugContextFrom(ctx); ok {
+			attrs = append(attrs, "debugValues", d.snapshot())
+		}
+		slog.Warn("Operation passed its soft deadline", attrs...)
+	}()
+
+	return ctx, cancel
+}
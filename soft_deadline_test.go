@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWithSoftDeadline tests the soft deadline warning, its debug values and the hard deadline
+func TestWithSoftDeadline(t *testing.T) {
+	TestMode(t)
+	var buf safeBuffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	debugCtx := (&DebugContext{Context: context.Background()}).WithValue("cik", "0000320193")
+	ctx, cancel := WithSoftDeadline(debugCtx, 5*time.Second, time.Minute)
+	defer cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), "Operation passed its soft deadline") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "soft=5s") || !strings.Contains(out, "elapsed=5s") ||
+		!strings.Contains(out, "func=TestWithSoftDeadline") || !strings.Contains(out, `debugValues="[cik=0000320193]"`) {
+		t.Errorf("Expected a warning naming the caller and debug values, got %s", out)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Expected the soft deadline not to cancel the context, got %v", ctx.Err())
+	}
+	if ctx.Value("cik") != "0000320193" {
+		t.Error("Expected the context to keep the parent values")
+	}
+
+	var quiet safeBuffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&quiet, nil)))
+	ctx, cancel = WithSoftDeadline(context.Background(), time.Second, time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("Expected the hard deadline to cancel the context, got %v", ctx.Err())
+	}
+	if quiet.String() != "" {
+		t.Errorf("Expected no warning when soft is not below hard, got %s", quiet.String())
+	}
+}
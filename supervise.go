@@ -0,0 +1,238 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrTooManyRestarts is wrapped by the error Supervise returns when a process keeps exiting after
+// RestartPolicy.MaxRestarts consecutive restarts.
+var ErrTooManyRestarts = errors.New("process restarted too many times")
+
+// maxOutputLine bounds the output of a supervised process buffered before a newline; longer lines are logged in
+// pieces.
+const maxOutputLine = 64 * 1024
+
+// CommandSpec describes a process run by Supervise.
+type CommandSpec struct {
+	// Name labels the process in logs. Defaults to the base name of Path.
+	Name string
+	Path string
+	Args []string
+	// Env, when non-nil, is the environment of the process, see exec.Cmd.Env
+	Env []string
+	Dir string
+	// StopSignal is sent to the process when the context is cancelled. Defaults to SIGTERM.
+	StopSignal os.Signal
+	// StopTimeout is how long the process has to exit after StopSignal before it is killed. Defaults to 10 seconds.
+	StopTimeout time.Duration
+}
+
+// RestartPolicy controls how Supervise restarts a process that exits.
+type RestartPolicy struct {
+	// MaxRestarts is the number of consecutive restarts allowed before Supervise gives up. Negative means unlimited.
+	MaxRestarts int
+	// InitialDelay is the delay before the first restart
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between restarts
+	MaxDelay time.Duration
+	// GrowthFactor multiplies the delay after every consecutive restart
+	GrowthFactor float64
+	// ResetAfter is how long a process must run to be considered healthy, resetting the delay and restart count
+	ResetAfter time.Duration
+	// RestartOnSuccess restarts processes that exit with status 0 too. Otherwise a clean exit ends Supervise.
+	RestartOnSuccess bool
+}
+
+// DefaultRestartPolicy provides sensible default values for RestartPolicy
+var DefaultRestartPolicy = RestartPolicy{
+	MaxRestarts:  10,
+	InitialDelay: time.Second,
+	MaxDelay:     time.Minute,
+	GrowthFactor: 2,
+	ResetAfter:   time.Minute,
+}
+
+// Supervise runs the process described by spec until ctx is cancelled, restarting it with growing delays when it
+// exits. Lines the process writes to stdout are logged at Info and lines written to stderr at Warn, labelled with
+// the process name. Zero fields of policy fall back to DefaultRestartPolicy.
+//
+// When ctx is cancelled, e.g. the MainContext passed by Run on SIGTERM, the process is sent spec.StopSignal, killed
+// if it is still running after spec.StopTimeout, and Supervise returns nil once it has exited. Supervise also
+// returns nil when the process exits cleanly and policy.RestartOnSuccess is false. It returns an error when the
+// process cannot be started, or one wrapping ErrTooManyRestarts and the last exit error when the restart budget is
+// spent.
+//
+// Example usage:
+//
+//	go func() {
+//		err := app.Supervise(ctx, app.CommandSpec{
+//			Path: "/usr/local/bin/pdf-renderer",
+//			Args: []string{"--listen", "unix:///run/renderer.sock"},
+//		}, app.DefaultRestartPolicy)
+//		if err != nil {
+//			slog.Error("Renderer gave up", "error", err)
+//		}
+//	}()
+func Supervise(ctx context.Context, spec CommandSpec, policy RestartPolicy) error {
+	if spec.Name == "" {
+		spec.Name = filepath.Base(spec.Path)
+	}
+	if spec.StopSignal == nil {
+		spec.StopSignal = syscall.SIGTERM
+	}
+	if spec.StopTimeout == 0 {
+		spec.StopTimeout = 10 * time.Second
+	}
+	if policy.MaxRestarts == 0 {
+		policy.MaxRestarts = DefaultRestartPolicy.MaxRestarts
+	}
+	if policy.InitialDelay == 0 {
+		policy.InitialDelay = DefaultRestartPolicy.InitialDelay
+	}
+	if policy.MaxDelay == 0 {
+		policy.MaxDelay = DefaultRestartPolicy.MaxDelay
+	}
+	if policy.GrowthFactor == 0 {
+		policy.GrowthFactor = DefaultRestartPolicy.GrowthFactor
+	}
+	if policy.ResetAfter == 0 {
+		policy.ResetAfter = DefaultRestartPolicy.ResetAfter
+	}
+
+	restarts := 0
+	delay := policy.InitialDelay
+	for {
+		started := Now()
+		ran, err := runSupervised(ctx, spec)
+		switch {
+		case ctx.Err() != nil:
+			slog.Info("Supervised process stopped", "process", spec.Name)
+			return nil
+		case !ran:
+			return fmt.Errorf("start %s: %w", spec.Name, err)
+		case err == nil && !policy.RestartOnSuccess:
+			slog.Info("Supervised process exited", "process", spec.Name)
+			return nil
+		}
+
+		if Since(started) >= policy.ResetAfter {
+			restarts = 0
+			delay = policy.InitialDelay
+		}
+		if policy.MaxRestarts >= 0 && restarts >= policy.MaxRestarts {
+			return fmt.Errorf("%w: %s after %d restarts: %w", ErrTooManyRestarts, spec.Name, restarts, exitError(err))
+		}
+		restarts++
+
+		slog.Warn("Supervised process exited, restarting",
+			"process", spec.Name,
+			"error", exitError(err),
+			"restart", restarts,
+			"delay", delay)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Supervised process stopped", "process", spec.Name)
+			return nil
+		case <-After(delay):
+		}
+
+		delay = time.Duration(float64(delay) * policy.GrowthFactor)
+		if delay > policy.MaxDelay || delay <= 0 {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+// runSupervised runs the process once and waits for it to exit. ran is false when the process could not be started.
+func runSupervised(ctx context.Context, spec CommandSpec) (ran bool, err error) {
+	stdout := &outputLogger{process: spec.Name, stream: "stdout", level: slog.LevelInfo}
+	stderr := &outputLogger{process: spec.Name, stream: "stderr", level: slog.LevelWarn}
+
+	cmd := exec.CommandContext(ctx, spec.Path, spec.Args...)
+	cmd.Env = spec.Env
+	cmd.Dir = spec.Dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(spec.StopSignal)
+	}
+	cmd.WaitDelay = spec.StopTimeout
+
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+	slog.Info("Supervised process started", "process", spec.Name, "pid", cmd.Process.Pid)
+
+	err = cmd.Wait()
+	stdout.flush()
+	stderr.flush()
+	return true, err
+}
+
+// exitError describes a clean exit, which carries no error, for logs and restart errors.
+func exitError(err error) error {
+	if err == nil {
+		return errors.New("exited with status 0")
+	}
+	return err
+}
+
+// outputLogger is an io.Writer logging each line written to it.
+type outputLogger struct {
+	process string
+	stream  string
+	level   slog.Level
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (l *outputLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.log(l.buf[:i])
+		l.buf = l.buf[i+1:]
+	}
+	if len(l.buf) >= maxOutputLine {
+		l.log(l.buf)
+		l.buf = nil
+	}
+	return len(p), nil
+}
+
+// flush logs a final line that did not end with a newline.
+func (l *outputLogger) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) > 0 {
+		l.log(l.buf)
+		l.buf = nil
+	}
+}
+
+func (l *outputLogger) log(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	slog.Log(context.Background(), l.level, "Supervised process output",
+		"process", l.process,
+		"stream", l.stream,
+		"line", string(line))
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSupervise tests restarting a failing process, logging its output and stopping it on cancellation
+func TestSupervise(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	TestMode(t)
+	var buf safeBuffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	err = Supervise(context.Background(), CommandSpec{
+		Name: "flaky",
+		Path: sh,
+		Args: []string{"-c", "echo hello; echo oops >&2; exit 3"},
+	}, RestartPolicy{MaxRestarts: 2})
+	if !errors.Is(err, ErrTooManyRestarts) || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("Expected ErrTooManyRestarts with the exit status, got %v", err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "line=hello"); n != 3 {
+		t.Errorf("Expected 3 runs of the process, got %d in %s", n, out)
+	}
+	if !strings.Contains(out, "level=WARN msg=\"Supervised process output\" process=flaky stream=stderr line=oops") {
+		t.Errorf("Expected stderr logged at Warn, got %s", out)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = Supervise(ctx, CommandSpec{Path: sh, Args: []string{"-c", "exec sleep 30"}, StopTimeout: time.Second}, DefaultRestartPolicy)
+	if err != nil || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected cancellation to stop the process, got %v after %s", err, time.Since(start))
+	}
+}
+
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Category classifies errors across services, independently of the package that produced them.
+type Category string
+
+// Categories understood by CategoryOf, each with a default severity, HTTP status and exit code.
+const (
+	CategoryNotFound     Category = "not_found"
+	CategoryInvalid      Category = "invalid"
+	CategoryConflict     Category = "conflict"
+	CategoryUnauthorized Category = "unauthorized"
+	CategoryForbidden    Category = "forbidden"
+	CategoryRateLimited  Category = "rate_limited"
+	CategoryUnavailable  Category = "unavailable"
+	CategoryTimeout      Category = "timeout"
+	CategoryCancelled    Category = "cancelled"
+	CategoryConfig       Category = "config"
+	CategoryInternal     Category = "internal"
+)
+
+// Severity is how urgently an error needs attention, as reported to error trackers, see ErrorEvent.
+type Severity string
+
+// Severities, from most to least urgent.
+const (
+	SeverityFatal   Severity = "fatal"
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// statusClientClosedRequest is the non-standard status, popularized by nginx, for requests the client abandoned.
+const statusClientClosedRequest = 499
+
+type categoryDefaults struct {
+	severity Severity
+	status   int
+	exitCode int
+}
+
+var categories = map[Category]categoryDefaults{
+	CategoryNotFound:     {SeverityWarning, http.StatusNotFound, ExitInternal},
+	CategoryInvalid:      {SeverityWarning, http.StatusBadRequest, ExitInternal},
+	CategoryConflict:     {SeverityWarning, http.StatusConflict, ExitInternal},
+	CategoryUnauthorized: {SeverityWarning, http.StatusUnauthorized, ExitInternal},
+	CategoryForbidden:    {SeverityWarning, http.StatusForbidden, ExitInternal},
+	CategoryRateLimited:  {SeverityWarning, http.StatusTooManyRequests, ExitUnavailable},
+	CategoryUnavailable:  {SeverityError, http.StatusServiceUnavailable, ExitUnavailable},
+	CategoryTimeout:      {SeverityError, http.StatusGatewayTimeout, ExitUnavailable},
+	CategoryCancelled:    {SeverityWarning, statusClientClosedRequest, ExitCancelled},
+	CategoryConfig:       {SeverityFatal, http.StatusInternalServerError, ExitConfig},
+	CategoryInternal:     {SeverityError, http.StatusInternalServerError, ExitInternal},
+}
+
+// Error is a sentinel error created by Define. It carries a category and the severity, HTTP status and exit code that
+// go with it, which CategoryOf, SeverityOf, HTTPStatus and ExitCode find through any wrapping.
+type Error struct {
+	pkgPath  string
+	name     string
+	message  string
+	category Category
+	severity Severity
+	status   int
+}
+
+var (
+	definedMu sync.RWMutex
+	defined   = make(map[string]*Error)
+)
+
+// Define creates and registers a sentinel error named name, typically the name of the variable holding it. The
+// message is derived from the name, so "ErrNotFound" reads "not found". The severity and HTTP status default to
+// those of category and can be overridden with WithSeverity and WithHTTPStatus. Errors are registered under their
+// package-qualified name, such as "github.com/mhpenta/edgar.ErrFilingNotFound", so different packages can define
+// the same name. Define panics if the calling package already defined name, so call it from package-level variable
+// declarations.
+//
+// Example usage:
+//
+//	var (
+//		ErrFilingNotFound = app.Define("ErrFilingNotFound", app.CategoryNotFound)
+//		ErrQuotaExceeded  = app.Define("ErrQuotaExceeded", app.CategoryRateLimited).WithSeverity(app.SeverityError)
+//	)
+//	...
+//	return app.NewMetaError(fmt.Errorf("%w: %s", ErrFilingNotFound, accession))
+func Define(name string, category Category) *Error {
+	defaults, ok := categories[category]
+	if !ok {
+		defaults = categories[CategoryInternal]
+	}
+
+	e := &Error{
+		pkgPath:  Caller(1).PkgPath,
+		name:     name,
+		message:  messageFromName(name),
+		category: category,
+		severity: defaults.severity,
+		status:   defaults.status,
+	}
+
+	definedMu.Lock()
+	defer definedMu.Unlock()
+	if _, exists := defined[e.QualifiedName()]; exists {
+		panic("app: error " + e.QualifiedName() + " defined twice")
+	}
+	defined[e.QualifiedName()] = e
+	return e
+}
+
+// LookupError returns the error defined under qualifiedName, as returned by Error.QualifiedName.
+func LookupError(qualifiedName string) (*Error, bool) {
+	definedMu.RLock()
+	defer definedMu.RUnlock()
+	e, ok := defined[qualifiedName]
+	return e, ok
+}
+
+// DefinedErrors returns every error created with Define, sorted by qualified name, e.g. to document a service's
+// error codes.
+func DefinedErrors() []*Error {
+	definedMu.RLock()
+	defer definedMu.RUnlock()
+
+	errs := make([]*Error, 0, len(defined))
+	for _, e := range defined {
+		errs = append(errs, e)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].QualifiedName() < errs[j].QualifiedName()
+	})
+	return errs
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+// WithSeverity overrides the severity of e and returns e. Call it only where e is defined.
+func (e *Error) WithSeverity(severity Severity) *Error {
+	e.severity = severity
+	return e
+}
+
+// WithHTTPStatus overrides the HTTP status of e and returns e. Call it only where e is defined.
+func (e *Error) WithHTTPStatus(status int) *Error {
+	e.status = status
+	return e
+}
+
+// Name returns the name e was defined with.
+func (e *Error) Name() string {
+	return e.name
+}
+
+// QualifiedName returns the name e was defined with, prefixed by the import path of the package that defined it,
+// as in "github.com/mhpenta/edgar.ErrFilingNotFound".
+func (e *Error) QualifiedName() string {
+	return e.pkgPath + "." + e.name
+}
+
+// Category returns the category of e.
+func (e *Error) Category() Category {
+	return e.category
+}
+
+// Severity returns the severity of e.
+func (e *Error) Severity() Severity {
+	return e.severity
+}
+
+// HTTPStatus returns the HTTP status of e.
+func (e *Error) HTTPStatus() int {
+	return e.status
+}
+
+// ExitCode implements ExitCoder, mapping the category of e to ExitConfig, ExitUnavailable, ExitCancelled or
+// ExitInternal.
+func (e *Error) ExitCode() int {
+	if defaults, ok := categories[e.category]; ok {
+		return defaults.exitCode
+	}
+	return ExitInternal
+}
+
+// CategoryOf returns the category of err: that of the first error created with Define in its chain, otherwise one
+// inferred from the module's sentinels, context errors and HTTP status, otherwise CategoryInternal. It returns an
+// empty category for a nil err.
+func CategoryOf(err error) Category {
+	if err == nil {
+		return ""
+	}
+
+	var definedErr *Error
+	if errors.As(err, &definedErr) {
+		return definedErr.category
+	}
+
+	switch {
+	case errors.Is(err, ErrConfig):
+		return CategoryConfig
+	case errors.Is(err, ErrDependencyUnavailable):
+		return CategoryUnavailable
+	case errors.Is(err, context.Canceled) || errors.Is(err, ErrContextCancelled):
+		return CategoryCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	}
+
+	if status, ok := HTTPStatus(err); ok {
+		for category, defaults := range categories {
+			if defaults.status == status && category != CategoryConfig {
+				return category
+			}
+		}
+	}
+	return CategoryInternal
+}
+
+// SeverityOf returns the severity of err: that of the first error created with Define in its chain, otherwise
+// SeverityFatal for panics and the default severity of CategoryOf(err).
+func SeverityOf(err error) Severity {
+	var definedErr *Error
+	if errors.As(err, &definedErr) {
+		return definedErr.severity
+	}
+	if errors.Is(err, ErrPanic) {
+		return SeverityFatal
+	}
+	if defaults, ok := categories[CategoryOf(err)]; ok {
+		return defaults.severity
+	}
+	return SeverityError
+}
+
+// messageFromName turns an identifier such as "ErrHTTPTimeout" into the message "http timeout".
+func messageFromName(name string) string {
+	trimmed := strings.TrimPrefix(name, "Err")
+	if trimmed == "" {
+		return strings.ToLower(name)
+	}
+
+	runes := []rune(trimmed)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		upper := unicode.IsUpper(runes[i])
+		// a word starts at an upper case letter following a lower case one, or at the last upper case letter of an
+		// acronym followed by a lower case one, as in "HTTPTimeout"
+		if upper && (unicode.IsLower(runes[i-1]) ||
+			(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	return strings.ToLower(strings.Join(words, " "))
+}
@@ -0,0 +1,6 @@
+package app_test
+
+import "github.com/mhpenta/app"
+
+// ErrTestDuplicate shares its name with an error defined by package app, see TestDefineAcrossPackages
+var ErrTestDuplicate = app.Define("ErrTestDuplicate", app.CategoryNotFound)
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestDefine tests sentinel errors defined with a category and their resolution through wrapping
+func TestDefine(t *testing.T) {
+	errNotFound := Define("ErrTestFilingNotFound", CategoryNotFound)
+	errQuota := Define("ErrTestHTTPQuota", CategoryRateLimited).WithSeverity(SeverityError)
+	defer func() {
+		definedMu.Lock()
+		delete(defined, errNotFound.QualifiedName())
+		delete(defined, errQuota.QualifiedName())
+		definedMu.Unlock()
+	}()
+
+	if errNotFound.Error() != "test filing not found" || errQuota.Error() != "test http quota" {
+		t.Errorf("Expected messages derived from names, got %q and %q", errNotFound, errQuota)
+	}
+
+	wrapped := NewMetaError(fmt.Errorf("load 0001: %w", errNotFound))
+	if CategoryOf(wrapped) != CategoryNotFound || SeverityOf(wrapped) != SeverityWarning {
+		t.Errorf("Expected category and severity through wrapping, got %s, %s", CategoryOf(wrapped), SeverityOf(wrapped))
+	}
+	if status, ok := HTTPStatus(wrapped); !ok || status != 404 {
+		t.Errorf("Expected the category's HTTP status, got %d", status)
+	}
+	if status, _ := HTTPStatus(NewMetaError(errNotFound).WithHTTPStatus(410)); status != 410 {
+		t.Errorf("Expected an explicit status to win, got %d", status)
+	}
+	if SeverityOf(errQuota) != SeverityError || ExitCode(fmt.Errorf("sync: %w", errQuota)) != ExitUnavailable {
+		t.Error("Expected the overridden severity and the category's exit code")
+	}
+
+	if found, ok := LookupError("github.com/mhpenta/app.ErrTestHTTPQuota"); !ok || found != errQuota {
+		t.Error("Expected LookupError to find the defined error by its qualified name")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected defining a name twice to panic")
+		}
+	}()
+	Define("ErrTestHTTPQuota", CategoryInternal)
+}
+
+// errTestDuplicate is also defined by the external test package, see taxonomy_external_test.go
+var errTestDuplicate = Define("ErrTestDuplicate", CategoryConflict)
+
+// TestDefineAcrossPackages tests that packages defining the same name get separate errors
+func TestDefineAcrossPackages(t *testing.T) {
+	local, ok := LookupError("github.com/mhpenta/app.ErrTestDuplicate")
+	if !ok || local != errTestDuplicate || local.Name() != "ErrTestDuplicate" {
+		t.Fatalf("Expected the package's own definition, got %v", local)
+	}
+	external, ok := LookupError("github.com/mhpenta/app_test.ErrTestDuplicate")
+	if !ok || external == local || external.Category() != CategoryNotFound {
+		t.Fatalf("Expected the external test package's definition kept separately, got %v", external)
+	}
+	if errors.Is(external, errTestDuplicate) {
+		t.Error("Expected the two definitions to be different errors")
+	}
+}
+
+// TestCategoryOf tests inferring categories for errors not created with Define
+func TestCategoryOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Category
+	}{
+		{nil, ""},
+		{fmt.Errorf("%w: missing DSN", ErrConfig), CategoryConfig},
+		{fmt.Errorf("fetch: %w", context.DeadlineExceeded), CategoryTimeout},
+		{context.Canceled, CategoryCancelled},
+		{NewMetaError(errors.New("gone")).WithHTTPStatus(503), CategoryUnavailable},
+		{errors.New("boom"), CategoryInternal},
+	}
+	for _, tt := range tests {
+		if got := CategoryOf(tt.err); got != tt.want {
+			t.Errorf("Expected CategoryOf(%v) = %q, got %q", tt.err, tt.want, got)
+		}
+	}
+	if SeverityOf(FromPanic("boom")) != SeverityFatal {
+		t.Error("Expected panics to be fatal")
+	}
+}
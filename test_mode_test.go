@@ -0,0 +1,37 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTestMode tests that TestMode freezes the clock and makes random draws reproducible
+func TestTestMode(t *testing.T) {
+	var first []int64
+	t.Run("first", func(t *testing.T) {
+		clock := TestMode(t)
+		if !Now().Equal(TestModeStart) {
+			t.Errorf("Expected clock at %s, got %s", TestModeStart, Now())
+		}
+		Sleep(time.Hour)
+		if Since(TestModeStart) != time.Hour || !clock.Now().Equal(TestModeStart.Add(time.Hour)) {
+			t.Error("Expected Sleep to advance the fake clock by an hour")
+		}
+		for i := 0; i < 3; i++ {
+			first = append(first, RandN(1000))
+		}
+	})
+
+	t.Run("second", func(t *testing.T) {
+		TestMode(t)
+		for i := 0; i < 3; i++ {
+			if n := RandN(1000); n != first[i] {
+				t.Errorf("Expected draw %d to repeat %d, got %d", i, first[i], n)
+			}
+		}
+	})
+
+	if Since(time.Now()) > time.Second {
+		t.Error("Expected the system clock to be restored")
+	}
+}